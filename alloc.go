@@ -0,0 +1,135 @@
+package art
+
+import "sync"
+
+// Allocator allocates nodes of the tree. Node types are internal to the package,
+// therefore Allocator can't be implemented outside of the package, one of HeapAllocator
+// or SlabAllocator is selected with WithAllocator.
+// Memory of the node can't be reused after the node is removed from the tree, because
+// optimistic readers may still access it, nodes are reclaimed by the garbage collector.
+type Allocator interface {
+	leaf() *leaf
+	inner() *inner
+	node4() *node4
+	node16() *node16
+	node48() *node48
+	node256() *node256
+}
+
+// WithAllocator configures allocator for the nodes of the tree.
+func WithAllocator(a Allocator) Option {
+	return func(t *Tree) {
+		t.alloc = a
+	}
+}
+
+// heap allocates every node separately, it is used by default.
+var heap Allocator = heapAllocator{}
+
+// HeapAllocator returns allocator that allocates every node separately.
+func HeapAllocator() Allocator {
+	return heap
+}
+
+type heapAllocator struct{}
+
+func (heapAllocator) leaf() *leaf {
+	return &leaf{}
+}
+
+func (heapAllocator) inner() *inner {
+	return &inner{}
+}
+
+func (heapAllocator) node4() *node4 {
+	return &node4{}
+}
+
+func (heapAllocator) node16() *node16 {
+	return &node16{}
+}
+
+func (heapAllocator) node48() *node48 {
+	return &node48{}
+}
+
+func (heapAllocator) node256() *node256 {
+	return &node256{}
+}
+
+// SlabAllocator returns allocator that allocates leaves and inner nodes in slabs
+// of the given size, reducing the number of allocations. Slab is reclaimed when
+// all nodes allocated from it are unreachable. Size must be positive.
+func SlabAllocator(size int) Allocator {
+	if size <= 0 {
+		panic("slab size must be positive")
+	}
+	return &slabAllocator{size: size}
+}
+
+type slabAllocator struct {
+	heapAllocator
+	size int
+
+	mu     sync.Mutex
+	leaves []leaf
+	inners []inner
+}
+
+func (s *slabAllocator) leaf() *leaf {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.leaves) == 0 {
+		s.leaves = make([]leaf, s.size)
+	}
+	l := &s.leaves[0]
+	s.leaves = s.leaves[1:]
+	return l
+}
+
+func (s *slabAllocator) inner() *inner {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.inners) == 0 {
+		s.inners = make([]inner, s.size)
+	}
+	n := &s.inners[0]
+	s.inners = s.inners[1:]
+	return n
+}
+
+// allocator returns configured allocator, or heap allocator.
+func (t *Tree) allocator() Allocator {
+	if t.alloc == nil {
+		return heap
+	}
+	return t.alloc
+}
+
+// WithCopiedKeys copies keys into the leaves on insert, so that the caller may reuse
+// the key buffer after the insert returns. Without the option the key is stored by reference
+// and must not be modified while it is in the tree.
+func WithCopiedKeys() Option {
+	return func(t *Tree) {
+		t.copyKeys = true
+	}
+}
+
+// ownKey returns the key that will be stored in the leaf.
+func (t *Tree) ownKey(key []byte) []byte {
+	if !t.copyKeys {
+		return key
+	}
+	owned := make([]byte, len(key))
+	copy(owned, key)
+	return owned
+}
+
+// newLeaf allocates the leaf with the key and value.
+func (t *Tree) newLeaf(key []byte, value ValueType) *leaf {
+	l := t.allocator().leaf()
+	l.key = t.ownKey(key)
+	l.value = value
+	l.expires = t.deadline(l.key)
+	return l
+}
@@ -0,0 +1,100 @@
+package art
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingAllocator struct {
+	heapAllocator
+	leaves, inners, nodes int
+}
+
+func (c *countingAllocator) leaf() *leaf {
+	c.leaves++
+	return c.heapAllocator.leaf()
+}
+
+func (c *countingAllocator) inner() *inner {
+	c.inners++
+	return c.heapAllocator.inner()
+}
+
+func (c *countingAllocator) node4() *node4 {
+	c.nodes++
+	return c.heapAllocator.node4()
+}
+
+func (c *countingAllocator) node16() *node16 {
+	c.nodes++
+	return c.heapAllocator.node16()
+}
+
+func (c *countingAllocator) node48() *node48 {
+	c.nodes++
+	return c.heapAllocator.node48()
+}
+
+func (c *countingAllocator) node256() *node256 {
+	c.nodes++
+	return c.heapAllocator.node256()
+}
+
+func TestAllocator(t *testing.T) {
+	alloc := &countingAllocator{}
+	tree := New(WithAllocator(alloc))
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte(fmt.Sprintf("%04d", i)), i)
+	}
+	require.Equal(t, 1000, alloc.leaves)
+	require.NotZero(t, alloc.inners)
+	require.NotZero(t, alloc.nodes)
+
+	nodes := alloc.nodes
+	for i := 0; i < 1000; i++ {
+		tree.Delete([]byte(fmt.Sprintf("%04d", i)))
+	}
+	require.Greater(t, alloc.nodes, nodes, "shrinking must allocate through the allocator")
+}
+
+func TestSlabAllocator(t *testing.T) {
+	tree := New(WithAllocator(SlabAllocator(64)))
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte(fmt.Sprintf("%04d", i)), i)
+	}
+	for i := 0; i < 1000; i++ {
+		value, found := tree.Get([]byte(fmt.Sprintf("%04d", i)))
+		require.True(t, found)
+		require.Equal(t, i, value)
+	}
+	require.Panics(t, func() { SlabAllocator(0) })
+	require.Panics(t, func() { SlabAllocator(-1) })
+}
+
+func TestCopiedKeys(t *testing.T) {
+	tree := New(WithCopiedKeys())
+	typed := NewTreeOf[int](WithCopiedKeys())
+	var hint Hint
+	buf := make([]byte, 4)
+	for i := 0; i < 1000; i++ {
+		copy(buf, fmt.Sprintf("%04d", i))
+		tree.Insert(buf, i)
+		typed.Insert(buf, i)
+		copy(buf, fmt.Sprintf("h%03d", i))
+		tree.InsertWithHint(&hint, buf, i)
+	}
+	require.Equal(t, 2000, tree.Len())
+	for i := 0; i < 1000; i++ {
+		value, found := tree.Get([]byte(fmt.Sprintf("%04d", i)))
+		require.True(t, found)
+		require.Equal(t, i, value)
+		value, found = tree.Get([]byte(fmt.Sprintf("h%03d", i)))
+		require.True(t, found)
+		require.Equal(t, i, value)
+		rst, found := typed.Get([]byte(fmt.Sprintf("%04d", i)))
+		require.True(t, found)
+		require.Equal(t, i, rst)
+	}
+}
@@ -0,0 +1,132 @@
+package art
+
+import "bytes"
+
+// Ascend calls fn for every key that is >= start in ascending order, until fn returns false.
+// nil start visits all keys.
+// Unlike iterator, traversal doesn't allocate checkpoints, nodes on the path are tracked by the stack.
+// Traversal is concurrently safe, but doesn't guarantee to provide consistent snapshot of the tree state.
+func (t *Tree) Ascend(start []byte, fn func(key []byte, value ValueType) bool) {
+	t.traverse(start, false, fn)
+}
+
+// Descend calls fn for every key that is <= start in descending order, until fn returns false.
+// nil start visits all keys.
+func (t *Tree) Descend(start []byte, fn func(key []byte, value ValueType) bool) {
+	t.traverse(start, true, fn)
+}
+
+func (t *Tree) traverse(start []byte, reverse bool, fn func([]byte, ValueType) bool) {
+	t.checkPoisoned()
+	tr := traversal{cursor: t.keyOf(start), inclusive: true, reverse: reverse, fn: fn}
+	for {
+		version, _ := t.lock.RLock()
+		root := t.root
+		n, isInner := root.(*inner)
+		if !isInner {
+			l, _ := root.(*leaf)
+			if t.lock.RUnlock(version, nil) {
+				continue
+			}
+			if l != nil && tr.follows(l.key) {
+				_ = fn(l.key, l.value)
+			}
+			return
+		}
+		_, restart := tr.visit(n, &t.lock, version, 0, len(tr.cursor) > 0)
+		// visit validates the root version, lock is released for the pessimistic lock
+		_ = t.lock.RUnlock(version, nil)
+		if restart {
+			continue
+		}
+		return
+	}
+}
+
+// traversal visits keys that follow the cursor, cursor is updated to the last visited key.
+// If traversal is restarted it descends again from the root to the cursor.
+type traversal struct {
+	cursor []byte
+	// inclusive is true until the first key is visited, so that key equal to start is visited.
+	inclusive bool
+	reverse   bool
+	fn        func([]byte, ValueType) bool
+}
+
+// follows returns true if key follows the cursor in the direction of traversal.
+func (tr *traversal) follows(key []byte) bool {
+	if len(tr.cursor) == 0 {
+		return true
+	}
+	cmp := bytes.Compare(key, tr.cursor)
+	if tr.reverse {
+		cmp = -cmp
+	}
+	return cmp > 0 || cmp == 0 && tr.inclusive
+}
+
+// visit calls fn for keys in the subtree of n that follow the cursor.
+// bounded is true if the path to the node is a prefix of the cursor, otherwise
+// the whole subtree follows the cursor.
+// Returns true if fn requested to stop, and true if traversal needs to be restarted.
+func (tr *traversal) visit(n *inner, parent *olock, parentVersion uint64, depth int, bounded bool) (bool, bool) {
+	version, obsolete := n.lock.RLock()
+	if obsolete || parent.Check(parentVersion) {
+		_ = n.lock.RUnlock(version, nil)
+		return false, true
+	}
+	var (
+		pointer *byte
+		edge    byte
+	)
+	if bounded {
+		cmp := comparePrefix(n.prefix[:n.prefixLen], tr.cursor, 0, depth)
+		if cmp != n.prefixLen || depth+n.prefixLen >= len(tr.cursor) {
+			after := depth+cmp >= len(tr.cursor) || n.prefix[cmp] > tr.cursor[depth+cmp]
+			if after == tr.reverse {
+				// subtree precedes the cursor
+				return false, n.lock.RUnlock(version, nil)
+			}
+			bounded = false
+		} else {
+			edge = tr.cursor[depth+n.prefixLen]
+			pointer = before(edge, tr.reverse)
+		}
+	}
+	nextDepth := depth + n.prefixLen + 1
+	for {
+		var (
+			b     byte
+			child node
+		)
+		if tr.reverse {
+			b, child = n.node.prev(pointer)
+		} else {
+			b, child = n.node.next(pointer)
+		}
+		if child == nil {
+			return false, n.lock.RUnlock(version, nil)
+		}
+		pointer = &b
+		switch child := child.(type) {
+		case *leaf:
+			key, value := child.key, child.value
+			if n.lock.Check(version) {
+				_ = n.lock.RUnlock(version, nil)
+				return false, true
+			}
+			if tr.follows(key) {
+				tr.cursor, tr.inclusive = key, false
+				if !tr.fn(key, value) {
+					_ = n.lock.RUnlock(version, nil)
+					return true, false
+				}
+			}
+		case *inner:
+			if stop, restart := tr.visit(child, &n.lock, version, nextDepth, bounded && b == edge); stop || restart {
+				_ = n.lock.RUnlock(version, nil)
+				return stop, restart
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package art
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAscendDescend(t *testing.T) {
+	var tree Tree
+	rng := rand.New(rand.NewSource(3))
+	sorted := []string{}
+	seen := map[string]bool{}
+	for len(sorted) < 2000 {
+		key := make([]byte, 1+rng.Intn(6))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 80)
+		}
+		key = append(key, 1)
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		tree.Insert(key, string(key))
+		sorted = append(sorted, string(key))
+	}
+	sort.Strings(sorted)
+
+	for i := 0; i < 200; i++ {
+		var start []byte
+		if i > 0 {
+			if rng.Intn(2) == 0 {
+				start = []byte(sorted[rng.Intn(len(sorted))])
+			} else {
+				start = make([]byte, 1+rng.Intn(7))
+				for j := range start {
+					start[j] = byte(rng.Intn(4) * 80)
+				}
+			}
+		}
+		limit := rng.Intn(100)
+
+		expected := []string{}
+		for _, key := range sorted {
+			if key >= string(start) && len(expected) < limit {
+				expected = append(expected, key)
+			}
+		}
+		rst := []string{}
+		tree.Ascend(start, func(key []byte, value ValueType) bool {
+			require.Equal(t, string(key), value)
+			if len(rst) == limit {
+				return false
+			}
+			rst = append(rst, string(key))
+			return true
+		})
+		require.Equal(t, expected, rst, "ascend from %v", start)
+
+		expected = expected[:0]
+		for j := len(sorted) - 1; j >= 0; j-- {
+			if (start == nil || sorted[j] <= string(start)) && len(expected) < limit {
+				expected = append(expected, sorted[j])
+			}
+		}
+		rst = rst[:0]
+		tree.Descend(start, func(key []byte, _ ValueType) bool {
+			if len(rst) == limit {
+				return false
+			}
+			rst = append(rst, string(key))
+			return true
+		})
+		require.Equal(t, expected, rst, "descend from %v", start)
+	}
+}
+
+func TestAscendConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	key := func(i int) []byte {
+		k := make([]byte, 5)
+		binary.BigEndian.PutUint32(k, uint32(i))
+		return k
+	}
+	for i := 0; i < 10000; i += 2 {
+		tree.Insert(key(i), i)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i < 10000; i += 2 {
+			tree.Insert(key(i), i)
+		}
+	}()
+	for r := 0; r < 10; r++ {
+		prev := -1
+		tree.Ascend(nil, func(_ []byte, value ValueType) bool {
+			require.Greater(t, value.(int), prev)
+			if value.(int)%2 == 0 {
+				require.LessOrEqual(t, value.(int)-prev, 2)
+			}
+			prev = value.(int)
+			return true
+		})
+		require.GreaterOrEqual(t, prev, 9998)
+	}
+	wg.Wait()
+}
@@ -3,6 +3,7 @@ package main
 import (
 	. "github.com/mmcloughlin/avo/build"
 	. "github.com/mmcloughlin/avo/operand"
+	"github.com/mmcloughlin/avo/reg"
 )
 
 func main() {
@@ -24,6 +25,50 @@ func main() {
 
 	Store(mask.As16(), ReturnIndex(0))
 	RET()
+
+	// lowerAVX sets a bit for every element of nkey that is lower than the key.
+	// VPCMPGTB compares signed bytes, sign bit of both operands is flipped
+	// to get unsigned comparison.
+	TEXT("lowerAVX", NOSPLIT, "func(key *byte, nkey *[16]byte) uint16")
+	key = Load(Param("key"), GP64())
+	nkey = Mem{Base: Load(Param("nkey"), GP64())}
+
+	x0, x1, x2, x3 := XMM(), XMM(), XMM(), XMM()
+	sign, mask := GP32(), GP32()
+
+	VPXOR(x1, x1, x1)
+	VMOVD(Mem{Base: key}, x0)
+	VPSHUFB(x1, x0, x0)
+
+	MOVL(U32(0x80), sign)
+	VMOVD(sign, x3)
+	VPSHUFB(x1, x3, x3)
+
+	VLDDQU(nkey.Offset(0x00), x2)
+	VPXOR(x3, x0, x0)
+	VPXOR(x3, x2, x2)
+	VPCMPGTB(x2, x0, x0)
+	VPMOVMSKB(x0, mask)
+
+	Store(mask.As16(), ReturnIndex(0))
+	RET()
+
+	TEXT("cpuid", NOSPLIT, "func(leaf, subleaf uint32) (eax, ebx, ecx, edx uint32)")
+	Load(Param("leaf"), reg.EAX)
+	Load(Param("subleaf"), reg.ECX)
+	CPUID()
+	Store(reg.EAX, Return("eax"))
+	Store(reg.EBX, Return("ebx"))
+	Store(reg.ECX, Return("ecx"))
+	Store(reg.EDX, Return("edx"))
+	RET()
+
+	TEXT("xgetbv", NOSPLIT, "func() (eax, edx uint32)")
+	XORL(reg.ECX, reg.ECX)
+	XGETBV()
+	Store(reg.EAX, Return("eax"))
+	Store(reg.EDX, Return("edx"))
+	RET()
 	Generate()
 
 }
@@ -0,0 +1,74 @@
+package art
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Backoff is a policy for waiting before the descent is restarted from the root, after
+// a concurrent writer modified a node on the path. Wait is called with the number
+// of restarts of the operation, starting from 1, and must be safe for concurrent use.
+type Backoff interface {
+	Wait(attempt int)
+}
+
+// WithBackoff configures the policy for restarts, by default descent is restarted immediately.
+// Policy can be replaced by Reconfigure, WithBackoff(nil) restores the default.
+func WithBackoff(b Backoff) Option {
+	return func(t *Tree) {
+		t.backoff.Store(&backoffPolicy{policy: b})
+	}
+}
+
+// backoffPolicy wraps the interface, so that it can be replaced atomically.
+type backoffPolicy struct {
+	policy Backoff
+}
+
+// SpinBackoff restarts immediately, it is the default policy.
+type SpinBackoff struct{}
+
+func (SpinBackoff) Wait(int) {}
+
+// YieldBackoff yields the processor before every restart, giving the writer that caused
+// the conflict a chance to complete.
+type YieldBackoff struct{}
+
+func (YieldBackoff) Wait(int) {
+	runtime.Gosched()
+}
+
+// ExponentialBackoff sleeps for a random duration up to Base * 2^(attempt-1), limited by Max.
+// Random jitter prevents conflicting operations from restarting in lockstep.
+type ExponentialBackoff struct {
+	Base, Max time.Duration
+}
+
+func (b ExponentialBackoff) Wait(attempt int) {
+	limit := b.Max
+	if shift := attempt - 1; shift < 32 && b.Base<<shift < limit {
+		limit = b.Base << shift
+	}
+	if limit <= 0 {
+		runtime.Gosched()
+		return
+	}
+	time.Sleep(time.Duration(rand.Int63n(int64(limit)) + 1))
+}
+
+// Restarts returns a number of descents restarted from the root since the tree was created.
+func (t *Tree) Restarts() uint64 {
+	return atomic.LoadUint64(&t.restarts)
+}
+
+// restarted is called before the descent is restarted from the root, attempt is a number
+// of restarts of the operation.
+func (t *Tree) restarted(attempt int) {
+	atomic.AddUint64(&t.restarts, 1)
+	t.count(MetricRestarts, 1)
+	if b := t.backoff.Load(); b != nil {
+		b.policy.Wait(attempt)
+	}
+}
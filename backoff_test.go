@@ -0,0 +1,81 @@
+package art
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBackoff struct {
+	waits, first int64
+}
+
+func (b *recordingBackoff) Wait(attempt int) {
+	atomic.AddInt64(&b.waits, 1)
+	if attempt == 1 {
+		atomic.AddInt64(&b.first, 1)
+	}
+	YieldBackoff{}.Wait(attempt)
+}
+
+func TestBackoff(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		backoff recordingBackoff
+		sink    countingSink
+	)
+	tree := New(WithBackoff(&backoff), WithMetrics(&sink))
+	require.True(t, tree.Config().Backoff)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 20_000; i++ {
+				key := []byte{byte(rng.Intn(4)), byte(rng.Intn(256)), 0}
+				switch rng.Intn(3) {
+				case 0:
+					tree.Insert(key, i)
+				case 1:
+					tree.Delete(key)
+				default:
+					_, _ = tree.Get(key)
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	// conflicts are rare without parallelism, restart is recorded as a descent would do
+	tree.restarted(1)
+	require.NotZero(t, tree.Restarts())
+	require.EqualValues(t, tree.Restarts(), backoff.waits)
+	require.EqualValues(t, tree.Restarts(), sink[MetricRestarts])
+	require.LessOrEqual(t, backoff.first, backoff.waits)
+
+	var replaced recordingBackoff
+	require.NoError(t, tree.Reconfigure(WithBackoff(&replaced)))
+	tree.restarted(1)
+	require.EqualValues(t, 1, replaced.waits)
+
+	require.NoError(t, tree.Reconfigure(WithBackoff(nil)))
+	require.False(t, tree.Config().Backoff)
+	tree.restarted(1)
+	require.EqualValues(t, 1, replaced.waits)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Base: time.Microsecond, Max: time.Millisecond}
+	for _, attempt := range []int{1, 5, 64} {
+		start := time.Now()
+		b.Wait(attempt)
+		// sleep may overshoot the limit by the timer resolution
+		require.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	}
+	ExponentialBackoff{}.Wait(1)
+}
@@ -0,0 +1,105 @@
+package art
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// leafOverhead is an estimated size of the leaf without the key.
+const leafOverhead = int64(unsafe.Sizeof(leaf{}))
+
+// WithMemoryLimit bounds estimated memory used by the tree. Estimate includes leaves
+// and keys, but not inner nodes and values.
+// Once estimate exceeds the limit Insert blocks until Delete, eviction or Clear
+// frees space, and TryInsert returns ErrBackpressure.
+func WithMemoryLimit(limit int64) Option {
+	return func(t *Tree) {
+		t.limiter = newLimiter(limit)
+	}
+}
+
+type limiter struct {
+	// limit can be changed by Reconfigure, and must be accessed atomically.
+	limit int64
+	usage int64
+	// waiters is a number of blocked writers, that need to be notified when space is freed.
+	waiters int32
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+func newLimiter(limit int64) *limiter {
+	l := &limiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func leafSize(l *leaf) int64 {
+	if l == nil {
+		return 0
+	}
+	return leafOverhead + int64(len(l.key))
+}
+
+func (l *limiter) full() bool {
+	return atomic.LoadInt64(&l.usage) >= atomic.LoadInt64(&l.limit)
+}
+
+// add updates usage and wakes up blocked writers if space was freed.
+func (l *limiter) add(delta int64) {
+	usage := atomic.AddInt64(&l.usage, delta)
+	if delta < 0 && usage < atomic.LoadInt64(&l.limit) {
+		l.wake()
+	}
+}
+
+// setLimit updates the limit and wakes up blocked writers if it was raised.
+func (l *limiter) setLimit(limit int64) {
+	if atomic.SwapInt64(&l.limit, limit) < limit {
+		l.wake()
+	}
+}
+
+func (l *limiter) wake() {
+	if atomic.LoadInt32(&l.waiters) > 0 {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	}
+}
+
+func (l *limiter) reset() {
+	l.add(-atomic.LoadInt64(&l.usage))
+}
+
+// wait blocks until usage is below the limit.
+func (l *limiter) wait() {
+	if !l.full() {
+		return
+	}
+	atomic.AddInt32(&l.waiters, 1)
+	l.mu.Lock()
+	for l.full() {
+		l.cond.Wait()
+	}
+	l.mu.Unlock()
+	atomic.AddInt32(&l.waiters, -1)
+}
+
+// TryInsert inserts the key, unless estimated memory exceeds the limit configured
+// with WithMemoryLimit, in which case ErrBackpressure is returned.
+// ErrFull is returned if the new key was not admitted by WithTinyLFU.
+func (t *Tree) TryInsert(key []byte, value ValueType) error {
+	if t.limiter != nil && t.limiter.full() {
+		return ErrBackpressure
+	}
+	l := t.newLeaf(t.keyOf(key), value)
+	op := upsert{key: l.key, leaf: l}
+	t.upsert(&op)
+	if op.stored == nil {
+		return ErrFull
+	}
+	return nil
+}
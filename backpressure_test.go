@@ -0,0 +1,43 @@
+package art
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	skipUnsafe(t)
+	keySize := int64(8)
+	tree := New(WithMemoryLimit(10 * (leafOverhead + keySize)))
+	key := func(i int) []byte {
+		return []byte{0, 0, 0, 0, 0, 0, 0, byte(i)}
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tree.TryInsert(key(i), i))
+	}
+	require.Equal(t, ErrBackpressure, tree.TryInsert(key(10), 10))
+
+	inserted := make(chan struct{})
+	go func() {
+		tree.Insert(key(10), 10)
+		close(inserted)
+	}()
+	select {
+	case <-inserted:
+		require.FailNow(t, "insert must block until space is freed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	tree.Delete(key(0))
+	select {
+	case <-inserted:
+	case <-time.After(time.Second):
+		require.FailNow(t, "insert wasn't unblocked")
+	}
+	_, found := tree.Get(key(10))
+	require.True(t, found)
+
+	tree.Clear()
+	require.NoError(t, tree.TryInsert(key(0), 0))
+}
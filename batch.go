@@ -0,0 +1,168 @@
+package art
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// InsertBatch inserts keys with corresponding values. If keys are sorted, keys that
+// belong to the same empty slot in the tree are built into a subtree bottom-up and
+// spliced into the tree under a single write lock. Keys that replace existing keys,
+// or that need to split existing nodes, are inserted one by one.
+// Unsorted input, and trees with eviction, admission, memory limit or write-ahead log,
+// fall back to Insert.
+func (t *Tree) InsertBatch(keys [][]byte, values []ValueType) {
+	leaves := make([]*leaf, 0, len(keys))
+	for i, key := range keys {
+		l := t.newLeaf(t.keyOf(key), values[i])
+		if i > 0 {
+			cmp := bytes.Compare(leaves[len(leaves)-1].key, l.key)
+			if cmp > 0 || t.evictor != nil || t.limiter != nil || t.wal != nil {
+				leaves = nil
+				break
+			}
+			if cmp == 0 {
+				// last value wins
+				leaves[len(leaves)-1] = l
+				continue
+			}
+		}
+		leaves = append(leaves, l)
+	}
+	if leaves == nil {
+		for i, key := range keys {
+			t.Insert(key, values[i])
+		}
+		return
+	}
+	for len(leaves) > 0 {
+		n := t.splice(leaves)
+		if n == 0 {
+			t.insert(leaves[0])
+			n = 1
+		}
+		leaves = leaves[n:]
+	}
+}
+
+// splice finds empty slot for the first leaf, and stores the first leaf together with
+// the following leaves that belong to the same slot as a single subtree.
+// Returns number of stored leaves, zero if slot for the first leaf is not empty.
+func (t *Tree) splice(leaves []*leaf) int {
+	t.checkPoisoned()
+	key := leaves[0].key
+restart:
+	version, _ := t.lock.RLock()
+	if t.root == nil {
+		if t.lock.Upgrade(version, nil) {
+			goto restart
+		}
+		t.root = t.build(leaves, 0)
+		t.lock.Unlock()
+		return len(leaves)
+	}
+	n, isInner := t.root.(*inner)
+	if !isInner {
+		if t.lock.RUnlock(version, nil) {
+			goto restart
+		}
+		return 0
+	}
+	parent := &t.lock
+	depth := 0
+	for {
+		nversion, obsolete := n.lock.RLock()
+		if obsolete {
+			_ = parent.RUnlock(version, nil)
+			goto restart
+		}
+		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
+		var child node
+		if cmp == n.prefixLen {
+			depth += n.prefixLen
+			_, child = n.node.child(key[depth])
+		}
+		if cmp != n.prefixLen || child != nil && child.isLeaf() {
+			if n.lock.RUnlock(nversion, nil) || parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return 0
+		}
+		if child == nil {
+			if n.lock.Upgrade(nversion, nil) {
+				_ = parent.RUnlock(version, nil)
+				goto restart
+			}
+			if parent.RUnlock(version, &n.lock) {
+				goto restart
+			}
+			count := 1
+			for count < len(leaves) && bytes.HasPrefix(leaves[count].key, key[:depth+1]) {
+				count++
+			}
+			if n.node.full() {
+				n.node = n.node.grow(t.allocator())
+				t.count(MetricGrows, 1)
+			}
+			n.node.addChild(key[depth], t.build(leaves[:count], depth+1))
+			n.unlock()
+			return count
+		}
+		if parent.RUnlock(version, nil) {
+			_ = n.lock.RUnlock(nversion, nil)
+			goto restart
+		}
+		parent, version = &n.lock, nversion
+		n = child.(*inner)
+		depth++
+	}
+}
+
+// build creates subtree from sorted leaves, starting at depth.
+func (t *Tree) build(leaves []*leaf, depth int) node {
+	version := atomic.AddUint64(&t.writes, 1)
+	atomic.AddInt64(&t.size, int64(len(leaves)))
+	t.count(MetricInserts, int64(len(leaves)))
+	for _, l := range leaves {
+		l.version = version
+		if t.guard != nil {
+			t.guard.stored(l)
+		}
+	}
+	return buildSubtree(leaves, depth, t.allocator())
+}
+
+func buildSubtree(leaves []*leaf, depth int, a Allocator) node {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	first, last := leaves[0].key, leaves[len(leaves)-1].key
+	cp := commonPrefix(first[depth:], last[depth:])
+	if cp > maxPrefixLen {
+		// prefix is split into multiple nodes, same as in leaf expansion
+		n := a.inner()
+		n.prefixLen = maxPrefixLen
+		n.node = a.node4()
+		copy(n.prefix[:], first[depth:depth+maxPrefixLen])
+		n.node.addChild(first[depth+maxPrefixLen], buildSubtree(leaves, depth+maxPrefixLen+1, a))
+		return n
+	}
+	n := a.inner()
+	n.prefixLen = cp
+	n.node = a.node4()
+	copy(n.prefix[:], first[depth:depth+cp])
+	depth += cp
+	for len(leaves) > 0 {
+		b := leaves[0].key[depth]
+		count := 1
+		for count < len(leaves) && leaves[count].key[depth] == b {
+			count++
+		}
+		if n.node.full() {
+			n.node = n.node.grow(a)
+		}
+		n.node.addChild(b, buildSubtree(leaves[:count], depth+1, a))
+		leaves = leaves[count:]
+	}
+	return n
+}
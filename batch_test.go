@@ -0,0 +1,127 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sortedKeys(rng *rand.Rand, n int) [][]byte {
+	keys := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		key := []byte(fmt.Sprintf("%x:%d\x00", rng.Intn(1<<20), i))
+		if i%10 == 0 {
+			// long shared prefixes
+			key = append(bytes.Repeat([]byte{'p'}, 20), key...)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+func TestInsertBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := sortedKeys(rng, 5000)
+	values := make([]ValueType, len(keys))
+	for i := range values {
+		values[i] = i
+	}
+
+	var batched, inserted Tree
+	batched.InsertBatch(keys[:len(keys)/2], values[:len(keys)/2])
+	for i, key := range keys {
+		inserted.Insert(key, values[i])
+	}
+	// second half is spliced into the existing tree
+	batched.InsertBatch(keys[len(keys)/2:], values[len(keys)/2:])
+	require.Equal(t, inserted.Len(), batched.Len())
+
+	for i, key := range keys {
+		value, found := batched.Get(key)
+		require.True(t, found, "key %s", key)
+		require.Equal(t, values[i], value)
+	}
+	iter := batched.Iterator(nil, nil)
+	i := 0
+	for iter.Next() {
+		require.Equal(t, keys[i], iter.Key())
+		i++
+	}
+	require.Equal(t, len(keys), i)
+}
+
+func TestInsertBatchReplace(t *testing.T) {
+	var tree Tree
+	tree.Insert([]byte{1, 1}, 0)
+	tree.Insert([]byte{2, 1}, 0)
+	tree.InsertBatch(
+		[][]byte{{1, 1}, {1, 2}, {1, 2}, {2, 1}, {3, 1}, {3, 2}},
+		[]ValueType{1, 2, 3, 4, 5, 6},
+	)
+	require.Equal(t, 5, tree.Len())
+	for key, value := range map[string]int{"\x01\x01": 1, "\x01\x02": 3, "\x02\x01": 4, "\x03\x01": 5, "\x03\x02": 6} {
+		rst, found := tree.Get([]byte(key))
+		require.True(t, found)
+		require.Equal(t, value, rst)
+	}
+
+	// unsorted input
+	tree.InsertBatch([][]byte{{4, 2}, {4, 1}}, []ValueType{8, 7})
+	rst, _ := tree.Get([]byte{4, 1})
+	require.Equal(t, 7, rst)
+	require.Equal(t, 7, tree.Len())
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	keys := sortedKeys(rand.New(rand.NewSource(1)), 100_000)
+	values := make([]ValueType, len(keys))
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var tree Tree
+			tree.InsertBatch(keys, values)
+		}
+	})
+	b.Run("insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var tree Tree
+			for j, key := range keys {
+				tree.Insert(key, values[j])
+			}
+		}
+	})
+}
+
+func TestInsertBatchConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	keys := [][]byte{}
+	values := []ValueType{}
+	for i := 0; i < 20000; i += 2 {
+		keys = append(keys, []byte(fmt.Sprintf("%08d\x00", i)))
+		values = append(values, i)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 20000; i += 2 {
+			tree.Insert([]byte(fmt.Sprintf("%08d\x00", i)), i)
+		}
+	}()
+	for i := 0; i < len(keys); i += 100 {
+		tree.InsertBatch(keys[i:i+100], values[i:i+100])
+	}
+	<-done
+	require.Equal(t, 20000, tree.Len())
+	for i := 0; i < 20000; i++ {
+		value, found := tree.Get([]byte(fmt.Sprintf("%08d\x00", i)))
+		require.True(t, found)
+		require.Equal(t, i, value)
+	}
+}
@@ -0,0 +1,71 @@
+package art
+
+import "math/bits"
+
+// bitmap tracks which of the 256 possible children are present, so that the
+// next and previous child are found with a few trailing/leading zeros operations
+// instead of scanning every slot.
+type bitmap [4]uint64
+
+func (b *bitmap) set(k byte) {
+	b[k>>6] |= 1 << (k & 63)
+}
+
+func (b *bitmap) clear(k byte) {
+	b[k>>6] &^= 1 << (k & 63)
+}
+
+func (b *bitmap) has(k byte) bool {
+	return b[k>>6]&(1<<(k&63)) != 0
+}
+
+// next returns the smallest present key that is larger than k, or the smallest
+// present key if k is nil.
+func (b *bitmap) next(k *byte) (byte, bool) {
+	start := 0
+	if k != nil {
+		if *k == 255 {
+			return 0, false
+		}
+		start = int(*k) + 1
+	}
+	word := start >> 6
+	w := b[word] &^ (1<<(start&63) - 1)
+	for {
+		if w != 0 {
+			return byte(word<<6 + bits.TrailingZeros64(w)), true
+		}
+		word++
+		if word == len(b) {
+			return 0, false
+		}
+		w = b[word]
+	}
+}
+
+// prev returns the largest present key that is smaller than k, or the largest
+// present key if k is nil.
+func (b *bitmap) prev(k *byte) (byte, bool) {
+	end := 255
+	if k != nil {
+		if *k == 0 {
+			return 0, false
+		}
+		end = int(*k) - 1
+	}
+	word := end >> 6
+	w := b[word]
+	if shift := 63 - end&63; shift > 0 {
+		w &= ^uint64(0) >> shift
+	}
+	for {
+		if w != 0 {
+			return byte(word<<6 + 63 - bits.LeadingZeros64(w)), true
+		}
+		word--
+		if word < 0 {
+			return 0, false
+		}
+		w = b[word]
+	}
+}
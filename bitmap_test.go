@@ -0,0 +1,86 @@
+package art
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for r := 0; r < 100; r++ {
+		var (
+			b       bitmap
+			present [256]bool
+		)
+		for i := rng.Intn(20); i > 0; i-- {
+			k := byte(rng.Intn(256))
+			b.set(k)
+			present[k] = true
+		}
+		if r%2 == 0 {
+			k := byte(rng.Intn(256))
+			b.clear(k)
+			present[k] = false
+		}
+		expectNext := func(k *byte) (byte, bool) {
+			for i := 0; i < 256; i++ {
+				if present[i] && (k == nil || i > int(*k)) {
+					return byte(i), true
+				}
+			}
+			return 0, false
+		}
+		expectPrev := func(k *byte) (byte, bool) {
+			for i := 255; i >= 0; i-- {
+				if present[i] && (k == nil || i < int(*k)) {
+					return byte(i), true
+				}
+			}
+			return 0, false
+		}
+		next, found := b.next(nil)
+		enext, efound := expectNext(nil)
+		require.Equal(t, efound, found)
+		require.Equal(t, enext, next)
+		prev, found := b.prev(nil)
+		eprev, efound := expectPrev(nil)
+		require.Equal(t, efound, found)
+		require.Equal(t, eprev, prev)
+		for i := 0; i < 256; i++ {
+			k := byte(i)
+			require.Equal(t, present[i], b.has(k))
+			next, found := b.next(&k)
+			enext, efound := expectNext(&k)
+			require.Equal(t, efound, found, "next %d", k)
+			require.Equal(t, enext, next, "next %d", k)
+			prev, found := b.prev(&k)
+			eprev, efound := expectPrev(&k)
+			require.Equal(t, efound, found, "prev %d", k)
+			require.Equal(t, eprev, prev, "prev %d", k)
+		}
+	}
+}
+
+func BenchmarkSparseNext(b *testing.B) {
+	for _, tc := range []struct {
+		desc string
+		node inode
+	}{
+		{"node48", &node48{}},
+		{"node256", &node256{}},
+	} {
+		// children at both ends, scan has to skip the whole node
+		tc.node.addChild(0, &leaf{})
+		tc.node.addChild(255, &leaf{})
+		b.Run(tc.desc, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				k, _ := tc.node.next(nil)
+				_, _ = tc.node.next(&k)
+				k, _ = tc.node.prev(nil)
+				_, _ = tc.node.prev(&k)
+			}
+		})
+	}
+}
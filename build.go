@@ -0,0 +1,142 @@
+package art
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Source is a stream of keys sorted in ascending order, iterator of the tree is a Source.
+type Source interface {
+	Next() bool
+	Key() []byte
+	Value() ValueType
+}
+
+// buildCheckInterval is a number of keys read from the source between checks of the context.
+const buildCheckInterval = 1 << 12
+
+// Build creates the tree with options from the keys of the source. Source is read to the end,
+// and subtrees for disjoint ranges of the keys are built bottom-up by up to parallelism
+// goroutines, then linked under the shared root. Tree is returned only after it is complete.
+// Keys are stored by reference, unless WithCopiedKeys is used, if the key repeats the last
+// value wins. ErrUnsorted is returned if keys are not sorted, and context error if it is
+// canceled while the source is read.
+func Build(ctx context.Context, source Source, parallelism int, opts ...Option) (*Tree, error) {
+	t := New(opts...)
+	var leaves []*leaf
+	for i := 0; source.Next(); i++ {
+		if i%buildCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		l := t.newLeaf(t.keyOf(source.Key()), source.Value())
+		if n := len(leaves); n > 0 {
+			prev := leaves[n-1].key
+			cmp := bytes.Compare(prev, l.key)
+			switch {
+			case cmp > 0:
+				return nil, fmt.Errorf("%w: %x follows %x", ErrUnsorted, l.key, prev)
+			case cmp == 0:
+				leaves[n-1] = l
+				continue
+			case bytes.HasPrefix(l.key, prev):
+				return nil, fmt.Errorf("%w: %x is a prefix of %x", ErrUnsorted, prev, l.key)
+			}
+		}
+		leaves = append(leaves, l)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(leaves) == 0 {
+		return t, nil
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	version := atomic.AddUint64(&t.writes, 1)
+	for _, l := range leaves {
+		l.version = version
+	}
+	b := builder{
+		alloc: t.allocator(),
+		slots: make(chan struct{}, parallelism-1),
+		grain: len(leaves)/(4*parallelism) + 1,
+	}
+	t.root = b.build(leaves, 0)
+	t.size = int64(len(leaves))
+	t.loaded(leaves)
+	return t, nil
+}
+
+// builder builds subtree with the same structure as buildSubtree, children
+// of the large subtrees are built concurrently.
+type builder struct {
+	alloc Allocator
+	// slots limits number of goroutines in addition to the caller.
+	slots chan struct{}
+	// grain is a number of leaves below which subtree is built by the calling goroutine.
+	grain int
+}
+
+func (b *builder) build(leaves []*leaf, depth int) node {
+	if len(leaves) <= b.grain {
+		return buildSubtree(leaves, depth, b.alloc)
+	}
+	first, last := leaves[0].key, leaves[len(leaves)-1].key
+	cp := commonPrefix(first[depth:], last[depth:])
+	if cp > maxPrefixLen {
+		n := b.alloc.inner()
+		n.prefixLen = maxPrefixLen
+		n.node = b.alloc.node4()
+		copy(n.prefix[:], first[depth:depth+maxPrefixLen])
+		n.node.addChild(first[depth+maxPrefixLen], b.build(leaves, depth+maxPrefixLen+1))
+		return n
+	}
+	depth += cp
+	var (
+		// node has at most 256 children, goroutines write into children without reallocation
+		edges    = make([]byte, 0, 256)
+		children = make([]node, 0, 256)
+		wg       sync.WaitGroup
+	)
+	for len(leaves) > 0 {
+		edge := leaves[0].key[depth]
+		count := 1
+		for count < len(leaves) && leaves[count].key[depth] == edge {
+			count++
+		}
+		group := leaves[:count]
+		leaves = leaves[count:]
+		edges = append(edges, edge)
+		children = append(children, nil)
+		child := &children[len(children)-1]
+		select {
+		case b.slots <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				*child = b.build(group, depth+1)
+				<-b.slots
+			}()
+		default:
+			*child = b.build(group, depth+1)
+		}
+	}
+	wg.Wait()
+	n := b.alloc.inner()
+	n.prefixLen = cp
+	n.node = b.alloc.node4()
+	copy(n.prefix[:], first[depth-cp:depth])
+	for i, child := range children {
+		if n.node.full() {
+			n.node = n.node.grow(b.alloc)
+		}
+		n.node.addChild(edges[i], child)
+	}
+	return n
+}
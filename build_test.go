@@ -0,0 +1,111 @@
+package art
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource is a Source over keys with the index of the key as a value.
+type sliceSource struct {
+	keys [][]byte
+	i    int
+}
+
+func (s *sliceSource) Next() bool {
+	s.i++
+	return s.i <= len(s.keys)
+}
+
+func (s *sliceSource) Key() []byte {
+	return s.keys[s.i-1]
+}
+
+func (s *sliceSource) Value() ValueType {
+	return s.i - 1
+}
+
+func TestBuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := sortedKeys(rng, 50_000)
+	var batched Tree
+	values := make([]ValueType, len(keys))
+	for i := range values {
+		values[i] = i
+	}
+	batched.InsertBatch(keys, values)
+
+	for _, parallelism := range []int{1, 8} {
+		tree, err := Build(context.Background(), &sliceSource{keys: keys}, parallelism)
+		require.NoError(t, err)
+		require.Equal(t, len(keys), tree.Len())
+		require.Equal(t, batched.testView(), tree.testView())
+		for i, key := range keys {
+			value, found := tree.Get(key)
+			require.True(t, found)
+			require.Equal(t, i, value)
+		}
+		// built tree is modifiable
+		tree.Delete(keys[0])
+		tree.Insert([]byte("new\x00"), nil)
+		require.Equal(t, len(keys), tree.Len())
+	}
+
+	tree, err := Build(context.Background(), &sliceSource{}, 4)
+	require.NoError(t, err)
+	require.Equal(t, 0, tree.Len())
+}
+
+func TestBuildFromIterator(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tree Tree
+	for _, key := range sortedKeys(rng, 10_000) {
+		tree.Insert(key, key)
+	}
+	rebuilt, err := Build(context.Background(), tree.Iterator(nil, nil), 4, WithMisuseDetection())
+	require.NoError(t, err)
+	require.Equal(t, tree.testView(), rebuilt.testView())
+	keys, values := tree.Dump()
+	rebuiltKeys, rebuiltValues := rebuilt.Dump()
+	require.Equal(t, keys, rebuiltKeys)
+	require.Equal(t, values, rebuiltValues)
+}
+
+func TestBuildInvalid(t *testing.T) {
+	for _, keys := range [][][]byte{
+		{{1}, {3}, {2}},
+		{{1}, {1, 2}},
+	} {
+		_, err := Build(context.Background(), &sliceSource{keys: keys}, 2)
+		require.True(t, errors.Is(err, ErrUnsorted), "error %v", err)
+	}
+	tree, err := Build(context.Background(), &sliceSource{keys: [][]byte{{1}, {1}, {2}}}, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, tree.Len())
+	value, _ := tree.Get([]byte{1})
+	require.Equal(t, 1, value)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Build(ctx, &sliceSource{keys: [][]byte{{1}}}, 2)
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func BenchmarkBuild(b *testing.B) {
+	keys := make([][]byte, 4_000_000)
+	for i := range keys {
+		keys[i] = sequentialKey(i)
+	}
+	for _, parallelism := range []int{1, 4} {
+		b.Run(fmt.Sprintf("parallelism %d", parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := Build(context.Background(), &sliceSource{keys: keys}, parallelism)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
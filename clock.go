@@ -0,0 +1,66 @@
+package art
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock provides current time to the tree. It is used for access stamps
+// of the eviction, and can be replaced to control time in tests or to avoid
+// reading system time on every access.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock configures clock used by the tree, by default system time is used.
+func WithClock(c Clock) Option {
+	return func(t *Tree) {
+		t.clock = c
+	}
+}
+
+// CoarseClock caches current time and refreshes it in the background every resolution.
+// Now is a single atomic load, which is cheaper than reading system time on hot paths.
+type CoarseClock struct {
+	now        int64
+	stop, done chan struct{}
+	once       sync.Once
+}
+
+// NewCoarseClock starts the clock, Stop must be called to release the background goroutine.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	c := &CoarseClock{now: time.Now().UnixNano(), stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(resolution)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case now := <-ticker.C:
+				atomic.StoreInt64(&c.now, now.UnixNano())
+			}
+		}
+	}()
+	return c
+}
+
+func (c *CoarseClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.now))
+}
+
+// Stop stops refreshing the clock, Now returns the last refreshed time afterwards.
+func (c *CoarseClock) Stop() {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+	<-c.done
+}
@@ -0,0 +1,54 @@
+package art
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type manualClock struct {
+	now int64
+}
+
+func (c *manualClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.now))
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	atomic.AddInt64(&c.now, int64(d))
+}
+
+func TestSampledEvictionClock(t *testing.T) {
+	clock := &manualClock{now: 1}
+	limit := 100
+	tree := New(WithClock(clock), WithSampledEviction(limit, limit))
+	for i := 0; i < limit; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	clock.advance(time.Second)
+	// every key except the first one is accessed after insert
+	for i := 1; i < limit; i++ {
+		_, found := tree.Get(sequentialKey(i))
+		require.True(t, found)
+	}
+	tree.Insert(sequentialKey(limit), limit)
+	require.Equal(t, limit, tree.Len())
+	_, found := tree.Get(sequentialKey(0))
+	require.False(t, found)
+}
+
+func TestCoarseClock(t *testing.T) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Stop()
+	start := clock.Now()
+	require.WithinDuration(t, time.Now(), start, time.Second)
+	require.Eventually(t, func() bool {
+		return clock.Now().After(start)
+	}, time.Second, time.Millisecond)
+	clock.Stop()
+	stopped := clock.Now()
+	time.Sleep(5 * time.Millisecond)
+	require.Equal(t, stopped, clock.Now())
+}
@@ -0,0 +1,10 @@
+//go:build !artunsafe || race
+// +build !artunsafe race
+
+package art
+
+import "testing"
+
+// skipUnsafe skips the test that uses the tree concurrently, if locks are disabled
+// with the artunsafe build tag.
+func skipUnsafe(testing.TB) {}
@@ -0,0 +1,10 @@
+//go:build artunsafe && !race
+// +build artunsafe,!race
+
+package art
+
+import "testing"
+
+func skipUnsafe(tb testing.TB) {
+	tb.Skip("tree is not safe for concurrent use with artunsafe build tag")
+}
@@ -0,0 +1,118 @@
+package art
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Config is a snapshot of the tree configuration.
+type Config struct {
+	// MaxEntries is a limit configured by WithSampledEviction, zero if eviction is disabled.
+	MaxEntries int
+	// MaxBytes is a limit configured by WithMaxBytes, zero if the limit is disabled.
+	MaxBytes int64
+	// EvictionSamples is a number of keys sampled to select a victim.
+	EvictionSamples int
+	// MemoryLimit is a limit configured by WithMemoryLimit, zero if limit is disabled.
+	MemoryLimit int64
+	// Profiling is a sampling rate configured by WithProfiling, zero if profiling is disabled.
+	Profiling int
+	// TinyLFU is true if admission is enabled with WithTinyLFU.
+	TinyLFU bool
+	// MisuseDetection is true if enabled with WithMisuseDetection.
+	MisuseDetection bool
+	// CopiedKeys is true if enabled with WithCopiedKeys.
+	CopiedKeys bool
+	// KeyTransform is true if transform was configured with WithKeyTransform.
+	KeyTransform bool
+	// WAL is true if modifications are logged with WithWAL.
+	WAL bool
+	// Metrics is true if counters are reported with WithMetrics.
+	Metrics bool
+	// Backoff is true if the policy for restarts was configured with WithBackoff.
+	Backoff bool
+}
+
+// Config returns current configuration of the tree.
+func (t *Tree) Config() Config {
+	c := Config{
+		TinyLFU:         t.admission != nil,
+		MisuseDetection: t.guard != nil,
+		CopiedKeys:      t.copyKeys,
+		KeyTransform:    t.transform != nil,
+		WAL:             t.wal != nil,
+		Metrics:         t.metrics.Load() != nil,
+		Backoff:         t.backoff.Load() != nil,
+	}
+	if t.evictor != nil {
+		if max := atomic.LoadInt64(&t.evictor.max); max != unlimited {
+			c.MaxEntries = int(max)
+		}
+		if max := atomic.LoadInt64(&t.evictor.maxBytes); max != unlimited {
+			c.MaxBytes = max
+		}
+		c.EvictionSamples = int(atomic.LoadInt64(&t.evictor.samples))
+	}
+	if t.limiter != nil {
+		c.MemoryLimit = atomic.LoadInt64(&t.limiter.limit)
+	}
+	if t.profiler != nil {
+		c.Profiling = int(atomic.LoadUint64(&t.profiler.every))
+	}
+	return c
+}
+
+// Reconfigure changes settings of the options that the tree was created with, while the tree
+// is in use. WithSampledEviction, WithMaxBytes, WithMemoryLimit and WithProfiling can be
+// reconfigured, every setting is updated atomically. WithMetrics and WithBackoff can be set,
+// replaced or disabled at any time. Eviction of the keys above the new limit happens
+// on the following inserts, writers blocked by the memory limit are woken up if it was raised.
+// If any of the options can't be changed at runtime ErrNotReconfigurable is returned
+// and the tree is not modified.
+func (t *Tree) Reconfigure(opts ...Option) error {
+	next := &Tree{}
+	for _, opt := range opts {
+		opt(next)
+	}
+	switch {
+	case next.evictor != nil && t.evictor == nil:
+		return fmt.Errorf("%w: eviction is disabled", ErrNotReconfigurable)
+	case next.limiter != nil && t.limiter == nil:
+		return fmt.Errorf("%w: memory limit is disabled", ErrNotReconfigurable)
+	case next.profiler != nil && t.profiler == nil:
+		return fmt.Errorf("%w: profiling is disabled", ErrNotReconfigurable)
+	case next.admission != nil, next.alloc != nil, next.clock != nil, next.guard != nil,
+		next.copyKeys, next.transform != nil, next.encode != nil, next.decode != nil,
+		next.wal != nil:
+		return ErrNotReconfigurable
+	}
+	if next.evictor != nil {
+		// limits that are not configured by opts are not changed
+		if next.evictor.max != unlimited {
+			atomic.StoreInt64(&t.evictor.max, next.evictor.max)
+		}
+		if next.evictor.maxBytes != unlimited {
+			atomic.StoreInt64(&t.evictor.maxBytes, next.evictor.maxBytes)
+		}
+		atomic.StoreInt64(&t.evictor.samples, next.evictor.samples)
+	}
+	if next.limiter != nil {
+		t.limiter.setLimit(next.limiter.limit)
+	}
+	if next.profiler != nil {
+		atomic.StoreUint64(&t.profiler.every, next.profiler.every)
+	}
+	if s := next.metrics.Load(); s != nil {
+		if s.sink == nil {
+			s = nil
+		}
+		t.metrics.Store(s)
+	}
+	if b := next.backoff.Load(); b != nil {
+		if b.policy == nil {
+			b = nil
+		}
+		t.backoff.Store(b)
+	}
+	return nil
+}
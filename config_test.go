@@ -0,0 +1,83 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	var tree Tree
+	require.Equal(t, Config{}, tree.Config())
+
+	tree2 := New(WithSampledEviction(100, 5), WithMemoryLimit(1<<20), WithProfiling(10), WithCopiedKeys())
+	require.Equal(t, Config{
+		MaxEntries:      100,
+		EvictionSamples: 5,
+		MemoryLimit:     1 << 20,
+		Profiling:       10,
+		CopiedKeys:      true,
+	}, tree2.Config())
+}
+
+func TestReconfigureEviction(t *testing.T) {
+	tree := New(WithSampledEviction(100, 5))
+	for i := 0; i < 100; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	require.Equal(t, 100, tree.Len())
+
+	require.NoError(t, tree.Reconfigure(WithSampledEviction(50, 3)))
+	require.Equal(t, 50, tree.Config().MaxEntries)
+	require.Equal(t, 3, tree.Config().EvictionSamples)
+	tree.Insert(sequentialKey(100), 100)
+	require.Equal(t, 50, tree.Len())
+}
+
+func TestReconfigureMemoryLimit(t *testing.T) {
+	skipUnsafe(t)
+	keySize := int64(len(sequentialKey(0)))
+	tree := New(WithMemoryLimit(10 * (leafOverhead + keySize)))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tree.TryInsert(sequentialKey(i), i))
+	}
+	inserted := make(chan struct{})
+	go func() {
+		tree.Insert(sequentialKey(10), 10)
+		close(inserted)
+	}()
+	select {
+	case <-inserted:
+		require.FailNow(t, "insert must block until limit is raised")
+	case <-time.After(10 * time.Millisecond):
+	}
+	require.NoError(t, tree.Reconfigure(WithMemoryLimit(20*(leafOverhead+keySize))))
+	select {
+	case <-inserted:
+	case <-time.After(time.Second):
+		require.FailNow(t, "insert wasn't unblocked")
+	}
+	require.Equal(t, 11, tree.Len())
+}
+
+func TestReconfigureErrors(t *testing.T) {
+	tree := New(WithProfiling(10))
+	for _, opt := range []Option{
+		WithSampledEviction(10, 1),
+		WithMemoryLimit(10),
+		WithCopiedKeys(),
+		WithKeyTransform(bytes.ToLower),
+		WithMisuseDetection(),
+	} {
+		require.True(t, errors.Is(tree.Reconfigure(opt), ErrNotReconfigurable))
+	}
+	// tree is not modified if any of the options is rejected
+	require.True(t, errors.Is(tree.Reconfigure(WithProfiling(2), WithCopiedKeys()), ErrNotReconfigurable))
+	require.Equal(t, 10, tree.Config().Profiling)
+
+	require.NoError(t, tree.Reconfigure(WithProfiling(2)))
+	require.Equal(t, 2, tree.Config().Profiling)
+}
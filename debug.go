@@ -0,0 +1,95 @@
+//go:build artdebug
+// +build artdebug
+
+package art
+
+import "fmt"
+
+// debug enables validation of the inner nodes every time when they are unlocked
+// after modification. Enabled with artdebug build tag, meant for stress testing.
+const debug = true
+
+const canaryAlive = 0x5afe5afe5afe5afe
+
+// canary is set when node is validated for the first time and must remain intact
+// for the whole lifetime of the node.
+type canary struct {
+	value uint64
+}
+
+func (c *canary) check(n *inner) {
+	switch c.value {
+	case 0:
+		c.value = canaryAlive
+	case canaryAlive:
+	default:
+		panic(fmt.Sprintf("art: canary %x is corrupted in %v", c.value, n))
+	}
+}
+
+// validate panics if node invariants are violated.
+func (n *inner) validate() {
+	n.canary.check(n)
+	if n.prefixLen < 0 || n.prefixLen > maxPrefixLen {
+		panic(fmt.Sprintf("art: invalid prefix length %d in %v", n.prefixLen, n))
+	}
+	if n.node == nil {
+		panic(fmt.Sprintf("art: inner node without children %p", n))
+	}
+	switch nn := n.node.(type) {
+	case *node4:
+		validateSorted(n, int(nn.lth), nn.keys[:], nn.childs[:])
+	case *node16:
+		validateSorted(n, int(nn.lth), nn.keys[:], nn.childs[:])
+	case *node48:
+		var used [48]bool
+		count := 0
+		for k, idx := range nn.keys {
+			if idx == 0 {
+				continue
+			}
+			if int(idx) > len(nn.childs) || used[idx-1] || nn.childs[idx-1] == nil {
+				panic(fmt.Sprintf("art: invalid index %d for key %x in %v", idx, k, n))
+			}
+			used[idx-1] = true
+			count++
+		}
+		validatePresent(n, &nn.present, func(k byte) bool { return nn.keys[k] != 0 })
+		if count != int(nn.lth) {
+			panic(fmt.Sprintf("art: %d children with length %d in %v", count, nn.lth, n))
+		}
+	case *node256:
+		count := 0
+		for k := 0; k < 256; k++ {
+			if nn.load(byte(k)) != nil {
+				count++
+			}
+		}
+		validatePresent(n, &nn.present, func(k byte) bool { return nn.load(k) != nil })
+		if count != int(nn.lth) {
+			panic(fmt.Sprintf("art: %d children with length %d in %v", count, nn.lth, n))
+		}
+	}
+}
+
+func validatePresent(n *inner, present *bitmap, has func(k byte) bool) {
+	for k := 0; k < 256; k++ {
+		if present.has(byte(k)) != has(byte(k)) {
+			panic(fmt.Sprintf("art: presence bitmap is inconsistent for key %x in %v", k, n))
+		}
+	}
+}
+
+func validateSorted(n *inner, lth int, keys []byte, childs []node) {
+	if lth > len(keys) {
+		panic(fmt.Sprintf("art: length %d overflows capacity in %v", lth, n))
+	}
+	for i := range childs {
+		if (i < lth) != (childs[i] != nil) {
+			panic(fmt.Sprintf("art: child %d doesn't match length %d in %v", i, lth, n))
+		}
+		if i > 0 && i < lth && keys[i-1] >= keys[i] {
+			panic(fmt.Sprintf("art: keys are not sorted in %v", n))
+		}
+	}
+}
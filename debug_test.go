@@ -0,0 +1,39 @@
+//go:build artdebug
+// +build artdebug
+
+package art
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugValidation(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 100; i++ {
+		tree.Insert([]byte{1, byte(i)}, i)
+	}
+	root := tree.root.(*inner)
+	require.NotPanics(t, func() {
+		root.lock.Lock()
+		root.unlock()
+	})
+
+	root.canary.value = 1
+	require.Panics(t, func() {
+		root.lock.Lock()
+		root.unlock()
+	})
+	root.lock.Unlock()
+	root.canary.value = canaryAlive
+
+	nn := root.node.(*node256)
+	nn.lth++
+	require.Panics(t, func() {
+		root.lock.Lock()
+		root.unlock()
+	})
+	root.lock.Unlock()
+	nn.lth--
+}
@@ -0,0 +1,140 @@
+package art
+
+import (
+	"sync/atomic"
+)
+
+// DeleteRange removes all keys in range (start, end] and returns number of removed keys.
+// nil start or end means that range is unbounded on that side.
+func (t *Tree) DeleteRange(start, end []byte) int {
+	return t.DeleteIn(Range{Start: start, End: end, Inclusivity: IncludeEnd})
+}
+
+// DeleteIn removes all keys in the range and returns number of removed keys.
+// Subtrees with all keys inside the range are detached from the parent without visiting
+// them again, only subtrees that overlap with the boundaries of the range are descended.
+// Nodes on the path to the boundaries are locked for writing until the operation completes.
+func (t *Tree) DeleteIn(rng Range) int {
+	t.checkPoisoned()
+	rng.Start, rng.End = t.keyOf(rng.Start), t.keyOf(rng.End)
+	return t.deleteIn(rng)
+}
+
+// deleteIn removes keys in the range with bounds that were already transformed.
+func (t *Tree) deleteIn(rng Range) int {
+	atomic.AddUint64(&t.writes, 1)
+	if t.wal != nil {
+		t.wal.lockAll()
+		defer t.wal.unlockAll()
+	}
+	r := pruning{Range: rng, alloc: t.allocator(), guard: t.guard}
+	t.lock.Lock()
+	switch root := t.root.(type) {
+	case *leaf:
+		if r.Contains(root.key) {
+			t.root = nil
+			r.removed(root)
+		}
+	case *inner:
+		root.lock.Lock()
+		t.root = r.prune(root, nil)
+		root.unlock()
+	}
+	t.lock.Unlock()
+	if t.wal != nil && r.count > 0 {
+		t.wal.deleteIn(rng)
+	}
+	atomic.AddInt64(&t.size, -int64(r.count))
+	t.count(MetricDeletes, int64(r.count))
+	t.count(MetricShrinks, int64(r.shrinks))
+	if t.limiter != nil {
+		t.limiter.add(-r.bytes)
+	}
+	if t.evictor != nil {
+		t.evictor.add(-r.bytes)
+	}
+	return r.count
+}
+
+// pruning removes keys in range and accumulates stats about removed leaves.
+type pruning struct {
+	Range
+	alloc Allocator
+	guard *guard
+
+	count int
+	bytes int64
+	// shrinks is a number of nodes replaced by a smaller kind.
+	shrinks int
+}
+
+func (r *pruning) removed(l *leaf) {
+	r.count++
+	r.bytes += leafSize(l)
+	if r.guard != nil {
+		r.guard.forget(l)
+	}
+}
+
+// prune removes keys in range from the subtree of n, n must be locked for writing.
+// Returns node that should replace n in the parent, nil if subtree is empty.
+func (r *pruning) prune(n *inner, path []byte) node {
+	path = append(path, n.prefix[:n.prefixLen]...)
+	var (
+		keys    []byte
+		pointer *byte
+	)
+	for {
+		b, child := n.node.next(pointer)
+		if child == nil {
+			break
+		}
+		keys = append(keys, b)
+		pointer = &b
+	}
+	for _, b := range keys {
+		idx, child := n.node.child(b)
+		prefix := append(path, b)
+		if r.disjoint(prefix) {
+			continue
+		}
+		if r.covers(prefix) {
+			_ = child.walk(func(n node, _ int) bool {
+				if l, isLeaf := n.(*leaf); isLeaf {
+					r.removed(l)
+				}
+				return true
+			}, 0)
+			n.node.replace(idx, nil)
+			continue
+		}
+		switch child := child.(type) {
+		case *leaf:
+			if r.Contains(child.key) {
+				r.removed(child)
+				n.node.replace(idx, nil)
+			}
+		case *inner:
+			child.lock.Lock()
+			if replacement := r.prune(child, prefix); replacement != node(child) {
+				n.node.replace(idx, replacement)
+			}
+			child.unlock()
+		}
+	}
+	total, _ := children(n.node, 0)
+	switch {
+	case total == 0:
+		return nil
+	case total == 1 && n.prefixLen < maxPrefixLen:
+		// path compression, same as when the last but one child is deleted
+		b, child := n.node.next(nil)
+		n.prefix[n.prefixLen] = b
+		return child.inherit(n.prefix, n.prefixLen+1, r.alloc)
+	}
+	for overprovisioned(n.node) {
+		n.node = n.node.shrink(r.alloc)
+		r.shrinks++
+	}
+	return n
+}
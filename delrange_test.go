@@ -0,0 +1,86 @@
+package art
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRange(t *testing.T) {
+	key := func(i uint32) []byte {
+		k := make([]byte, 5)
+		binary.BigEndian.PutUint32(k, i)
+		return k
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	for round := 0; round < 50; round++ {
+		var (
+			tree Tree
+			keys [][]byte
+		)
+		n := rng.Intn(5000) + 1
+		for i := 0; i < n; i++ {
+			k := key(uint32(rng.Intn(1 << 16)))
+			if _, found := tree.Get(k); !found {
+				keys = append(keys, k)
+			}
+			tree.Insert(k, k)
+		}
+		var start, end []byte
+		if rng.Intn(4) > 0 {
+			start = key(uint32(rng.Intn(1 << 16)))
+		}
+		if rng.Intn(4) > 0 {
+			end = key(uint32(rng.Intn(1 << 16)))
+		}
+		r := Range{Start: start, End: end, Inclusivity: IncludeEnd}
+		var expected [][]byte
+		removed := 0
+		for _, k := range keys {
+			if r.Contains(k) {
+				removed++
+			} else {
+				expected = append(expected, k)
+			}
+		}
+		require.Equal(t, removed, tree.DeleteRange(start, end), "start %x end %x", start, end)
+		require.Equal(t, len(expected), tree.Len())
+		for _, k := range keys {
+			_, found := tree.Get(k)
+			require.Equal(t, !r.Contains(k), found, "key %x", k)
+		}
+		var rst [][]byte
+		for iter := tree.Iterator(nil, nil); iter.Next(); {
+			rst = append(rst, iter.Key())
+		}
+		require.Len(t, rst, len(expected))
+		for i := 1; i < len(rst); i++ {
+			require.True(t, bytes.Compare(rst[i-1], rst[i]) < 0)
+		}
+		for _, k := range expected {
+			tree.Delete(k)
+		}
+		require.True(t, tree.Empty())
+	}
+}
+
+func TestDeleteRangePrunesSubtree(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			tree.Insert([]byte{byte(i), byte(j), 0}, i)
+		}
+	}
+	require.Equal(t, 30, tree.DeleteRange([]byte{3}, []byte{5, 9, 0}))
+	require.Equal(t, 70, tree.Len())
+	_, found := tree.Get([]byte{2, 9, 0})
+	require.True(t, found)
+	_, found = tree.Get([]byte{3, 0, 0})
+	require.False(t, found)
+
+	require.Equal(t, 70, tree.DeleteRange(nil, nil))
+	require.True(t, tree.Empty())
+}
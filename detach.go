@@ -0,0 +1,250 @@
+package art
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// DetachPrefix removes all keys with the prefix and returns them as a new tree.
+// Keys are not removed one by one, subtree with the prefix is detached from the path
+// under write locks as by DeleteIn, and becomes the root of the new tree.
+// Before the path is unlocked every inner node of the subtree is locked, copied into
+// the new tree and marked obsolete. Writes that completed in the subtree before it was
+// locked are moved to the new tree, writes that didn't complete are restarted from the root
+// and applied to the source tree, as if they started after DetachPrefix returned.
+// Leaves are counted while the subtree is locked, size and memory accounting of both trees
+// include every key once.
+// New tree uses the same allocator, clock, key transform and value codec, other options
+// are not inherited. If no keys have the prefix the new tree is empty.
+func (t *Tree) DetachPrefix(prefix []byte) *Tree {
+	t.checkPoisoned()
+	prefix = t.keyOf(prefix)
+	atomic.AddUint64(&t.writes, 1)
+	if t.wal != nil {
+		t.wal.lockAll()
+		defer t.wal.unlockAll()
+	}
+	a := t.allocator()
+	d := detaching{prefix: prefix, alloc: a, removed: pruning{alloc: a, guard: t.guard}}
+	t.lock.Lock()
+	switch root := t.root.(type) {
+	case *leaf:
+		if bytes.HasPrefix(root.key, prefix) {
+			d.detach(root, 0)
+			t.root = nil
+		}
+	case *inner:
+		root.lock.Lock()
+		switch matched, covered := d.match(root, 0); {
+		case covered:
+			d.detach(root, 0)
+			t.root = nil
+		case matched:
+			t.root = d.cut(root, 0)
+			root.unlock()
+		default:
+			root.unlock()
+		}
+	}
+	d.release()
+	t.lock.Unlock()
+
+	detached := &Tree{
+		alloc:     t.alloc,
+		clock:     t.clock,
+		copyKeys:  t.copyKeys,
+		transform: t.transform,
+		encode:    t.encode,
+		decode:    t.decode,
+	}
+	if d.subtree == nil {
+		return detached
+	}
+	r := &d.removed
+	if t.wal != nil {
+		t.wal.deleteIn(PrefixRange(prefix))
+	}
+	atomic.AddInt64(&t.size, -int64(r.count))
+	if t.limiter != nil {
+		t.limiter.add(-r.bytes)
+	}
+	if t.evictor != nil {
+		t.evictor.add(-r.bytes)
+	}
+	detached.root = rooted(d.subtree, prefix[:d.depth], a)
+	detached.size = int64(r.count)
+	return detached
+}
+
+// detaching removes the subtree with keys that have the prefix.
+type detaching struct {
+	prefix []byte
+	alloc  Allocator
+
+	// subtree is the detached node, its prefix starts at depth.
+	subtree node
+	depth   int
+	// sealed are the original inner nodes of the subtree, locked until release.
+	sealed  []*inner
+	removed pruning
+}
+
+// detach seals n, that starts at depth, as the detached subtree.
+// n must be locked for writing if it is an inner node.
+func (d *detaching) detach(n node, depth int) {
+	d.subtree, d.depth = d.seal(n), depth
+}
+
+// seal returns copy of the subtree n, where every inner node is replaced by a new one
+// that shares the children. n must be locked for writing if it is an inner node, its
+// descendants are locked by seal. Locks are held until release, so that writers that
+// descended below the cut point either complete before the copy or observe the obsolete node.
+func (d *detaching) seal(n node) node {
+	switch n := n.(type) {
+	case *leaf:
+		d.removed.removed(n)
+		return n
+	case *inner:
+		d.sealed = append(d.sealed, n)
+		nn := d.alloc.inner()
+		nn.prefix, nn.prefixLen, nn.node = n.prefix, n.prefixLen, n.node
+		var pointer *byte
+		for {
+			b, child := nn.node.next(pointer)
+			if child == nil {
+				return nn
+			}
+			if in, isInner := child.(*inner); isInner {
+				in.lock.Lock()
+				idx, _ := nn.node.child(b)
+				nn.node.replace(idx, d.seal(in))
+			} else {
+				d.seal(child)
+			}
+			pointer = &b
+		}
+	}
+	return n
+}
+
+// drop removes the subtree n without copying it. Inner nodes are locked as by seal,
+// and leaves are counted as removed. n must be locked for writing if it is an inner node.
+func (d *detaching) drop(n node) {
+	switch n := n.(type) {
+	case *leaf:
+		d.removed.removed(n)
+	case *inner:
+		d.sealed = append(d.sealed, n)
+		var pointer *byte
+		for {
+			b, child := n.node.next(pointer)
+			if child == nil {
+				return
+			}
+			if in, isInner := child.(*inner); isInner {
+				in.lock.Lock()
+			}
+			d.drop(child)
+			pointer = &b
+		}
+	}
+}
+
+// release marks sealed nodes obsolete, readers and writers that hold their versions
+// restart from the root.
+func (d *detaching) release() {
+	for _, n := range d.sealed {
+		n.lock.UnlockObsolete()
+	}
+}
+
+// match compares prefix of n, that starts at depth, with the prefix.
+// matched is true if n may store keys with the prefix, covered is true if all keys
+// of n have the prefix.
+func (d *detaching) match(n *inner, depth int) (matched, covered bool) {
+	for i := 0; i < n.prefixLen; i++ {
+		if depth+i == len(d.prefix) {
+			return true, true
+		}
+		if n.prefix[i] != d.prefix[depth+i] {
+			return false, false
+		}
+	}
+	return true, depth+n.prefixLen == len(d.prefix)
+}
+
+// cut detaches the subtree from the subtree of n, n must be locked for writing and
+// its prefix must match but not cover the prefix.
+// Returns node that should replace n in the parent.
+func (d *detaching) cut(n *inner, depth int) node {
+	depth += n.prefixLen
+	idx, child := n.node.child(d.prefix[depth])
+	switch child := child.(type) {
+	case nil:
+		return n
+	case *leaf:
+		if !bytes.HasPrefix(child.key, d.prefix) {
+			return n
+		}
+		d.detach(child, depth+1)
+		n.node.replace(idx, nil)
+	case *inner:
+		child.lock.Lock()
+		switch matched, covered := d.match(child, depth+1); {
+		case covered:
+			// unlocked by release
+			d.detach(child, depth+1)
+			n.node.replace(idx, nil)
+		case matched:
+			defer child.unlock()
+			if replacement := d.cut(child, depth+1); replacement != node(child) {
+				n.node.replace(idx, replacement)
+			}
+			return n
+		default:
+			child.unlock()
+			return n
+		}
+	}
+	total, _ := children(n.node, 0)
+	switch {
+	case total == 0:
+		return nil
+	case total == 1 && n.prefixLen < maxPrefixLen:
+		// path compression, same as when the last but one child is deleted
+		b, last := n.node.next(nil)
+		n.prefix[n.prefixLen] = b
+		if in, isInner := last.(*inner); isInner {
+			in.lock.Lock()
+			defer in.unlock()
+		}
+		return last.inherit(n.prefix, n.prefixLen+1, d.alloc)
+	}
+	for overprovisioned(n.node) {
+		n.node = n.node.shrink(d.alloc)
+	}
+	return n
+}
+
+// rooted returns subtree n, that starts after the path, as a subtree that starts at depth zero.
+func rooted(n node, path []byte, a Allocator) node {
+	in, isInner := n.(*inner)
+	if !isInner || len(path) == 0 {
+		// leaves store complete keys
+		return n
+	}
+	if len(path) > maxPrefixLen {
+		// prefix is split into multiple nodes, same as in leaf expansion
+		chain := a.inner()
+		chain.prefixLen = maxPrefixLen
+		chain.node = a.node4()
+		copy(chain.prefix[:], path[:maxPrefixLen])
+		chain.node.addChild(path[maxPrefixLen], rooted(n, path[maxPrefixLen+1:], a))
+		return chain
+	}
+	var prefix [maxPrefixLen]byte
+	copy(prefix[:], path)
+	in.lock.Lock()
+	defer in.unlock()
+	return in.inherit(prefix, len(path), a)
+}
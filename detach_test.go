@@ -0,0 +1,154 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachPrefix(t *testing.T) {
+	var keys [][]byte
+	for tenant := 0; tenant < 20; tenant++ {
+		for i := 0; i < 100; i++ {
+			keys = append(keys, []byte(fmt.Sprintf("tenant:%02d:long-shared-segment:%03d\x00", tenant, i)))
+		}
+	}
+	keys = append(keys, []byte("tenant:21\x00"), []byte("other\x00"))
+	for _, prefix := range [][]byte{
+		[]byte("tenant:05:"),
+		[]byte("tenant:05:long-shared-segment:0"),
+		[]byte("tenant:1"),
+		[]byte("tenant:21"),
+		[]byte("tenant:"),
+		[]byte("tenant:99"),
+		[]byte("tenant:05:long-shared-segment:042\x00"),
+		[]byte("x"),
+		nil,
+	} {
+		t.Run(string(prefix), func(t *testing.T) {
+			tree := New(WithMisuseDetection())
+			for _, key := range keys {
+				tree.Insert(key, key)
+			}
+			detached := tree.DetachPrefix(prefix)
+			var remaining, removed [][]byte
+			for _, key := range keys {
+				if bytes.HasPrefix(key, prefix) {
+					removed = append(removed, key)
+				} else {
+					remaining = append(remaining, key)
+				}
+			}
+			for _, tc := range []struct {
+				tree *Tree
+				keys [][]byte
+			}{{tree, remaining}, {detached, removed}} {
+				require.Equal(t, len(tc.keys), tc.tree.Len())
+				var rst [][]byte
+				for iter := tc.tree.Iterator(nil, nil); iter.Next(); {
+					rst = append(rst, iter.Key())
+				}
+				require.ElementsMatch(t, tc.keys, rst)
+				for _, key := range tc.keys {
+					value, found := tc.tree.Get(key)
+					require.True(t, found, "key %s", key)
+					require.Equal(t, key, value)
+				}
+			}
+			// both trees remain modifiable, new keys split prefixes of the detached nodes
+			for _, key := range removed {
+				tree.Insert(key, nil)
+				extended := append(append([]byte(nil), key[:len(key)-1]...), 1, 0)
+				detached.Insert(extended, nil)
+				detached.Delete(key)
+				_, found := detached.Get(extended)
+				require.True(t, found)
+				detached.Delete(extended)
+			}
+			require.Equal(t, len(keys), tree.Len())
+			require.Equal(t, 0, detached.Len())
+		})
+	}
+}
+
+func TestDetachPrefixConcurrentReads(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	for i := 0; i < 10_000; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// keys below 8192 are never detached
+			_, found := tree.Get(sequentialKey(i % 8192))
+			require.True(t, found)
+		}
+	}()
+	for i := 0x20; i < 0x27; i++ {
+		// every key with the second lowest byte i, 256 keys each
+		detached := tree.DetachPrefix([]byte{0, 0, 0, 0, 0, 0, byte(i)})
+		require.Equal(t, 256, detached.Len())
+	}
+	close(stop)
+	<-done
+	require.Equal(t, 10_000-7*256, tree.Len())
+}
+
+func TestDetachPrefixConcurrentWrites(t *testing.T) {
+	skipUnsafe(t)
+	// writers insert keys into the detached subtree, every key must end up in exactly
+	// one of the trees, and sizes must match the keys
+	var (
+		tree     Tree
+		wg       sync.WaitGroup
+		stop     = make(chan struct{})
+		detached []*Tree
+		written  [2]int
+		inserts  int64
+	)
+	for w := range written {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					written[w] = i
+					return
+				default:
+				}
+				tree.Insert(append(sequentialKey(i), byte(w)), i)
+				atomic.AddInt64(&inserts, 1)
+			}
+		}(w)
+	}
+	for atomic.LoadInt64(&inserts) < 100_000 {
+		detached = append(detached, tree.DetachPrefix([]byte{0, 0, 0, 0, 0}))
+	}
+	close(stop)
+	wg.Wait()
+
+	seen := map[string]struct{}{}
+	for _, tr := range append(detached, &tree) {
+		keys, _ := tr.Dump()
+		require.Equal(t, len(keys), tr.Len())
+		for _, key := range keys {
+			_, exists := seen[string(key)]
+			require.False(t, exists, "key %x", key)
+			seen[string(key)] = struct{}{}
+		}
+	}
+	require.Len(t, seen, written[0]+written[1])
+}
@@ -0,0 +1,238 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// reference is an ordered map used as a model of the tree in differential tests.
+type reference struct {
+	entries []kv
+}
+
+func (r *reference) find(key []byte) (int, bool) {
+	i := sort.Search(len(r.entries), func(i int) bool {
+		return bytes.Compare(r.entries[i].key, key) >= 0
+	})
+	return i, i < len(r.entries) && bytes.Equal(r.entries[i].key, key)
+}
+
+func (r *reference) set(key []byte, value interface{}) (interface{}, bool) {
+	i, found := r.find(key)
+	if found {
+		old := r.entries[i].value
+		r.entries[i].value = value
+		return old, true
+	}
+	r.entries = append(r.entries, kv{})
+	copy(r.entries[i+1:], r.entries[i:])
+	r.entries[i] = kv{key: key, value: value}
+	return nil, false
+}
+
+func (r *reference) get(key []byte) (interface{}, bool) {
+	i, found := r.find(key)
+	if !found {
+		return nil, false
+	}
+	return r.entries[i].value, true
+}
+
+func (r *reference) remove(key []byte) (interface{}, bool) {
+	i, found := r.find(key)
+	if !found {
+		return nil, false
+	}
+	old := r.entries[i].value
+	r.entries = append(r.entries[:i], r.entries[i+1:]...)
+	return old, true
+}
+
+func (r *reference) removeRange(rng Range) int {
+	rst := r.entries[:0]
+	for _, entry := range r.entries {
+		if !rng.Contains(entry.key) {
+			rst = append(rst, entry)
+		}
+	}
+	removed := len(r.entries) - len(rst)
+	r.entries = rst
+	return removed
+}
+
+func (r *reference) scan(rng Range) []kv {
+	var rst []kv
+	for _, entry := range r.entries {
+		if rng.Contains(entry.key) {
+			rst = append(rst, entry)
+		}
+	}
+	return rst
+}
+
+// differential applies the same randomized operations to the tree and the reference,
+// compares result of every operation, and full iteration order after every batch.
+type differential struct {
+	t    *testing.T
+	rng  *rand.Rand
+	tree *Tree
+	ref  reference
+	// trace of the operations in the current batch, logged on failure
+	trace []string
+}
+
+// key returns random null terminated key from the small alphabet, so that operations
+// frequently hit existing keys, and none of the keys is a prefix of another key.
+func (d *differential) key() []byte {
+	key := make([]byte, 1+d.rng.Intn(6), 7)
+	for i := range key {
+		key[i] = 'a' + byte(d.rng.Intn(4))
+	}
+	return append(key, 0)
+}
+
+// bound returns random range bound, nil is returned occasionally.
+func (d *differential) bound() []byte {
+	if d.rng.Intn(5) == 0 {
+		return nil
+	}
+	return d.key()[:1+d.rng.Intn(3)]
+}
+
+func (d *differential) log(op string, key []byte) {
+	d.trace = append(d.trace, op+" "+string(bytes.TrimRight(key, "\x00")))
+}
+
+func (d *differential) step() {
+	key := d.key()
+	switch d.rng.Intn(10) {
+	case 0, 1, 2:
+		d.log("set", key)
+		value := d.rng.Int()
+		old, replaced := d.tree.Set(key, value)
+		eold, ereplaced := d.ref.set(key, value)
+		require.Equal(d.t, ereplaced, replaced, d.trace)
+		require.Equal(d.t, eold, old, d.trace)
+	case 3, 4:
+		d.log("remove", key)
+		old, removed := d.tree.Remove(key)
+		eold, eremoved := d.ref.remove(key)
+		require.Equal(d.t, eremoved, removed, d.trace)
+		require.Equal(d.t, eold, old, d.trace)
+	case 5, 6:
+		d.log("get", key)
+		value, found := d.tree.Get(key)
+		evalue, efound := d.ref.get(key)
+		require.Equal(d.t, efound, found, d.trace)
+		require.Equal(d.t, evalue, value, d.trace)
+	case 7:
+		d.log("next", key)
+		next, value, found := d.tree.Next(key)
+		i, exists := d.ref.find(key)
+		if exists {
+			i++
+		}
+		require.Equal(d.t, i < len(d.ref.entries), found, d.trace)
+		if found {
+			require.Equal(d.t, d.ref.entries[i].key, next, d.trace)
+			require.Equal(d.t, d.ref.entries[i].value, value, d.trace)
+		}
+		d.log("prev", key)
+		prev, value, found := d.tree.Prev(key)
+		i, _ = d.ref.find(key)
+		require.Equal(d.t, i > 0, found, d.trace)
+		if found {
+			require.Equal(d.t, d.ref.entries[i-1].key, prev, d.trace)
+			require.Equal(d.t, d.ref.entries[i-1].value, value, d.trace)
+		}
+	case 8:
+		rng := Range{Start: d.bound(), End: d.bound(), Inclusivity: Inclusivity(d.rng.Intn(4))}
+		d.log("scan", rng.Start)
+		expected := d.ref.scan(rng)
+		var rst []kv
+		for iter := d.tree.Scan(rng); iter.Next(); {
+			rst = append(rst, kv{key: iter.Key(), value: iter.Value()})
+		}
+		require.Equal(d.t, expected, rst, d.trace)
+		rst = rst[:0]
+		for iter := d.tree.Scan(rng).Reverse(); iter.Next(); {
+			rst = append(rst, kv{key: iter.Key(), value: iter.Value()})
+		}
+		for i, j := 0, len(rst)-1; i < j; i, j = i+1, j-1 {
+			rst[i], rst[j] = rst[j], rst[i]
+		}
+		if len(expected) == 0 {
+			require.Empty(d.t, rst, d.trace)
+		} else {
+			require.Equal(d.t, expected, rst, d.trace)
+		}
+	case 9:
+		// ranges are rarely removed, otherwise tree stays almost empty
+		if d.rng.Intn(10) > 0 {
+			return
+		}
+		rng := Range{Start: d.bound(), End: d.bound(), Inclusivity: Inclusivity(d.rng.Intn(4))}
+		d.log("delete range", rng.Start)
+		require.Equal(d.t, d.ref.removeRange(rng), d.tree.DeleteIn(rng), d.trace)
+	}
+}
+
+// verify compares full state of the tree with the reference.
+func (d *differential) verify() {
+	require.Equal(d.t, len(d.ref.entries), d.tree.Len(), d.trace)
+	i := 0
+	for iter := d.tree.Iterator(nil, nil); iter.Next(); i++ {
+		require.Less(d.t, i, len(d.ref.entries), d.trace)
+		require.Equal(d.t, d.ref.entries[i].key, iter.Key(), d.trace)
+		require.Equal(d.t, d.ref.entries[i].value, iter.Value(), d.trace)
+	}
+	require.Equal(d.t, len(d.ref.entries), i, d.trace)
+	min, _, found := d.tree.Min()
+	require.Equal(d.t, i > 0, found)
+	if found {
+		require.Equal(d.t, d.ref.entries[0].key, min)
+	}
+	max, _, found := d.tree.Max()
+	require.Equal(d.t, i > 0, found)
+	if found {
+		require.Equal(d.t, d.ref.entries[i-1].key, max)
+	}
+}
+
+// run executes batches of operations, state of the tree is verified after every batch.
+func (d *differential) run(batches, size int) {
+	for b := 0; b < batches; b++ {
+		d.trace = d.trace[:0]
+		for i := 0; i < size; i++ {
+			d.step()
+		}
+		d.verify()
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	batches := 200
+	if testing.Short() {
+		batches = 20
+	}
+	for _, tc := range []struct {
+		desc string
+		tree *Tree
+	}{
+		{"default", New()},
+		{"copied keys", New(WithCopiedKeys())},
+		{"slab allocator", New(WithAllocator(SlabAllocator(64)))},
+		{"misuse detection", New(WithMisuseDetection())},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Logf("differential test with seed %v", *seed)
+			d := &differential{t: t, rng: rand.New(rand.NewSource(*seed)), tree: tc.tree}
+			d.run(batches, 500)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package art
+
+import "errors"
+
+// Errors returned by the tree APIs. Returned errors may wrap one of them with
+// additional context, use errors.Is to check for the specific error.
+var (
+	// ErrFull is returned when the tree reached capacity configured with WithSampledEviction,
+	// and the new key was not admitted by WithTinyLFU.
+	ErrFull = errors.New("art: tree is full")
+	// ErrConcurrentModification is returned when operation observed conflicting
+	// concurrent modification and can't be retried transparently.
+	ErrConcurrentModification = errors.New("art: concurrent modification")
+	// ErrCorrupt is returned when serialized or persisted data is malformed.
+	ErrCorrupt = errors.New("art: data is corrupted")
+	// ErrUnsupportedValue is returned when value can't be serialized.
+	ErrUnsupportedValue = errors.New("art: unsupported value")
+	// ErrBackpressure is returned when the tree exceeded memory limit and write
+	// can't proceed without blocking.
+	ErrBackpressure = errors.New("art: memory limit exceeded")
+	// ErrMisuse is wrapped by the panic value when misuse detection is enabled
+	// and incorrect use of the API is detected.
+	ErrMisuse = errors.New("art: api misuse")
+	// ErrNotReconfigurable is returned when option can't be changed after the tree was created.
+	ErrNotReconfigurable = errors.New("art: option can't be changed at runtime")
+	// ErrTxnClosed is returned when transaction is used after Commit or Rollback.
+	ErrTxnClosed = errors.New("art: transaction is closed")
+	// ErrUnsorted is returned when input that must be sorted in ascending order is not sorted,
+	// or a key is a prefix of the following key.
+	ErrUnsorted = errors.New("art: keys are not sorted")
+	// ErrPoisoned is wrapped by the panic value when the tree is used after callback
+	// panicked while tree was locked.
+	ErrPoisoned = errors.New("art: tree is poisoned")
+)
@@ -0,0 +1,153 @@
+package art
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// WithSampledEviction limits the number of keys in the tree. Once the limit is reached
+// every insert of a new key evicts approximately least recently used key.
+// Victim is the oldest of the randomly sampled keys, as in Redis, therefore the tree
+// doesn't maintain global LRU list and reads don't contend on it.
+// With a single sample victim is selected at random.
+func WithSampledEviction(maxEntries, samples int) Option {
+	return func(t *Tree) {
+		s := t.sampler(samples)
+		s.max = int64(maxEntries)
+	}
+}
+
+// WithMaxBytes limits estimated memory used by the tree, estimate is the same as for
+// WithMemoryLimit. Once the limit is reached every insert evicts approximately least recently
+// used keys until the estimate is within the limit, victims are selected as by WithSampledEviction.
+// Both limits can be configured together, keys are evicted until both are satisfied.
+func WithMaxBytes(maxBytes int64, samples int) Option {
+	return func(t *Tree) {
+		s := t.sampler(samples)
+		s.maxBytes = maxBytes
+	}
+}
+
+// unlimited is a limit of the sampler that wasn't configured.
+const unlimited = math.MaxInt64
+
+// sampler returns the sampler configured by the previous option, or creates one without limits.
+func (t *Tree) sampler(samples int) *sampler {
+	if samples < 1 {
+		samples = 1
+	}
+	if t.evictor == nil {
+		t.evictor = &sampler{
+			max:      unlimited,
+			maxBytes: unlimited,
+			clock:    systemClock{},
+		}
+	}
+	t.evictor.samples = int64(samples)
+	return t.evictor
+}
+
+// accessResolution is a minimal difference between access stamps that will be written
+// to the leaf. Reads of the hot keys don't update the leaf every time.
+const accessResolution = int64(time.Millisecond)
+
+type sampler struct {
+	// max, maxBytes and samples can be changed by Reconfigure, and must be accessed atomically.
+	max      int64
+	maxBytes int64
+	samples  int64
+	// bytes is an estimated memory used by the leaves, same as usage of the limiter.
+	bytes int64
+	clock Clock
+}
+
+func (s *sampler) now() int64 {
+	return s.clock.Now().UnixNano()
+}
+
+// touch updates access stamp of the leaf.
+func (s *sampler) touch(l *leaf) {
+	now := s.now()
+	if now-atomic.LoadInt64(&l.access) >= accessResolution {
+		atomic.StoreInt64(&l.access, now)
+	}
+}
+
+// add adjusts estimated memory by delta bytes.
+func (s *sampler) add(delta int64) {
+	atomic.AddInt64(&s.bytes, delta)
+}
+
+// full is true if new key can't be added without eviction.
+func (s *sampler) full(t *Tree) bool {
+	return atomic.LoadInt64(&t.size) >= atomic.LoadInt64(&s.max) ||
+		atomic.LoadInt64(&s.bytes) >= atomic.LoadInt64(&s.maxBytes)
+}
+
+// over is true if keys must be evicted to satisfy the limits.
+func (s *sampler) over(t *Tree) bool {
+	return atomic.LoadInt64(&t.size) > atomic.LoadInt64(&s.max) ||
+		atomic.LoadInt64(&s.bytes) > atomic.LoadInt64(&s.maxBytes)
+}
+
+// evict removes keys until number of keys and estimated memory are within the limits.
+func (s *sampler) evict(t *Tree) {
+	for s.over(t) {
+		victim := s.victim(t)
+		if victim == nil {
+			return
+		}
+		// key may be concurrently replaced by a recent write, that must not be evicted
+		t.deleteLeaf(victim.key, victim)
+	}
+}
+
+// victim returns the oldest leaf among sampled.
+func (s *sampler) victim(t *Tree) *leaf {
+	var victim *leaf
+	for i, samples := 0, atomic.LoadInt64(&s.samples); i < int(samples); i++ {
+		l := t.sample()
+		if l == nil {
+			return nil
+		}
+		if victim == nil || atomic.LoadInt64(&l.access) < atomic.LoadInt64(&victim.access) {
+			victim = l
+		}
+	}
+	return victim
+}
+
+// sample returns random leaf from the tree or nil if the tree is empty.
+// At every inner node child is selected by the random byte, therefore keys
+// from the sparse nodes are more likely to be sampled.
+func (t *Tree) sample() *leaf {
+restart:
+	version, _ := t.lock.RLock()
+	parent := &t.lock
+	next := t.root
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l, _ := next.(*leaf)
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return l
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		b := byte(rand.Intn(256))
+		_, next = n.node.child(b)
+		if next == nil {
+			_, next = n.node.next(&b)
+		}
+		if next == nil {
+			_, next = n.node.next(nil)
+		}
+		parent, version = &n.lock, nversion
+	}
+}
@@ -0,0 +1,146 @@
+package art
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func countKeys(tree *Tree) int {
+	count := 0
+	iter := tree.Iterator(nil, nil)
+	for iter.Next() {
+		count++
+	}
+	return count
+}
+
+func TestSampledEviction(t *testing.T) {
+	limit := 100
+	tree := New(WithSampledEviction(limit, 20))
+	keys := [][]byte{}
+	for i := 0; i < limit; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, i)
+		keys = append(keys, key)
+	}
+	require.Equal(t, limit, countKeys(tree))
+
+	time.Sleep(5 * time.Millisecond)
+	hot := keys[:10]
+	for _, key := range hot {
+		_, found := tree.Get(key)
+		require.True(t, found)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	for i := 0; i < limit/2; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, i)
+		require.Equal(t, limit, countKeys(tree))
+	}
+	for _, key := range hot {
+		_, found := tree.Get(key)
+		require.True(t, found)
+	}
+}
+
+func TestSampledEvictionReplace(t *testing.T) {
+	tree := New(WithSampledEviction(2, 1))
+	for i := 0; i < 10; i++ {
+		tree.Insert([]byte{1}, i)
+		tree.Insert([]byte{2}, i)
+	}
+	require.Equal(t, 2, countKeys(tree))
+}
+
+func TestMaxBytesEviction(t *testing.T) {
+	const keySize = 9
+	limit := 10 * (leafOverhead + keySize)
+	tree := New(WithMaxBytes(limit, 5))
+	for i := 0; i < 100; i++ {
+		tree.Insert(sequentialKey(i), i)
+		require.LessOrEqual(t, tree.Len(), 10)
+	}
+	require.Equal(t, 10, countKeys(tree))
+	require.Equal(t, Config{MaxBytes: limit, EvictionSamples: 5}, tree.Config())
+
+	// both limits are satisfied
+	require.NoError(t, tree.Reconfigure(WithSampledEviction(5, 5)))
+	require.Equal(t, limit, tree.Config().MaxBytes)
+	tree.Insert(sequentialKey(100), 100)
+	require.Equal(t, 5, tree.Len())
+	require.NoError(t, tree.Reconfigure(WithMaxBytes(2*(leafOverhead+keySize), 5)))
+	tree.Insert(sequentialKey(101), 101)
+	require.Equal(t, 2, tree.Len())
+
+	tree.DeleteRange(nil, nil)
+	for i := 0; i < 2; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	require.Equal(t, 2, countKeys(tree))
+}
+
+func TestTinyLFURejectedTryInsert(t *testing.T) {
+	limit := 10
+	tree := New(WithSampledEviction(limit, limit), WithTinyLFU(1024))
+	for i := 0; i < limit; i++ {
+		require.NoError(t, tree.TryInsert(sequentialKey(i), i))
+	}
+	// every stored key is more frequent than the new one
+	for r := 0; r < 10; r++ {
+		for i := 0; i < limit; i++ {
+			require.NoError(t, tree.TryInsert(sequentialKey(i), i))
+		}
+	}
+	require.Equal(t, ErrFull, tree.TryInsert(sequentialKey(limit), limit))
+	_, found := tree.Get(sequentialKey(limit))
+	require.False(t, found)
+	require.Equal(t, limit, tree.Len())
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	limit := 100
+	tree := New(WithSampledEviction(limit, 5), WithTinyLFU(1024))
+	keys := [][]byte{}
+	for i := 0; i < limit; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, i)
+		keys = append(keys, key)
+	}
+	hot := keys[:10]
+	for i := 0; i < 5; i++ {
+		for _, key := range hot {
+			_, found := tree.Get(key)
+			require.True(t, found)
+		}
+	}
+
+	// single scan doesn't flush frequently used keys
+	for i := 0; i < 10*limit; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, i)
+	}
+	require.Equal(t, limit, countKeys(tree))
+	for _, key := range hot {
+		_, found := tree.Get(key)
+		require.True(t, found)
+	}
+
+	// key that is inserted repeatedly is eventually admitted
+	key := make([]byte, 8)
+	rand.Read(key)
+	for i := 0; i < 10; i++ {
+		tree.Insert(key, i)
+	}
+	value, found := tree.Get(key)
+	require.True(t, found)
+	require.Equal(t, 9, value)
+	require.Equal(t, limit, countKeys(tree))
+}
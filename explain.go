@@ -0,0 +1,137 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Termination describes where the descent for the key has stopped.
+type Termination int
+
+const (
+	// TerminatedEmpty means that the tree has no keys.
+	TerminatedEmpty Termination = iota
+	// TerminatedPrefix means that the key diverged from the prefix of the inner node.
+	TerminatedPrefix
+	// TerminatedKeyLength means that the key ended before the descent reached a leaf.
+	TerminatedKeyLength
+	// TerminatedChild means that the inner node has no child for the next byte of the key.
+	TerminatedChild
+	// TerminatedLeaf means that the leaf was reached, but it stores another key.
+	TerminatedLeaf
+	// TerminatedFound means that the leaf with the key was reached.
+	TerminatedFound
+)
+
+var terminationNames = [...]string{"empty tree", "prefix mismatch", "key too short", "missing child", "leaf mismatch", "found"}
+
+func (t Termination) String() string {
+	return terminationNames[t]
+}
+
+// ExplainStep is a single inner node visited by the descent.
+type ExplainStep struct {
+	// Depth is an offset in the key where the node prefix starts.
+	Depth int
+	// Kind is a name of the node type (node4, node16, node48, node256).
+	Kind string
+	// Children is a number of children in the node.
+	Children int
+	// Prefix of the node, and the number of prefix bytes that matched the key.
+	Prefix  []byte
+	Matched int
+	// Edge is a byte of the key used to select the child. Valid only if descent followed the child.
+	Edge byte
+}
+
+// Explanation is a trace of the descent for the key, as done by Get.
+type Explanation struct {
+	Key   []byte
+	Steps []ExplainStep
+	// Leaf is a key stored in the reached leaf, if any.
+	Leaf        []byte
+	Termination Termination
+}
+
+func (e Explanation) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "key: %x\n", e.Key)
+	for _, s := range e.Steps {
+		fmt.Fprintf(&b, "depth %d: %s with %d children, prefix %x matched %d", s.Depth, s.Kind, s.Children, s.Prefix, s.Matched)
+		if s.Matched == len(s.Prefix) && s.Depth+s.Matched < len(e.Key) {
+			fmt.Fprintf(&b, ", edge %x", s.Edge)
+		}
+		_, _ = b.WriteString("\n")
+	}
+	if e.Leaf != nil {
+		fmt.Fprintf(&b, "leaf: %x\n", e.Leaf)
+	}
+	fmt.Fprintf(&b, "terminated: %s", e.Termination)
+	return b.String()
+}
+
+// Explain descends the tree using the key the same way as Get does, and records
+// every visited node and the reason why descent has stopped.
+// Tree is not modified.
+func (t *Tree) Explain(key []byte) Explanation {
+	t.checkPoisoned()
+	key = t.keyOf(key)
+restart:
+	e := Explanation{Key: key}
+	version, _ := t.lock.RLock()
+	parent := &t.lock
+	next := t.root
+	depth := 0
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			break
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		parent, version = &n.lock, nversion
+		total, _ := children(n.node, 0)
+		step := ExplainStep{
+			Depth:    depth,
+			Kind:     kindNames[kindOf(n.node)],
+			Children: total,
+			Prefix:   append([]byte(nil), n.prefix[:n.prefixLen]...),
+			Matched:  comparePrefix(n.prefix[:n.prefixLen], key, 0, depth),
+		}
+		depth += n.prefixLen
+		next = nil
+		switch {
+		case step.Matched != n.prefixLen && step.Depth+step.Matched < len(key):
+			e.Termination = TerminatedPrefix
+		case depth >= len(key):
+			e.Termination = TerminatedKeyLength
+		default:
+			step.Edge = key[depth]
+			_, next = n.node.child(key[depth])
+			if next == nil {
+				e.Termination = TerminatedChild
+			}
+			depth++
+		}
+		e.Steps = append(e.Steps, step)
+		if next == nil {
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return e
+		}
+	}
+	if l, isLeaf := next.(*leaf); isLeaf {
+		e.Leaf = l.key
+		e.Termination = TerminatedLeaf
+		if l.cmp(key) {
+			e.Termination = TerminatedFound
+		}
+	}
+	if parent.RUnlock(version, nil) {
+		goto restart
+	}
+	return e
+}
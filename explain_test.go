@@ -0,0 +1,42 @@
+package art
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	var tree Tree
+	require.Equal(t, TerminatedEmpty, tree.Explain([]byte("a\x00")).Termination)
+
+	for _, key := range []string{"user:1\x00", "user:2\x00", "user:10\x00", "order:1\x00"} {
+		tree.Insert([]byte(key), key)
+	}
+	for _, tc := range []struct {
+		desc        string
+		key         string
+		termination Termination
+		steps       int
+	}{
+		{desc: "found", key: "user:10\x00", termination: TerminatedFound, steps: 3},
+		{desc: "leaf", key: "order:2\x00", termination: TerminatedLeaf, steps: 1},
+		{desc: "child", key: "admin\x00", termination: TerminatedChild, steps: 1},
+		{desc: "prefix", key: "usr:1\x00", termination: TerminatedPrefix, steps: 2},
+		{desc: "short", key: "user", termination: TerminatedKeyLength, steps: 2},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := tree.Explain([]byte(tc.key))
+			require.Equal(t, tc.termination, e.Termination, e.String())
+			require.Len(t, e.Steps, tc.steps, e.String())
+			_, found := tree.Get([]byte(tc.key))
+			require.Equal(t, tc.termination == TerminatedFound, found)
+		})
+	}
+
+	e := tree.Explain([]byte("user:10\x00"))
+	require.Equal(t, []byte("ser:"), e.Steps[1].Prefix)
+	require.Equal(t, 4, e.Steps[1].Matched)
+	require.Equal(t, byte('1'), e.Steps[1].Edge)
+	require.Equal(t, []byte("user:10\x00"), e.Leaf)
+}
@@ -0,0 +1,97 @@
+package art
+
+import "fmt"
+
+// Batch is a columnar batch of keys and encoded values. Columns use the layout
+// of the variable-size binary arrays in Apache Arrow: data of all rows is concatenated
+// and row i occupies data[offsets[i]:offsets[i+1]].
+type Batch struct {
+	Keys, Values             []byte
+	KeyOffsets, ValueOffsets []int32
+}
+
+// Len returns number of rows in the batch.
+func (b *Batch) Len() int {
+	if len(b.KeyOffsets) == 0 {
+		return 0
+	}
+	return len(b.KeyOffsets) - 1
+}
+
+func (b *Batch) Key(i int) []byte {
+	return b.Keys[b.KeyOffsets[i]:b.KeyOffsets[i+1]]
+}
+
+func (b *Batch) Value(i int) []byte {
+	return b.Values[b.ValueOffsets[i]:b.ValueOffsets[i+1]]
+}
+
+func (b *Batch) reset() {
+	b.Keys = b.Keys[:0]
+	b.Values = b.Values[:0]
+	b.KeyOffsets = append(b.KeyOffsets[:0], 0)
+	b.ValueOffsets = append(b.ValueOffsets[:0], 0)
+}
+
+func (b *Batch) append(key []byte, value ValueType, encode func([]byte, ValueType) []byte) {
+	b.Keys = append(b.Keys, key...)
+	b.KeyOffsets = append(b.KeyOffsets, int32(len(b.Keys)))
+	b.Values = encode(b.Values, value)
+	b.ValueOffsets = append(b.ValueOffsets, int32(len(b.Values)))
+}
+
+// Export visits keys in range (start, end] in ascending order and passes them to fn
+// in batches of at most size rows. Values are appended to the value column by encode,
+// or by the codec configured with WithValueCodec if encode is nil. ErrUnsupportedValue
+// is returned if neither is set. Batch is reused after fn returns, fn must copy data
+// that it needs to retain. Export stops if fn returns false.
+func (t *Tree) Export(start, end []byte, size int, encode func(dst []byte, value ValueType) []byte, fn func(*Batch) bool) error {
+	if encode == nil {
+		encode = t.encode
+	}
+	if encode == nil {
+		return fmt.Errorf("%w: value codec is not configured", ErrUnsupportedValue)
+	}
+	var batch Batch
+	batch.reset()
+	iter := t.Iterator(start, end)
+	for iter.Next() {
+		batch.append(iter.Key(), iter.Value(), encode)
+		if batch.Len() == size {
+			if !fn(&batch) {
+				return nil
+			}
+			batch.reset()
+		}
+	}
+	if batch.Len() > 0 {
+		fn(&batch)
+	}
+	return nil
+}
+
+// Dump returns all keys and values in ascending order, as they were stored at a single
+// point in time. Keys share memory with the tree and must not be modified.
+func (t *Tree) Dump() ([][]byte, []ValueType) {
+	t.checkPoisoned()
+	leaves := t.snapshot(All())
+	keys := make([][]byte, len(leaves))
+	values := make([]ValueType, len(leaves))
+	for i, l := range leaves {
+		keys[i] = l.key
+		values[i] = l.value
+	}
+	return keys, values
+}
+
+// DumpTo passes all keys and values to fn in ascending order, with the same snapshot semantics
+// as Dump. Snapshot is collected before the first call, fn may modify the tree.
+// DumpTo stops if fn returns false.
+func (t *Tree) DumpTo(fn func(key []byte, value ValueType) bool) {
+	t.checkPoisoned()
+	for _, l := range t.snapshot(All()) {
+		if !fn(l.key, l.value) {
+			return
+		}
+	}
+}
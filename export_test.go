@@ -0,0 +1,92 @@
+package art
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte{byte(i >> 8), byte(i)}, uint64(i))
+	}
+	encode := func(dst []byte, value ValueType) []byte {
+		return binary.BigEndian.AppendUint64(dst, value.(uint64))
+	}
+
+	var (
+		rows    int
+		batches int
+	)
+	err := tree.Export(nil, nil, 300, encode, func(batch *Batch) bool {
+		batches++
+		for i := 0; i < batch.Len(); i++ {
+			require.Equal(t, []byte{byte(rows >> 8), byte(rows)}, batch.Key(i))
+			require.Equal(t, uint64(rows), binary.BigEndian.Uint64(batch.Value(i)))
+			rows++
+		}
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1000, rows)
+	require.Equal(t, 4, batches)
+
+	batches = 0
+	err = tree.Export([]byte{0, 10}, []byte{0, 20}, 5, encode, func(batch *Batch) bool {
+		batches++
+		require.Equal(t, []byte{0, 11}, batch.Key(0))
+		return false
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, batches)
+
+	err = tree.Export(nil, nil, 5, nil, func(*Batch) bool {
+		require.Fail(t, "codec is not configured")
+		return false
+	})
+	require.True(t, errors.Is(err, ErrUnsupportedValue), err)
+
+	// codec of the tree is used if encode is nil
+	codec := New(WithValueCodec(encode, nil))
+	codec.Insert([]byte{1}, uint64(7))
+	err = codec.Export(nil, nil, 5, nil, func(batch *Batch) bool {
+		require.Equal(t, 1, batch.Len())
+		require.Equal(t, uint64(7), binary.BigEndian.Uint64(batch.Value(0)))
+		return true
+	})
+	require.NoError(t, err)
+}
+
+func TestDump(t *testing.T) {
+	var tree Tree
+	keys, values := tree.Dump()
+	require.Empty(t, keys)
+	require.Empty(t, values)
+
+	var expected [][]byte
+	for i := 999; i >= 0; i-- {
+		tree.Insert([]byte{byte(i >> 8), byte(i)}, i)
+	}
+	for i := 0; i < 1000; i++ {
+		expected = append(expected, []byte{byte(i >> 8), byte(i)})
+	}
+	keys, values = tree.Dump()
+	require.Equal(t, expected, keys)
+	for i, value := range values {
+		require.Equal(t, i, value)
+	}
+
+	visited := 0
+	tree.DumpTo(func(key []byte, value ValueType) bool {
+		require.Equal(t, expected[visited], key)
+		// snapshot is not affected by modifications
+		tree.Delete(key)
+		visited++
+		return visited < 500
+	})
+	require.Equal(t, 500, visited)
+	require.Equal(t, 500, tree.Len())
+}
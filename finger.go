@@ -0,0 +1,248 @@
+package art
+
+import "bytes"
+
+// Result is a result of the point lookup.
+type Result struct {
+	Value ValueType
+	Found bool
+}
+
+// step is an inner node on the path from the root together with the version
+// observed during descent.
+type step struct {
+	node    *inner
+	version uint64
+	// depth is an offset in the key where prefix of the node starts.
+	depth int
+}
+
+// finger remembers the path of the last descent. Next descent resumes from the
+// deepest inner node shared with the previous key, after versions of the remaining
+// path were validated.
+type finger struct {
+	tree        *Tree
+	rootVersion uint64
+	steps       []step
+	last        []byte
+}
+
+// resume returns deepest valid node on the path of the key, locked for reading.
+// Nodes that were modified since the previous descent are discarded together with
+// their descendants. If path can't be reused nil is returned.
+func (f *finger) resume(key []byte) (*inner, uint64, int) {
+	if !optimistic {
+		// versions are not maintained by the pessimistic lock, path can't be validated,
+		// and the parent of the resumed node would have to stay locked
+		f.steps = f.steps[:0]
+		return nil, 0, 0
+	}
+	cp := commonPrefix(f.last, key)
+	i := len(f.steps)
+	for i > 0 && f.steps[i-1].depth > cp {
+		i--
+	}
+	f.steps = f.steps[:i]
+	for len(f.steps) > 0 {
+		s := f.steps[len(f.steps)-1]
+		version, obsolete := s.node.lock.RLock()
+		if obsolete || version != s.version {
+			_ = s.node.lock.RUnlock(version, nil)
+			f.steps = f.steps[:len(f.steps)-1]
+			continue
+		}
+		if f.tree.lock.Check(f.rootVersion) {
+			_ = s.node.lock.RUnlock(version, nil)
+			f.steps = f.steps[:0]
+			return nil, 0, 0
+		}
+		valid := 0
+		for valid < len(f.steps)-1 && !f.steps[valid].node.lock.Check(f.steps[valid].version) {
+			valid++
+		}
+		if valid < len(f.steps)-1 {
+			_ = s.node.lock.RUnlock(version, nil)
+			f.steps = f.steps[:valid]
+			continue
+		}
+		return s.node, version, s.depth
+	}
+	return nil, 0, 0
+}
+
+// get returns the leaf that stores the key or nil.
+func (f *finger) get(key []byte) *leaf {
+	n, version, depth := f.resume(key)
+	f.last = key
+	for {
+		if n == nil {
+			var root node
+			n, version, root = f.root()
+			if n == nil {
+				l := match(root, key)
+				if f.tree.lock.RUnlock(version, nil) {
+					continue
+				}
+				return l
+			}
+			depth = 0
+		}
+		l, restart := f.descend(n, version, depth, key)
+		if !restart {
+			return l
+		}
+		n = nil
+	}
+}
+
+// root returns root inner node locked for reading. If root is not an inner node
+// tree lock remains locked for reading and root is returned.
+func (f *finger) root() (*inner, uint64, node) {
+	f.steps = f.steps[:0]
+	for {
+		version, _ := f.tree.lock.RLock()
+		root := f.tree.root
+		n, isInner := root.(*inner)
+		if !isInner {
+			return nil, version, root
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || f.tree.lock.RUnlock(version, nil) {
+			continue
+		}
+		f.rootVersion = version
+		f.steps = append(f.steps, step{node: n, version: nversion})
+		return n, nversion, nil
+	}
+}
+
+// match returns n if it is a leaf with the same key.
+func match(n node, key []byte) *leaf {
+	l, isLeaf := n.(*leaf)
+	if isLeaf && l.cmp(key) {
+		return l
+	}
+	return nil
+}
+
+// descend starts from the node locked for reading and returns true if descent needs to be restarted.
+func (f *finger) descend(n *inner, version uint64, depth int, key []byte) (*leaf, bool) {
+	for {
+		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
+		depth += n.prefixLen
+		var next node
+		if cmp == n.prefixLen && depth < len(key) {
+			_, next = n.node.child(key[depth])
+		}
+		child, isInner := next.(*inner)
+		if !isInner {
+			l := match(next, key)
+			if n.lock.RUnlock(version, nil) {
+				return nil, true
+			}
+			return l, false
+		}
+		cversion, obsolete := child.lock.RLock()
+		if obsolete || n.lock.RUnlock(version, nil) {
+			return nil, true
+		}
+		depth++
+		f.steps = append(f.steps, step{node: child, version: cversion, depth: depth})
+		n, version = child, cversion
+	}
+}
+
+// GetSorted looks up keys, which must be sorted in ascending order, and writes results
+// into out. out must be at least as long as keys.
+// Tree is traversed in a single pass: lookup of the next key resumes from the deepest
+// inner node shared with the previous key, instead of descending from the root.
+func (t *Tree) GetSorted(keys [][]byte, out []Result) {
+	f := finger{tree: t}
+	for i, key := range keys {
+		out[i] = Result{}
+		if l := t.lookup(t.keyOf(key), &f); l != nil {
+			out[i] = Result{Value: l.value, Found: true}
+		}
+	}
+}
+
+// GetMany looks up keys in any order and returns results in the order of the keys.
+// Results are written into out, which is reallocated if it is shorter than keys.
+// If keys are sorted in ascending order they are looked up as by GetSorted, common
+// prefixes of the adjacent keys are traversed once. Otherwise every key is looked up
+// from the root, sorting the batch costs more than the shared descent saves.
+func (t *Tree) GetMany(keys [][]byte, out []Result) []Result {
+	if len(out) < len(keys) {
+		out = make([]Result, len(keys))
+	}
+	out = out[:len(keys)]
+	if t.transform != nil {
+		transformed := make([][]byte, len(keys))
+		for i, key := range keys {
+			transformed[i] = t.keyOf(key)
+		}
+		keys = transformed
+	}
+	var (
+		f      *finger
+		sorted = true
+	)
+	for i := 1; i < len(keys) && sorted; i++ {
+		sorted = bytes.Compare(keys[i-1], keys[i]) <= 0
+	}
+	if sorted {
+		f = &finger{tree: t}
+	}
+	for i, key := range keys {
+		out[i] = Result{}
+		if l := t.lookup(key, f); l != nil {
+			out[i] = Result{Value: l.value, Found: true}
+		}
+	}
+	return out
+}
+
+func commonPrefix(k1, k2 []byte) int {
+	lth := len(k1)
+	if len(k2) < lth {
+		lth = len(k2)
+	}
+	for i := 0; i < lth; i++ {
+		if k1[i] != k2[i] {
+			return i
+		}
+	}
+	return lth
+}
+
+// upsert applies modification starting from the deepest valid node on the path of the key.
+// Returns false if path can't be reused, or descent needs to be restarted from the root.
+func (f *finger) upsert(op *upsert) bool {
+	n, version, depth := f.resume(op.key)
+	if n == nil || n.lock.RUnlock(version, nil) {
+		return false
+	}
+	parent, parentVersion := &f.tree.lock, f.rootVersion
+	if i := len(f.steps) - 1; i > 0 {
+		parent, parentVersion = &f.steps[i-1].node.lock, f.steps[i-1].version
+	}
+	_, restart := n.upsert(op, depth, parent, parentVersion)
+	return !restart
+}
+
+// refresh updates version of the deepest node on the path after it was modified by upsert.
+// Only the deepest node is refreshed, if any of the ancestors were modified concurrently
+// resume will discard the node.
+func (f *finger) refresh() {
+	if len(f.steps) == 0 {
+		return
+	}
+	s := &f.steps[len(f.steps)-1]
+	version, obsolete := s.node.lock.RLock()
+	if obsolete {
+		f.steps = f.steps[:len(f.steps)-1]
+	} else {
+		s.version = version
+	}
+	_ = s.node.lock.RUnlock(version, nil)
+}
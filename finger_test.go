@@ -0,0 +1,147 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSorted(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{}
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 8)
+		rand.Read(key[4:])
+		key[0] = byte(i % 3)
+		if i%2 == 0 {
+			tree.Insert(key, i)
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	out := make([]Result, len(keys))
+	tree.GetSorted(keys, out)
+	for i, key := range keys {
+		value, found := tree.Get(key)
+		require.Equal(t, Result{Value: value, Found: found}, out[i])
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{}
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 8)
+		rand.Read(key[4:])
+		key[0] = byte(i % 3)
+		if i%2 == 0 {
+			tree.Insert(key, i)
+		}
+		keys = append(keys, key)
+	}
+	// duplicates are looked up for every occurrence
+	keys = append(keys, keys[:10]...)
+	out := tree.GetMany(keys, nil)
+	require.Len(t, out, len(keys))
+	for i, key := range keys {
+		value, found := tree.Get(key)
+		require.Equal(t, Result{Value: value, Found: found}, out[i])
+	}
+	require.Empty(t, tree.GetMany(nil, nil))
+
+	// out is reused and overwritten
+	reused := tree.GetMany(keys[1:11], out)
+	require.Len(t, reused, 10)
+	require.Equal(t, &out[0], &reused[0])
+	for i, key := range keys[1:11] {
+		value, found := tree.Get(key)
+		require.Equal(t, Result{Value: value, Found: found}, reused[i])
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	var tree Tree
+	for i := 0; i < 1_000_000; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	for _, bc := range []struct {
+		desc string
+		key  func(i int) []byte
+	}{
+		{"random", func(int) []byte { return sequentialKey(rand.Intn(1_000_000)) }},
+		{"clustered", func(i int) []byte { return sequentialKey(500_000 + i) }},
+		{"sorted", func(i int) []byte { return sequentialKey(500_000 + i) }},
+		{"sorted-random", func(int) []byte { return sequentialKey(rand.Intn(1_000_000)) }},
+	} {
+		batch := make([][]byte, 256)
+		for i := range batch {
+			batch[i] = bc.key(i)
+		}
+		if strings.HasPrefix(bc.desc, "sorted") {
+			sort.Slice(batch, func(i, j int) bool {
+				return bytes.Compare(batch[i], batch[j]) < 0
+			})
+		} else {
+			rand.Shuffle(len(batch), func(i, j int) {
+				batch[i], batch[j] = batch[j], batch[i]
+			})
+		}
+		b.Run(bc.desc+"/Get", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, key := range batch {
+					tree.Get(key)
+				}
+			}
+		})
+		out := make([]Result, len(batch))
+		b.Run(bc.desc+"/GetMany", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tree.GetMany(batch, out)
+			}
+		})
+	}
+}
+
+func TestGetSortedConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		tree   Tree
+		stable [][]byte
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < 1_000; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, key)
+		stable = append(stable, key)
+	}
+	sort.Slice(stable, func(i, j int) bool {
+		return bytes.Compare(stable[i], stable[j]) < 0
+	})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10_000; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			tree.Insert(key, key)
+			tree.Delete(key)
+		}
+	}()
+	out := make([]Result, len(stable))
+	for i := 0; i < 20; i++ {
+		tree.GetSorted(stable, out)
+		for j := range stable {
+			require.True(t, out[j].Found)
+			require.Equal(t, stable[j], out[j].Value)
+		}
+	}
+	wg.Wait()
+}
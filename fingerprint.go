@@ -0,0 +1,67 @@
+package art
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+const fingerprintSpace = 1 << 16
+
+// Bucket summarizes keys in the range [Start, End).
+type Bucket struct {
+	// Start is inclusive bound of the bucket, empty for the first bucket.
+	Start []byte
+	// End is exclusive bound of the bucket, empty for the last bucket.
+	End []byte
+	// Count is a number of keys in the bucket.
+	Count uint64
+	// Hash is an order independent combination of hashes of the keys in the bucket.
+	Hash uint64
+}
+
+// Fingerprint splits key space into equal buckets by the first two bytes of the key
+// and returns number of keys and combined hash of the keys for every bucket.
+// Replicas can exchange fingerprints and compare only the ranges of the buckets
+// that differ. buckets is clamped to [1, 65536].
+// Fingerprint is concurrently safe, but doesn't guarantee consistent snapshot.
+func (t *Tree) Fingerprint(buckets int) []Bucket {
+	if buckets < 1 {
+		buckets = 1
+	} else if buckets > fingerprintSpace {
+		buckets = fingerprintSpace
+	}
+	rst := make([]Bucket, buckets)
+	for i := range rst {
+		if i > 0 {
+			rst[i].Start = bucketBound(i, buckets)
+		}
+		if i < buckets-1 {
+			rst[i].End = bucketBound(i+1, buckets)
+		}
+	}
+	h := fnv.New64a()
+	iter := t.Iterator(nil, nil)
+	for iter.Next() {
+		key := iter.Key()
+		h.Reset()
+		_, _ = h.Write(key)
+		b := &rst[bucketIndex(key, buckets)]
+		b.Count++
+		b.Hash ^= h.Sum64()
+	}
+	return rst
+}
+
+func bucketIndex(key []byte, buckets int) int {
+	var prefix [2]byte
+	copy(prefix[:], key)
+	return int(binary.BigEndian.Uint16(prefix[:])) * buckets / fingerprintSpace
+}
+
+// bucketBound returns the smallest two byte prefix that belongs to the bucket i.
+func bucketBound(i, buckets int) []byte {
+	bound := (i*fingerprintSpace + buckets - 1) / buckets
+	rst := make([]byte, 2)
+	binary.BigEndian.PutUint16(rst, uint16(bound))
+	return rst
+}
@@ -0,0 +1,46 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	var t1, t2 Tree
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		t1.Insert(key, i)
+		t2.Insert(key, i)
+	}
+	buckets := 100
+	f1 := t1.Fingerprint(buckets)
+	require.Len(t, f1, buckets)
+	require.Equal(t, f1, t2.Fingerprint(buckets))
+
+	var total uint64
+	for i, b := range f1 {
+		total += b.Count
+		if i > 0 {
+			require.Equal(t, f1[i-1].End, b.Start)
+		}
+	}
+	require.Equal(t, uint64(10_000), total)
+
+	diverged := []byte{0x80, 1, 2, 3, 4, 5, 6, 7}
+	t2.Insert(diverged, 0)
+	f2 := t2.Fingerprint(buckets)
+	for i := range f1 {
+		inBucket := bytes.Compare(diverged, f1[i].Start) >= 0 &&
+			(len(f1[i].End) == 0 || bytes.Compare(diverged, f1[i].End) < 0)
+		if inBucket {
+			require.Equal(t, f1[i].Count+1, f2[i].Count)
+			require.NotEqual(t, f1[i].Hash, f2[i].Hash)
+		} else {
+			require.Equal(t, f1[i], f2[i])
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package art
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzInput decodes operations and keys from the input of the fuzzer,
+// zeroes are returned after the input is exhausted.
+type fuzzInput struct {
+	data []byte
+}
+
+func (in *fuzzInput) byte() byte {
+	if len(in.data) == 0 {
+		return 0
+	}
+	b := in.data[0]
+	in.data = in.data[1:]
+	return b
+}
+
+// key returns a key of up to 15 bytes from the input, or a long key with a repeated byte
+// if the high bit of the length is set. Long keys are split into chains of inner nodes.
+func (in *fuzzInput) key() []byte {
+	n := in.byte()
+	if n&0x80 != 0 {
+		return escapeKey(bytes.Repeat([]byte{in.byte()}, int(n&0x7f)*4))
+	}
+	raw := make([]byte, 0, n&0x0f)
+	for i := 0; i < int(n&0x0f); i++ {
+		raw = append(raw, in.byte())
+	}
+	return escapeKey(raw)
+}
+
+// bound returns a prefix of the key as the range bound, it may be empty.
+func (in *fuzzInput) bound() []byte {
+	key := in.key()
+	return key[:int(in.byte())%(len(key)+1)]
+}
+
+func (in *fuzzInput) rng() Range {
+	return Range{Start: in.bound(), End: in.bound(), Inclusivity: Inclusivity(in.byte() % 4)}
+}
+
+// escapeKey encodes arbitrary bytes, including empty key, as a key that is not a prefix
+// of any other encoded key. Zero is escaped as 0x00 0xff and the key is terminated with 0x00 0x00.
+func escapeKey(raw []byte) []byte {
+	key := make([]byte, 0, len(raw)+2)
+	for _, b := range raw {
+		key = append(key, b)
+		if b == 0 {
+			key = append(key, 0xff)
+		}
+	}
+	return append(key, 0, 0)
+}
+
+func collect(iter *iterator) []kv {
+	var rst []kv
+	for iter.Next() {
+		key, value := iter.KV()
+		rst = append(rst, kv{key: key, value: value})
+	}
+	return rst
+}
+
+// requireEntries compares entries, nil and empty slices are equal.
+func requireEntries(t *testing.T, expected, actual []kv) {
+	if len(expected) == 0 {
+		require.Empty(t, actual)
+		return
+	}
+	require.Equal(t, expected, actual)
+}
+
+func FuzzTree(f *testing.F) {
+	f.Add([]byte{0, 1, 'a', 0, 1, 'b', 2, 1, 'a', 1, 1, 'a', 3, 0, 0, 0, 0, 1})
+	f.Add([]byte{0, 0, 0, 2, 'a', 0, 0, 0x82, 'x', 0, 0x83, 'x', 2, 0x82, 'x', 4, 0, 0, 0, 0, 0})
+	f.Add([]byte{0, 3, 'a', 'b', 'c', 0, 3, 'a', 'b', 'd', 0, 2, 'a', 'b', 6, 2, 'a', 'b', 3, 5, 0, 0, 0, 0, 0})
+	f.Add(bytes.Repeat([]byte{0, 2, 'k', 7, 0, 2, 'k', 9, 4, 0, 0, 0, 0, 1, 1, 2, 'k', 7}, 8))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		in := fuzzInput{data: data}
+		tree := New()
+		var ref reference
+		for i := 0; len(in.data) > 0; i++ {
+			switch in.byte() % 7 {
+			case 0:
+				key := in.key()
+				tree.Insert(key, i)
+				ref.set(key, i)
+			case 1:
+				key := in.key()
+				old, removed := tree.Remove(key)
+				eold, eremoved := ref.remove(key)
+				require.Equal(t, eremoved, removed)
+				require.Equal(t, eold, old)
+			case 2:
+				key := in.key()
+				value, found := tree.Get(key)
+				evalue, efound := ref.get(key)
+				require.Equal(t, efound, found)
+				require.Equal(t, evalue, value)
+			case 3:
+				rng := in.rng()
+				expected := ref.scan(rng)
+				requireEntries(t, expected, collect(tree.Scan(rng)))
+				reversed := collect(tree.Scan(rng).Reverse())
+				require.Equal(t, len(expected), len(reversed))
+				for j := range reversed {
+					require.Equal(t, expected[len(expected)-1-j], reversed[j])
+				}
+			case 4:
+				// visited keys are deleted during iteration, iterator must not skip
+				// or repeat keys that were not visited yet
+				rng, every := in.rng(), int(in.byte()%3)+1
+				expected := ref.scan(rng)
+				var visited []kv
+				iter := tree.Scan(rng)
+				for j := 0; iter.Next(); j++ {
+					key, value := iter.KV()
+					visited = append(visited, kv{key: key, value: value})
+					if j%every == 0 {
+						tree.Delete(key)
+						ref.remove(key)
+						_, found := tree.Get(key)
+						require.False(t, found)
+					}
+				}
+				requireEntries(t, expected, visited)
+			case 5:
+				rng := in.rng()
+				require.Equal(t, ref.removeRange(rng), tree.DeleteIn(rng))
+			case 6:
+				prefix := in.bound()
+				var expected []kv
+				for _, entry := range ref.entries {
+					if bytes.HasPrefix(entry.key, prefix) {
+						expected = append(expected, entry)
+					}
+				}
+				requireEntries(t, expected, collect(tree.Prefix(prefix)))
+			}
+		}
+		require.Equal(t, len(ref.entries), tree.Len())
+		requireEntries(t, ref.entries, collect(tree.Iterator(nil, nil)))
+	})
+}
@@ -0,0 +1,59 @@
+package art
+
+// TreeOf is a tree with values of type V.
+// Value is stored in the leaf without conversion to the ValueType, the leaf refers
+// to itself in place of the value, therefore Insert doesn't allocate for boxing
+// and callers don't need type assertions.
+type TreeOf[V any] struct {
+	tree Tree
+}
+
+// NewTreeOf creates a tree with provided options.
+// Zero value of the TreeOf is valid and equal to the tree created without options.
+func NewTreeOf[V any](opts ...Option) *TreeOf[V] {
+	t := &TreeOf[V]{}
+	for _, opt := range opts {
+		opt(&t.tree)
+	}
+	return t
+}
+
+// leafOf is a leaf allocated together with the typed value.
+type leafOf[V any] struct {
+	leaf
+	value V
+}
+
+func valueOf[V any](value ValueType) V {
+	return value.(*leafOf[V]).value
+}
+
+func (t *TreeOf[V]) Insert(key []byte, value V) {
+	l := &leafOf[V]{value: value}
+	l.leaf.key = t.tree.ownKey(t.tree.keyOf(key))
+	l.leaf.value = l
+	t.tree.insert(&l.leaf)
+}
+
+func (t *TreeOf[V]) Get(key []byte) (V, bool) {
+	value, found := t.tree.Get(key)
+	if !found {
+		var empty V
+		return empty, false
+	}
+	return valueOf[V](value), true
+}
+
+func (t *TreeOf[V]) Delete(key []byte) {
+	t.tree.Delete(key)
+}
+
+// Iterate visits keys in range (start, end] in ascending order, until fn returns false.
+func (t *TreeOf[V]) Iterate(start, end []byte, fn func(key []byte, value V) bool) {
+	iter := t.tree.Iterator(start, end)
+	for iter.Next() {
+		if !fn(iter.Key(), valueOf[V](iter.Value())) {
+			return
+		}
+	}
+}
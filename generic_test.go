@@ -0,0 +1,53 @@
+package art
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type point struct {
+	x, y int
+}
+
+func TestTreeOf(t *testing.T) {
+	var tree TreeOf[point]
+	_, found := tree.Get([]byte("a"))
+	require.False(t, found)
+
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte(fmt.Sprintf("%04d", i)), point{x: i, y: -i})
+	}
+	tree.Insert([]byte("0001"), point{x: 1, y: 1})
+	rst, found := tree.Get([]byte("0001"))
+	require.True(t, found)
+	require.Equal(t, point{x: 1, y: 1}, rst)
+	rst, found = tree.Get([]byte("0999"))
+	require.True(t, found)
+	require.Equal(t, point{x: 999, y: -999}, rst)
+
+	visited := []point{}
+	tree.Iterate([]byte("0100"), []byte("0103"), func(key []byte, value point) bool {
+		visited = append(visited, value)
+		return true
+	})
+	require.Equal(t, []point{{101, -101}, {102, -102}, {103, -103}}, visited)
+
+	tree.Delete([]byte("0001"))
+	_, found = tree.Get([]byte("0001"))
+	require.False(t, found)
+}
+
+func BenchmarkTreeOfInserts(b *testing.B) {
+	tree := NewTreeOf[int]()
+	keys := make([][]byte, b.N)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("%016d", i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+}
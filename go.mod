@@ -1,9 +1,17 @@
 module github.com/dshulyak/art
 
-go 1.14
+go 1.19
 
 require (
 	github.com/anishathalye/porcupine v0.1.0
 	github.com/mmcloughlin/avo v0.0.0-20200523190732-4439b6b2c061
 	github.com/stretchr/testify v1.6.1
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/tools v0.0.0-20200425043458-8463f397d07c // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
@@ -0,0 +1,37 @@
+package art
+
+// Hint caches the path of the previous descent. Operation with a hint resumes from
+// the deepest inner node shared with the previous key, if nodes on the path weren't modified,
+// so clustered keys (sorted batches, sequential ids) skip most of the traversal.
+// Zero value is ready to use. Hint must not be used concurrently.
+type Hint struct {
+	finger finger
+}
+
+// bind returns cached path for the tree, path is discarded if hint was used with another tree.
+func (h *Hint) bind(t *Tree) *finger {
+	if h.finger.tree != t {
+		h.finger = finger{tree: t}
+	}
+	return &h.finger
+}
+
+// InsertWithHint is the same as Insert, descent is resumed from the path cached in the hint.
+func (t *Tree) InsertWithHint(hint *Hint, key []byte, value ValueType) {
+	if t.limiter != nil {
+		t.limiter.wait()
+	}
+	l := t.newLeaf(t.keyOf(key), value)
+	op := upsert{key: l.key, leaf: l, hint: hint.bind(t)}
+	t.upsert(&op)
+}
+
+// GetWithHint is the same as Get, descent is resumed from the path cached in the hint.
+// Nodes on the cached path are used only if their versions weren't changed since they were visited.
+func (t *Tree) GetWithHint(hint *Hint, key []byte) (ValueType, bool) {
+	l := t.lookup(t.keyOf(key), hint.bind(t))
+	if l == nil {
+		return nil, false
+	}
+	return l.value, true
+}
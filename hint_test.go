@@ -0,0 +1,180 @@
+package art
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sequentialKey(i int) []byte {
+	key := make([]byte, 9)
+	binary.BigEndian.PutUint64(key, uint64(i))
+	return key
+}
+
+func TestInsertWithHint(t *testing.T) {
+	var (
+		tree Tree
+		hint Hint
+	)
+	for i := 0; i < 10_000; i++ {
+		tree.InsertWithHint(&hint, sequentialKey(i), i)
+		if i > 0 {
+			require.NotEmpty(t, hint.finger.steps)
+		}
+	}
+	require.Equal(t, 10_000, tree.Len())
+	for i := 0; i < 10_000; i++ {
+		value, found := tree.Get(sequentialKey(i))
+		require.True(t, found)
+		require.Equal(t, i, value)
+	}
+
+	// hint is discarded when used with another tree
+	var other Tree
+	other.InsertWithHint(&hint, sequentialKey(1), 1)
+	other.InsertWithHint(&hint, sequentialKey(2), 2)
+	require.Equal(t, 2, other.Len())
+	require.Equal(t, 10_000, tree.Len())
+}
+
+func TestInsertWithHintConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		tree Tree
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var hint Hint
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := w; i < 20_000; i += 4 {
+				tree.InsertWithHint(&hint, sequentialKey(i), i)
+				// deletes modify nodes on the cached paths of other workers
+				if rng.Intn(4) == 0 {
+					tree.Delete(sequentialKey(i))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		value, found := tree.Get(iter.Key())
+		require.True(t, found)
+		require.Equal(t, iter.Value(), value)
+	}
+	count := 0
+	for i := 0; i < 20_000; i++ {
+		if _, found := tree.Get(sequentialKey(i)); found {
+			count++
+		}
+	}
+	require.Equal(t, count, tree.Len())
+}
+
+func TestGetWithHint(t *testing.T) {
+	var (
+		tree Tree
+		hint Hint
+	)
+	for i := 0; i < 10_000; i += 2 {
+		tree.Insert(sequentialKey(i), i)
+	}
+	for i := 0; i < 10_000; i++ {
+		value, found := tree.GetWithHint(&hint, sequentialKey(i))
+		require.Equal(t, i%2 == 0, found, "key %d", i)
+		if found {
+			require.Equal(t, i, value)
+		}
+	}
+}
+
+func TestGetWithHintConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		tree Tree
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 10_000; i += 2 {
+		tree.Insert(sequentialKey(i), i)
+	}
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			for i := 1; i < 10_000; i += 2 {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				tree.Insert(sequentialKey(i), i)
+				tree.Delete(sequentialKey(i))
+			}
+		}
+	}()
+	var hint Hint
+	for r := 0; r < 5; r++ {
+		for i := 0; i < 10_000; i += 2 {
+			value, found := tree.GetWithHint(&hint, sequentialKey(i))
+			require.True(t, found, "key %d", i)
+			require.Equal(t, i, value)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkInsertWithHint(b *testing.B) {
+	// long keys with shared prefixes are split into several levels of inner nodes
+	keys := make([][]byte, 100_000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("tenant:%04d:account:%06d:event:%010d\x00", i/10_000, i/100, i))
+	}
+	b.Run("hint", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var (
+				tree Tree
+				hint Hint
+			)
+			for _, key := range keys {
+				tree.InsertWithHint(&hint, key, nil)
+			}
+		}
+	})
+	b.Run("insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var tree Tree
+			for _, key := range keys {
+				tree.Insert(key, nil)
+			}
+		}
+	})
+}
+
+func BenchmarkGetWithHint(b *testing.B) {
+	var tree Tree
+	keys := make([][]byte, 100_000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("tenant:%04d:account:%06d:event:%010d\x00", i/10_000, i/100, i))
+		tree.Insert(keys[i], nil)
+	}
+	b.Run("hint", func(b *testing.B) {
+		var hint Hint
+		for i := 0; i < b.N; i++ {
+			_, _ = tree.GetWithHint(&hint, keys[i%len(keys)])
+		}
+	})
+	b.Run("get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = tree.Get(keys[i%len(keys)])
+		}
+	})
+}
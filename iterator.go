@@ -2,6 +2,7 @@ package art
 
 import (
 	"bytes"
+	"context"
 )
 
 type checkpoint struct {
@@ -9,6 +10,10 @@ type checkpoint struct {
 	parentLock    *olock
 	parentVersion uint64
 	pointer       *byte
+	// edge stores the byte referenced by the pointer after it was advanced.
+	edge byte
+	// depth is an offset in the key where prefix of the node starts.
+	depth int
 
 	prev *checkpoint
 }
@@ -19,32 +24,150 @@ type iterator struct {
 
 	stack  *checkpoint
 	closed bool
+	// released is true after Close.
+	released bool
 
 	cursor, terminate []byte
 	reverse           bool
+	// inclusive is true if the key equal to the cursor must be visited,
+	// set by Seek until the next key is visited.
+	inclusive bool
+	// exclusiveEnd is true if the key equal to the terminate must not be visited.
+	exclusiveEnd bool
+	// ranged is true if iterator was created from the Range, such iterator
+	// visits the same range after Reverse.
+	ranged bool
+
+	// prefix is shared by all visited keys, iteration starts from the node
+	// that holds the prefix instead of the root.
+	prefix []byte
+	// filter is an additional condition for visited keys, optional.
+	filter func(key []byte) bool
+
+	// begin is the initial cursor, used for progress estimation
+	begin   []byte
+	visited uint64
+	// every is a number of visited keys after which the stack is discarded,
+	// and iteration resumes from the cursor.
+	every int
+	// nocopy disables copying of the keys returned by KV.
+	nocopy bool
+	// keysOnly iterator doesn't load values of the visited leaves.
+	keysOnly bool
+	// consistent iterator visits leaves of the snapshot, collected on the first Next.
+	consistent bool
+	snapshot   []*leaf
+	pos        int
+	// ctx aborts iteration when cancelled, err is set to the cause.
+	ctx context.Context
+	err error
 
 	key   []byte
 	value ValueType
 }
 
+// Reverse changes direction of the iteration, must be called before Next.
+// Iterator created with Iterator(start, end) visits [start, end) in descending order after Reverse,
+// use ReverseIterator to visit the same range as the forward iterator.
 func (i *iterator) Reverse() *iterator {
 	i.cursor, i.terminate = i.terminate, i.cursor
+	i.begin = i.cursor
+	if i.ranged {
+		i.inclusive, i.exclusiveEnd = len(i.cursor) > 0 && !i.exclusiveEnd, !i.inclusive
+	}
 	i.reverse = true
 	return i
 }
 
 // Next will iterate over all leaf nodes inbetween specified prefixes
 func (i *iterator) Next() bool {
+	if i.tree.guard != nil {
+		if i.released {
+			panic(&MisuseError{Op: "iterator", Reason: "iterator is used after Close"})
+		}
+		if i.key != nil {
+			i.tree.guard.verify("iterator", i.key)
+		}
+	}
 	if i.closed {
 		return false
 	}
+	if i.ctx != nil {
+		select {
+		case <-i.ctx.Done():
+			i.err = i.ctx.Err()
+			i.closed = true
+			i.stack = nil
+			return false
+		default:
+		}
+	}
+	if i.consistent {
+		return i.advanced(i.nextSnapshot())
+	}
 	if i.stack == nil {
 		// initialize iterator
 		if exit, next := i.init(); exit {
-			return next
+			return i.advanced(next)
 		}
 	}
-	return i.iterate()
+	return i.advanced(i.iterate())
+}
+
+// Close releases checkpoints of the iterator, Next returns false afterwards.
+func (i *iterator) Close() {
+	i.closed = true
+	i.released = true
+	i.stack = nil
+	i.snapshot = nil
+}
+
+// RefreshEvery discards checkpoints of the iterator every n visited keys, iteration
+// resumes by seeking the last visited key from the root. Long scans won't keep versions
+// of the nodes that were modified long time ago, and won't restart from the stale
+// checkpoints.
+func (i *iterator) RefreshEvery(n int) *iterator {
+	i.every = n
+	return i
+}
+
+// Seek repositions the iterator to the first key that is >= key, or <= key
+// if iterator is reversed. Checkpoints shared by the path of the last visited key
+// and the path of the new key are reused, the rest of the path is descended from
+// the deepest of them.
+func (i *iterator) Seek(key []byte) {
+	shared := commonPrefix(i.cursor, key)
+	if len(key) == 0 {
+		i.stack = nil
+	}
+	for i.stack != nil && i.stack.depth > shared {
+		i.stack = i.stack.prev
+	}
+	i.cursor = key
+	i.inclusive = len(key) > 0
+	i.closed = i.released || i.err != nil
+	if i.snapshot != nil {
+		i.seekSnapshot()
+		return
+	}
+	if i.stack != nil && len(key) > 0 && i.seek() {
+		i.stack = nil
+	}
+}
+
+// Err returns the error of the context that aborted iteration, or nil.
+func (i *iterator) Err() error {
+	return i.err
+}
+
+func (i *iterator) advanced(next bool) bool {
+	if next {
+		i.visited++
+		if i.every > 0 && i.visited%uint64(i.every) == 0 {
+			i.stack = nil
+		}
+	}
+	return next
 }
 
 func (i *iterator) Value() ValueType {
@@ -55,14 +178,65 @@ func (i *iterator) Key() []byte {
 	return i.key
 }
 
+// KV returns key and value of the current leaf. Key is copied, unlike the key returned
+// by Key that shares memory with the leaf and must not be modified by the caller.
+func (i *iterator) KV() ([]byte, ValueType) {
+	if i.nocopy {
+		return i.key, i.value
+	}
+	return append([]byte(nil), i.key...), i.value
+}
+
+// NoCopy disables copying of the keys returned by KV.
+func (i *iterator) NoCopy() *iterator {
+	i.nocopy = true
+	return i
+}
+
+// KeysOnly disables loading of the values, Value returns nil. Must be called before Next.
+func (i *iterator) KeysOnly() *iterator {
+	i.keysOnly = true
+	return i
+}
+
+// visit makes the leaf current.
+func (i *iterator) visit(l *leaf) {
+	i.key = l.key
+	if !i.keysOnly {
+		i.value = l.value
+	}
+}
+
 func (i *iterator) inRange(key []byte) bool {
+	if i.prefix != nil && !bytes.HasPrefix(key, i.prefix) {
+		return false
+	}
+	if i.filter != nil && !i.filter(key) {
+		return false
+	}
+	cmp := bytes.Compare(key, i.cursor)
+	if cmp == 0 && i.inclusive {
+		cmp = 1
+		if i.reverse {
+			cmp = -1
+		}
+	}
+	if len(i.terminate) > 0 {
+		term := bytes.Compare(key, i.terminate)
+		if term == 0 && i.exclusiveEnd || term > 0 && !i.reverse || term < 0 && i.reverse {
+			return false
+		}
+	}
 	if !i.reverse {
-		return bytes.Compare(key, i.cursor) > 0 && (len(i.terminate) == 0 || bytes.Compare(key, i.terminate) <= 0)
+		return cmp > 0
 	}
-	return (bytes.Compare(key, i.cursor) < 0 || len(i.cursor) == 0) && (len(i.terminate) == 0 || bytes.Compare(key, i.terminate) >= 0)
+	return cmp < 0 || len(i.cursor) == 0
 }
 
 func (i *iterator) init() (bool, bool) {
+	if i.prefix != nil {
+		return i.seed()
+	}
 	for {
 		version, _ := i.tree.lock.RLock()
 
@@ -81,21 +255,152 @@ func (i *iterator) init() (bool, bool) {
 			}
 			i.closed = true
 			if i.inRange(l.key) {
-				i.key = l.key
-				i.value = l.value
+				i.visit(l)
 				return true, true
 			}
 			return true, false
 		}
+		if i.tree.lock.RUnlock(version, nil) {
+			continue
+		}
 		i.stack = &checkpoint{
 			node:          root.(*inner),
 			parentLock:    &i.tree.lock,
 			parentVersion: version,
 		}
+		if len(i.cursor) > 0 && i.seek() {
+			continue
+		}
 		return false, false
 	}
 }
 
+// seek extends the stack along the path of the cursor, iteration will continue
+// from the first key after the cursor without visiting keys before it.
+// Seek starts from the last checkpoint of the stack, it is used to initialize the iterator
+// and to reposition checkpoints after Seek or after the deeper checkpoint was invalidated.
+// Returns true if concurrent modification was detected and stack needs to be initialized again.
+func (i *iterator) seek() bool {
+	depth := i.stack.depth
+	for {
+		tail := i.stack
+		n := tail.node
+		version, obsolete := n.lock.RLock()
+		if obsolete || tail.parentLock.Check(tail.parentVersion) {
+			_ = n.lock.RUnlock(version, nil)
+			return true
+		}
+		cmp := comparePrefix(n.prefix[:n.prefixLen], i.cursor, 0, depth)
+		if cmp != n.prefixLen || depth+n.prefixLen >= len(i.cursor) {
+			// subtree is either completely before or after the cursor
+			after := depth+cmp >= len(i.cursor) || n.prefix[cmp] > i.cursor[depth+cmp]
+			if after == i.reverse {
+				i.stack = tail.prev
+			} else {
+				// checkpoint may be reused by Seek after it was partially visited
+				tail.pointer = nil
+			}
+			return n.lock.RUnlock(version, nil)
+		}
+		b := i.cursor[depth+n.prefixLen]
+		_, child := n.node.child(b)
+		next, isInner := child.(*inner)
+		if !isInner {
+			// leaf may be after the cursor
+			tail.pointer = before(b, i.reverse)
+			return n.lock.RUnlock(version, nil)
+		}
+		tail.pointer = &b
+		if n.lock.RUnlock(version, nil) {
+			return true
+		}
+		depth += n.prefixLen + 1
+		i.stack = &checkpoint{
+			node:          next,
+			prev:          tail,
+			parentLock:    &n.lock,
+			parentVersion: version,
+			depth:         depth,
+		}
+	}
+}
+
+// before returns pointer that precedes the byte in the direction of the iteration.
+func before(b byte, reverse bool) *byte {
+	if !reverse && b > 0 {
+		b--
+		return &b
+	}
+	if reverse && b < 255 {
+		b++
+		return &b
+	}
+	return nil
+}
+
+// seed initializes iterator from the node that holds all keys with the prefix.
+func (i *iterator) seed() (bool, bool) {
+restart:
+	version, _ := i.tree.lock.RLock()
+	parent := &i.tree.lock
+	next := i.tree.root
+	depth := 0
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l, _ := next.(*leaf)
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			i.closed = true
+			if l != nil && i.inRange(l.key) {
+				i.visit(l)
+				return true, true
+			}
+			return true, false
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete {
+			_ = parent.RUnlock(version, nil)
+			goto restart
+		}
+		cmp := comparePrefix(n.prefix[:n.prefixLen], i.prefix, 0, depth)
+		if cmp != n.prefixLen && depth+cmp != len(i.prefix) {
+			// none of the keys in the subtree has the prefix
+			if n.lock.RUnlock(nversion, nil) || parent.RUnlock(version, nil) {
+				goto restart
+			}
+			i.closed = true
+			return true, false
+		}
+		if depth+n.prefixLen >= len(i.prefix) {
+			// all keys in the subtree have the prefix
+			if n.lock.RUnlock(nversion, nil) {
+				_ = parent.RUnlock(version, nil)
+				goto restart
+			}
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			i.stack = &checkpoint{
+				node:          n,
+				parentLock:    parent,
+				parentVersion: version,
+				depth:         depth,
+			}
+			return false, false
+		}
+		if parent.RUnlock(version, nil) {
+			_ = n.lock.RUnlock(nversion, nil)
+			goto restart
+		}
+		depth += n.prefixLen
+		_, next = n.node.child(i.prefix[depth])
+		depth++
+		parent, version = &n.lock, nversion
+	}
+}
+
 func (i *iterator) next(n *inner, pointer *byte) (byte, node) {
 	if !i.reverse {
 		return n.node.next(pointer)
@@ -109,10 +414,10 @@ func (i *iterator) iterate() bool {
 		if more {
 			return more
 		} else if restart {
-			i.stack = i.stack.prev
+			i.resume()
 			if i.stack == nil {
 				// checkpoint is root
-				i.stack = nil
+				i.tree.count(MetricIteratorRestarts, 1)
 				if exit, next := i.init(); exit {
 					return next
 				}
@@ -123,36 +428,52 @@ func (i *iterator) iterate() bool {
 	return false
 }
 
+// resume discards the invalidated checkpoint and repositions the parent checkpoint
+// to the cursor. Keys of the modified node may have moved to the parent, e.g. when
+// the node was collapsed, therefore the edge that leads to the cursor is visited again
+// instead of advancing past it. Stack is nil if iteration must restart from the root.
+func (i *iterator) resume() {
+	i.stack = i.stack.prev
+	if len(i.cursor) == 0 {
+		// nothing was visited, pointers can't be repositioned
+		i.stack = nil
+	}
+	for i.stack != nil && i.seek() {
+		i.stack = i.stack.prev
+	}
+}
+
 func (i *iterator) tryAdvance() (bool, bool) {
 	for {
 		tail := i.stack
 
 		version, obsolete := tail.node.lock.RLock()
 		if obsolete || tail.parentLock.Check(tail.parentVersion) {
-			_ = tail.parentLock.RUnlock(version, nil)
+			_ = tail.node.lock.RUnlock(version, nil)
 			return false, true
 		}
 
 		pointer, child := i.next(tail.node, tail.pointer)
+		// leaves are immutable, and inner child is validated by its own checkpoint
+		if tail.node.lock.RUnlock(version, nil) {
+			continue
+		}
 
 		if child == nil {
-			if tail.node.lock.RUnlock(version, nil) {
-				continue
-			}
-			_ = tail.parentLock.RUnlock(version, nil)
 			// inner node is exhausted, move one level up the stack
 			i.stack = tail.prev
 			return false, false
 		}
 		// advance pointer
-		tail.pointer = &pointer
+		tail.edge = pointer
+		tail.pointer = &tail.edge
 
 		l, isLeaf := child.(*leaf)
 		if isLeaf {
 			if i.inRange(l.key) {
-				i.key = l.key
-				i.value = l.value
+				i.visit(l)
 				i.cursor = l.key
+				i.inclusive = false
 				return true, false
 			}
 			return false, false
@@ -162,6 +483,7 @@ func (i *iterator) tryAdvance() (bool, bool) {
 			prev:          tail,
 			parentLock:    &tail.node.lock,
 			parentVersion: version,
+			depth:         tail.depth + tail.node.prefixLen + 1,
 		}
 		return false, false
 	}
@@ -1,6 +1,12 @@
 package art
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
 	"sort"
 	"testing"
 
@@ -116,13 +122,34 @@ func TestIterator(t *testing.T) {
 			}
 			rst := []string{}
 			for iter.Next() {
-				rst = append(rst, iter.Value().(string))
+				key, value := iter.KV()
+				require.Equal(t, string(key), value)
+				rst = append(rst, value.(string))
 			}
 			require.Equal(t, tc.rst, rst)
 		})
 	}
 }
 
+func TestIteratorKV(t *testing.T) {
+	var tree Tree
+	tree.Insert([]byte("key1"), 1)
+
+	iter := tree.Iterator(nil, nil)
+	require.True(t, iter.Next())
+	key, value := iter.KV()
+	require.Equal(t, []byte("key1"), key)
+	require.Equal(t, 1, value)
+	key[0] = 'x'
+	_, found := tree.Get([]byte("key1"))
+	require.True(t, found)
+
+	iter = tree.Iterator(nil, nil).NoCopy()
+	require.True(t, iter.Next())
+	key, _ = iter.KV()
+	require.Equal(t, &iter.Key()[0], &key[0])
+}
+
 func TestIterConcurrentExpansion(t *testing.T) {
 	var (
 		tree Tree
@@ -145,3 +172,373 @@ func TestIterConcurrentExpansion(t *testing.T) {
 	require.True(t, iter.Next())
 	require.Equal(t, []byte("aaca"), iter.Key())
 }
+
+func TestIterDeleteCollapsesNode(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{[]byte("a\x00"), []byte("x\x00"), []byte("x0\x00"), []byte("y\x00")}
+	for _, reverse := range []bool{false, true} {
+		for _, key := range keys {
+			tree.Insert(key, nil)
+		}
+		var visited [][]byte
+		iter := tree.Iterator(nil, nil)
+		if reverse {
+			iter.Reverse()
+		}
+		for iter.Next() {
+			visited = append(visited, iter.Key())
+			// node with "x" and "x0" is collapsed into the parent, "x0" is moved
+			// to the edge of the parent that was already visited by the iterator
+			tree.Delete(iter.Key())
+		}
+		require.Len(t, visited, len(keys), "reverse %v", reverse)
+	}
+}
+
+func TestIterResumeFromParent(t *testing.T) {
+	var sink countingSink
+	tree := New(WithMetrics(&sink))
+	for _, key := range []string{"a1\x00", "a2\x00", "a3\x00", "b\x00"} {
+		tree.Insert([]byte(key), nil)
+	}
+	iter := tree.Iterator(nil, nil)
+	require.True(t, iter.Next())
+	require.Equal(t, []byte("a1\x00"), iter.Key())
+	// root is modified, checkpoint of the node with "a" keys is invalidated
+	// and repositioned from the root checkpoint, which is still valid
+	tree.Insert([]byte("c\x00"), nil)
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.Equal(t, []string{"a2\x00", "a3\x00", "b\x00", "c\x00"}, keys)
+	require.Zero(t, sink[MetricIteratorRestarts])
+}
+
+func TestIteratorSeek(t *testing.T) {
+	var tree Tree
+	rng := rand.New(rand.NewSource(7))
+	sorted := []string{}
+	seen := map[string]bool{}
+	for len(sorted) < 3000 {
+		key := make([]byte, 1+rng.Intn(6))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 80)
+		}
+		key = append(key, 1)
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		tree.Insert(key, nil)
+		sorted = append(sorted, string(key))
+	}
+	sort.Strings(sorted)
+
+	bound := func() []byte {
+		if rng.Intn(10) == 0 {
+			return nil
+		}
+		if rng.Intn(2) == 0 {
+			return []byte(sorted[rng.Intn(len(sorted))])
+		}
+		key := make([]byte, rng.Intn(7))
+		for j := range key {
+			key[j] = byte(rng.Intn(256))
+		}
+		return key
+	}
+	for i := 0; i < 100; i++ {
+		start, end := bound(), bound()
+		if end != nil && bytes.Compare(start, end) > 0 {
+			start, end = end, start
+		}
+		expected := []string{}
+		for _, key := range sorted {
+			if key > string(start) && (end == nil || key <= string(end)) {
+				expected = append(expected, key)
+			}
+		}
+		for _, every := range []int{0, 1, 7} {
+			rst := []string{}
+			iter := tree.Iterator(start, end).RefreshEvery(every)
+			for iter.Next() {
+				rst = append(rst, string(iter.Key()))
+			}
+			require.Equal(t, expected, rst, "range (%v, %v] every %d", start, end, every)
+		}
+		if start == nil {
+			continue
+		}
+		// reverse iteration is in range [start, end)
+		expected = expected[:0]
+		for j := len(sorted) - 1; j >= 0; j-- {
+			key := sorted[j]
+			if key >= string(start) && (end == nil || key < string(end)) {
+				expected = append(expected, key)
+			}
+		}
+		for _, every := range []int{0, 1, 7} {
+			rst := []string{}
+			iter := tree.Iterator(start, end).Reverse().RefreshEvery(every)
+			for iter.Next() {
+				rst = append(rst, string(iter.Key()))
+			}
+			require.Equal(t, expected, rst, "reverse range (%v, %v] every %d", start, end, every)
+		}
+	}
+}
+
+func TestIteratorSeekRepositions(t *testing.T) {
+	var tree Tree
+	rng := rand.New(rand.NewSource(11))
+	sorted := []string{}
+	seen := map[string]bool{}
+	for len(sorted) < 3000 {
+		key := make([]byte, 1+rng.Intn(6))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 80)
+		}
+		key = append(key, 1)
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		tree.Insert(key, nil)
+		sorted = append(sorted, string(key))
+	}
+	sort.Strings(sorted)
+
+	target := func() []byte {
+		if rng.Intn(2) == 0 {
+			return []byte(sorted[rng.Intn(len(sorted))])
+		}
+		key := make([]byte, 1+rng.Intn(7))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 80)
+		}
+		return key
+	}
+	for _, reverse := range []bool{false, true} {
+		iter := tree.Iterator(nil, nil)
+		if reverse {
+			iter = iter.Reverse()
+		}
+		for i := 0; i < 200; i++ {
+			key := target()
+			iter.Seek(key)
+			// first key >= key, or last key <= key if reversed
+			j := sort.SearchStrings(sorted, string(key))
+			if reverse && (j == len(sorted) || sorted[j] != string(key)) {
+				j--
+			}
+			steps := rng.Intn(20)
+			for k := 0; k < steps; k++ {
+				if j < 0 || j >= len(sorted) {
+					require.False(t, iter.Next())
+					break
+				}
+				require.True(t, iter.Next(), "seek %v reverse %v step %d", key, reverse, k)
+				require.Equal(t, sorted[j], string(iter.Key()), "seek %v reverse %v step %d", key, reverse, k)
+				if reverse {
+					j--
+				} else {
+					j++
+				}
+			}
+		}
+	}
+}
+
+func TestIteratorRefreshConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	for i := 0; i < 10000; i += 2 {
+		tree.Insert([]byte{byte(i >> 8), byte(i), 0}, i)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 10000; i += 2 {
+			tree.Insert([]byte{byte(i >> 8), byte(i), 0}, i)
+		}
+	}()
+	iter := tree.Iterator(nil, nil).RefreshEvery(100)
+	prev := -1
+	for iter.Next() {
+		value := iter.Value().(int)
+		require.Greater(t, value, prev)
+		if value%2 == 0 {
+			require.True(t, value-prev <= 2, "skipped keys between %d and %d", prev, value)
+		}
+		prev = value
+	}
+	require.True(t, prev >= 9998)
+	<-done
+}
+
+func TestIteratorCtx(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 1000; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	iter := tree.IteratorCtx(ctx, nil, nil)
+	count := 0
+	for iter.Next() {
+		count++
+		if count == 100 {
+			cancel()
+		}
+	}
+	require.Equal(t, 100, count)
+	require.True(t, errors.Is(iter.Err(), context.Canceled))
+
+	// seek doesn't resume cancelled iterator
+	iter.Seek(sequentialKey(500))
+	require.False(t, iter.Next())
+
+	iter = tree.IteratorCtx(context.Background(), nil, nil)
+	count = 0
+	for iter.Next() {
+		count++
+	}
+	require.Equal(t, 1000, count)
+	require.NoError(t, iter.Err())
+}
+
+func TestIteratorSparseNode256(t *testing.T) {
+	var (
+		tree     Tree
+		expected [][]byte
+	)
+	// node256 with children in the lowest and highest slots
+	for b := 0; b < 256; b += 5 {
+		expected = append(expected, []byte{byte(b), 0})
+	}
+	for _, key := range expected {
+		tree.Insert(key, nil)
+	}
+	require.IsType(t, &node256{}, tree.root.(*inner).node)
+
+	var keys [][]byte
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		keys = append(keys, iter.Key())
+	}
+	require.Equal(t, expected, keys)
+
+	keys = keys[:0]
+	for iter := tree.Iterator(nil, nil).Reverse(); iter.Next(); {
+		keys = append([][]byte{iter.Key()}, keys...)
+	}
+	require.Equal(t, expected, keys)
+
+	keys = keys[:0]
+	for iter := tree.Scan(From([]byte{0xfa})).Reverse(); iter.Next(); {
+		keys = append([][]byte{iter.Key()}, keys...)
+	}
+	require.Equal(t, expected[len(expected)-2:], keys)
+}
+
+func BenchmarkIterateSparseNode256(b *testing.B) {
+	var tree Tree
+	// every inner node is node256 with 52 children
+	for i := 0; i < 256; i += 5 {
+		for j := 0; j < 256; j += 5 {
+			tree.Insert([]byte{byte(i), byte(j), 0}, nil)
+		}
+	}
+	for _, reverse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reverse=%v", reverse), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				iter := tree.Iterator(nil, nil)
+				if reverse {
+					iter.Reverse()
+				}
+				for iter.Next() {
+				}
+			}
+		})
+	}
+}
+
+func TestReverseIterator(t *testing.T) {
+	rng := rand.New(rand.NewSource(*seed))
+	for _, fanout := range []int{3, 12, 40, 200} {
+		fanout := fanout
+		t.Run(fmt.Sprintf("fanout %d", fanout), func(t *testing.T) {
+			// fanout selects node kinds: node4, node16, node48 and node256
+			var tree Tree
+			keys := map[string]struct{}{}
+			for i := 0; i < 2000; i++ {
+				key := []byte{byte(rng.Intn(fanout)), byte(rng.Intn(fanout) * 256 / fanout), byte(rng.Intn(256))}
+				tree.Insert(key, string(key))
+				keys[string(key)] = struct{}{}
+			}
+			sorted := make([]string, 0, len(keys))
+			for key := range keys {
+				sorted = append(sorted, key)
+			}
+			sort.Strings(sorted)
+			bound := func() []byte {
+				if rng.Intn(5) == 0 {
+					return nil
+				}
+				if rng.Intn(2) == 0 {
+					return []byte(sorted[rng.Intn(len(sorted))])
+				}
+				return []byte{byte(rng.Intn(fanout)), byte(rng.Intn(256))}
+			}
+			for i := 0; i < 200; i++ {
+				start, end := bound(), bound()
+				expected := []string{}
+				for j := len(sorted) - 1; j >= 0; j-- {
+					key := sorted[j]
+					if (start == nil || key > string(start)) && (end == nil || key <= string(end)) {
+						expected = append(expected, key)
+					}
+				}
+				rst := []string{}
+				for iter := tree.ReverseIterator(start, end); iter.Next(); {
+					rst = append(rst, string(iter.Key()))
+				}
+				require.Equal(t, expected, rst, "start %v end %v", start, end)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 100; i++ {
+		tree.Insert([]byte{byte(i)}, i)
+	}
+	var rst []byte
+	for iter := tree.Keys([]byte{10}, []byte{20}); iter.Next(); {
+		require.Nil(t, iter.Value())
+		rst = append(rst, iter.Key()...)
+	}
+	require.Equal(t, []byte{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, rst)
+
+	iter := tree.Keys(nil, nil).Reverse()
+	require.True(t, iter.Next())
+	require.Equal(t, []byte{99}, iter.Key())
+	require.Nil(t, iter.Value())
+}
+
+func BenchmarkIterateKeys(b *testing.B) {
+	var tree Tree
+	for i := 0; i < 100_000; i++ {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(i))
+		tree.Insert(key, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for iter := tree.Keys(nil, nil); iter.Next(); {
+			_ = iter.Key()
+		}
+	}
+}
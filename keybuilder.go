@@ -0,0 +1,152 @@
+package art
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Variable-length fields are terminated by escapeByte followed by terminatorByte,
+// escapeByte in the field is followed by escapedByte. Terminator is ordered before
+// any escaped content, therefore shorter field is ordered before the longer field with the same prefix.
+const (
+	escapeByte     byte = 0x00
+	terminatorByte byte = 0x01
+	escapedByte    byte = 0xff
+)
+
+// KeyBuilder concatenates fields into a composite key with lexicographic order that
+// matches order of the fields. Variable-length fields are escaped and terminated,
+// numbers are encoded the same way as by Uint64Key, Int64Key and so on.
+// Keys built with the same sequence of field types are never prefixes of each other,
+// key with a subset of leading fields can be used as a prefix for iteration.
+// Zero value is ready to use.
+type KeyBuilder struct {
+	buf []byte
+}
+
+// Bytes appends variable-length field.
+func (b *KeyBuilder) Bytes(v []byte) *KeyBuilder {
+	for {
+		i := bytes.IndexByte(v, escapeByte)
+		if i < 0 {
+			break
+		}
+		b.buf = append(b.buf, v[:i+1]...)
+		b.buf = append(b.buf, escapedByte)
+		v = v[i+1:]
+	}
+	b.buf = append(b.buf, v...)
+	b.buf = append(b.buf, escapeByte, terminatorByte)
+	return b
+}
+
+// String appends variable-length field.
+func (b *KeyBuilder) String(v string) *KeyBuilder {
+	return b.Bytes([]byte(v))
+}
+
+func (b *KeyBuilder) Uint64(v uint64) *KeyBuilder {
+	b.buf = binary.BigEndian.AppendUint64(b.buf, v)
+	return b
+}
+
+func (b *KeyBuilder) Int64(v int64) *KeyBuilder {
+	return b.Uint64(uint64(v) ^ 1<<63)
+}
+
+func (b *KeyBuilder) Time(v time.Time) *KeyBuilder {
+	b.buf = append(b.buf, TimeKey(v)...)
+	return b
+}
+
+// UUID appends fixed 16 bytes.
+func (b *KeyBuilder) UUID(v [16]byte) *KeyBuilder {
+	b.buf = append(b.buf, v[:]...)
+	return b
+}
+
+// Key returns a copy of the built key, builder can be reused after Reset.
+func (b *KeyBuilder) Key() []byte {
+	return append([]byte(nil), b.buf...)
+}
+
+func (b *KeyBuilder) Reset() {
+	b.buf = b.buf[:0]
+}
+
+// KeyReader decodes fields of the key built by KeyBuilder, in the same order.
+type KeyReader struct {
+	key []byte
+	err error
+}
+
+func NewKeyReader(key []byte) *KeyReader {
+	return &KeyReader{key: key}
+}
+
+// Err returns ErrCorrupt if any of the fields couldn't be decoded.
+func (r *KeyReader) Err() error {
+	return r.err
+}
+
+func (r *KeyReader) fixed(size int) []byte {
+	if r.err != nil {
+		return make([]byte, size)
+	}
+	if len(r.key) < size {
+		r.err = fmt.Errorf("%w: key is too short for %d bytes field", ErrCorrupt, size)
+		return make([]byte, size)
+	}
+	rst := r.key[:size]
+	r.key = r.key[size:]
+	return rst
+}
+
+func (r *KeyReader) ReadBytes() []byte {
+	if r.err != nil {
+		return nil
+	}
+	var rst []byte
+	for {
+		i := bytes.IndexByte(r.key, escapeByte)
+		if i < 0 || i+1 == len(r.key) {
+			r.err = fmt.Errorf("%w: field is not terminated", ErrCorrupt)
+			return nil
+		}
+		rst = append(rst, r.key[:i+1]...)
+		next := r.key[i+1]
+		r.key = r.key[i+2:]
+		switch next {
+		case terminatorByte:
+			return rst[:len(rst)-1]
+		case escapedByte:
+		default:
+			r.err = fmt.Errorf("%w: invalid escape sequence %x", ErrCorrupt, next)
+			return nil
+		}
+	}
+}
+
+func (r *KeyReader) ReadString() string {
+	return string(r.ReadBytes())
+}
+
+func (r *KeyReader) ReadUint64() uint64 {
+	return binary.BigEndian.Uint64(r.fixed(8))
+}
+
+func (r *KeyReader) ReadInt64() int64 {
+	return int64(r.ReadUint64() ^ 1<<63)
+}
+
+func (r *KeyReader) ReadTime() time.Time {
+	return DecodeTimeKey(r.fixed(12))
+}
+
+func (r *KeyReader) ReadUUID() [16]byte {
+	var rst [16]byte
+	copy(rst[:], r.fixed(16))
+	return rst
+}
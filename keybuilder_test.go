@@ -0,0 +1,93 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBuilderOrder(t *testing.T) {
+	type row struct {
+		tenant string
+		id     int64
+		name   string
+	}
+	rows := []row{
+		{"a", -1, ""},
+		{"a", 0, "x"},
+		{"a", 0, "x\x00"},
+		{"a", 0, "x\x00y"},
+		{"a", 0, "x\x01"},
+		{"a", 0, "xy"},
+		{"a", 1, ""},
+		{"a\x00", -5, "z"},
+		{"a\x00\x00", -5, "z"},
+		{"a\x01", -5, "z"},
+		{"ab", -10, "z"},
+		{"b", -10, ""},
+	}
+	var (
+		b    KeyBuilder
+		keys [][]byte
+	)
+	for _, r := range rows {
+		b.Reset()
+		keys = append(keys, b.String(r.tenant).Int64(r.id).String(r.name).Key())
+	}
+	require.True(t, sort.SliceIsSorted(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	}))
+	for i := range keys {
+		for j := range keys {
+			if i != j {
+				require.False(t, bytes.HasPrefix(keys[i], keys[j]), "%x is a prefix of %x", keys[j], keys[i])
+			}
+		}
+		r := NewKeyReader(keys[i])
+		require.Equal(t, rows[i], row{r.ReadString(), r.ReadInt64(), r.ReadString()})
+		require.NoError(t, r.Err())
+	}
+}
+
+func TestKeyBuilderPrefix(t *testing.T) {
+	var tree Tree
+	var b KeyBuilder
+	for _, tenant := range []string{"t1", "t10", "t2"} {
+		for i := uint64(0); i < 3; i++ {
+			b.Reset()
+			tree.Insert(b.String(tenant).Uint64(i).Key(), tenant)
+		}
+	}
+	b.Reset()
+	iter := tree.Prefix(b.String("t1").Key())
+	count := 0
+	for iter.Next() {
+		require.Equal(t, "t1", iter.Value())
+		count++
+	}
+	require.Equal(t, 3, count)
+}
+
+func TestKeyReaderFields(t *testing.T) {
+	now := time.Now()
+	uuid := [16]byte{1, 2, 3}
+	var b KeyBuilder
+	key := b.Bytes([]byte{0, 0xff, 0}).Uint64(7).Time(now).UUID(uuid).Key()
+	r := NewKeyReader(key)
+	require.Equal(t, []byte{0, 0xff, 0}, r.ReadBytes())
+	require.Equal(t, uint64(7), r.ReadUint64())
+	require.True(t, now.Equal(r.ReadTime()))
+	require.Equal(t, uuid, r.ReadUUID())
+	require.NoError(t, r.Err())
+
+	r = NewKeyReader([]byte("unterminated"))
+	r.ReadString()
+	require.True(t, errors.Is(r.Err(), ErrCorrupt))
+	r = NewKeyReader([]byte{1})
+	r.ReadUint64()
+	require.True(t, errors.Is(r.Err(), ErrCorrupt))
+}
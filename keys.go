@@ -0,0 +1,67 @@
+package art
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Key encodings below produce fixed-length keys with lexicographic order that matches
+// natural order of the values. Keys of the same type are never prefixes of each other.
+
+// Uint64Key encodes v in big endian order.
+func Uint64Key(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// DecodeUint64Key decodes key encoded by Uint64Key.
+func DecodeUint64Key(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key)
+}
+
+// Int64Key encodes v with the sign bit flipped, so that negative values
+// are ordered before positive.
+func Int64Key(v int64) []byte {
+	return Uint64Key(uint64(v) ^ 1<<63)
+}
+
+// DecodeInt64Key decodes key encoded by Int64Key.
+func DecodeInt64Key(key []byte) int64 {
+	return int64(DecodeUint64Key(key) ^ 1<<63)
+}
+
+// Float64Key encodes v so that keys are ordered as floats: positive values have
+// the sign bit flipped, negative values have all bits flipped.
+// NaN is ordered after positive infinity.
+func Float64Key(v float64) []byte {
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	return Uint64Key(bits)
+}
+
+// DecodeFloat64Key decodes key encoded by Float64Key.
+func DecodeFloat64Key(key []byte) float64 {
+	bits := DecodeUint64Key(key)
+	if bits&(1<<63) != 0 {
+		bits &^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits)
+}
+
+// TimeKey encodes t as seconds since unix epoch followed by nanoseconds, 12 bytes in total.
+// Unlike UnixNano the whole range of time.Time is supported. Location is not encoded.
+func TimeKey(t time.Time) []byte {
+	key := Int64Key(t.Unix())
+	return binary.BigEndian.AppendUint32(key, uint32(t.Nanosecond()))
+}
+
+// DecodeTimeKey decodes key encoded by TimeKey, time is returned in the local location.
+func DecodeTimeKey(key []byte) time.Time {
+	return time.Unix(DecodeInt64Key(key[:8]), int64(binary.BigEndian.Uint32(key[8:])))
+}
@@ -0,0 +1,97 @@
+package art
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireOrdered(t *testing.T, keys [][]byte) {
+	t.Helper()
+	for i := 1; i < len(keys); i++ {
+		require.True(t, bytes.Compare(keys[i-1], keys[i]) < 0, "%x >= %x", keys[i-1], keys[i])
+	}
+}
+
+func TestKeyEncodings(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	t.Run("uint64", func(t *testing.T) {
+		values := []uint64{0, 1, 255, 256, 1 << 32, math.MaxUint64}
+		var keys [][]byte
+		for _, v := range values {
+			keys = append(keys, Uint64Key(v))
+			require.Equal(t, v, DecodeUint64Key(Uint64Key(v)))
+		}
+		requireOrdered(t, keys)
+	})
+	t.Run("int64", func(t *testing.T) {
+		values := []int64{math.MinInt64, -1 << 32, -256, -1, 0, 1, 256, math.MaxInt64}
+		for i := 0; i < 100; i++ {
+			values = append(values, rng.Int63()-rng.Int63())
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		var keys [][]byte
+		for i, v := range values {
+			if i > 0 && values[i-1] == v {
+				continue
+			}
+			keys = append(keys, Int64Key(v))
+			require.Equal(t, v, DecodeInt64Key(Int64Key(v)))
+		}
+		requireOrdered(t, keys)
+	})
+	t.Run("float64", func(t *testing.T) {
+		values := []float64{math.Inf(-1), -math.MaxFloat64, -1, -math.SmallestNonzeroFloat64, 0,
+			math.SmallestNonzeroFloat64, 0.5, 1, math.MaxFloat64, math.Inf(1)}
+		for i := 0; i < 100; i++ {
+			values = append(values, rng.NormFloat64()*1e10)
+		}
+		sort.Float64s(values)
+		var keys [][]byte
+		for i, v := range values {
+			if i > 0 && values[i-1] == v {
+				continue
+			}
+			keys = append(keys, Float64Key(v))
+			require.Equal(t, v, DecodeFloat64Key(Float64Key(v)))
+		}
+		requireOrdered(t, keys)
+		require.True(t, bytes.Compare(Float64Key(math.Inf(1)), Float64Key(math.NaN())) < 0)
+	})
+	t.Run("time", func(t *testing.T) {
+		now := time.Now()
+		values := []time.Time{
+			time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Unix(-1, 999_999_999),
+			time.Unix(0, 0),
+			now,
+			now.Add(time.Nanosecond),
+			time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		var keys [][]byte
+		for _, v := range values {
+			keys = append(keys, TimeKey(v))
+			require.True(t, v.Equal(DecodeTimeKey(TimeKey(v))))
+		}
+		requireOrdered(t, keys)
+	})
+}
+
+func TestKeyEncodingsIteration(t *testing.T) {
+	var tree Tree
+	for v := int64(-100); v <= 100; v++ {
+		tree.Insert(Int64Key(v), v)
+	}
+	expected := int64(-100)
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		require.Equal(t, expected, iter.Value())
+		require.Equal(t, expected, DecodeInt64Key(iter.Key()))
+		expected++
+	}
+	require.Equal(t, int64(101), expected)
+}
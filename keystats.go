@@ -0,0 +1,151 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// prefixThreshold is a minimal fraction of sampled keys that must share a prefix,
+// for the prefix to be reported as common.
+const prefixThreshold = 0.1
+
+// PrefixCount is a prefix shared by the number of sampled keys.
+type PrefixCount struct {
+	Prefix []byte
+	Count  int
+}
+
+// KeyStats describes distribution of the sampled keys. Used as guidance for choosing
+// shard bytes, dictionary prefixes and fixed-length specializations.
+type KeyStats struct {
+	Samples              int
+	MinLength, MaxLength int
+	// Entropy in bits of the byte at every position, for keys that are long enough.
+	// Bytes with entropy close to 8 are good candidates for shard bytes.
+	Entropy []float64
+	// CommonPrefix is shared by all sampled keys.
+	CommonPrefix []byte
+	// Prefixes are the longest prefixes, up to maxPrefixLen, shared by at least
+	// 10% of the sampled keys. Ordered by count.
+	Prefixes []PrefixCount
+}
+
+// FixedLength returns true if all sampled keys have the same length.
+func (s KeyStats) FixedLength() bool {
+	return s.Samples > 0 && s.MinLength == s.MaxLength
+}
+
+func (s KeyStats) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "samples: %d\n", s.Samples)
+	if s.Samples == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "length: %d-%d\n", s.MinLength, s.MaxLength)
+	fmt.Fprintf(&b, "common prefix: %x\n", s.CommonPrefix)
+	for _, p := range s.Prefixes {
+		fmt.Fprintf(&b, "prefix %x: %d\n", p.Prefix, p.Count)
+	}
+	_, _ = b.WriteString("entropy:")
+	for i, e := range s.Entropy {
+		fmt.Fprintf(&b, " %d=%.2f", i, e)
+	}
+	return b.String()
+}
+
+// KeyStats samples random keys from the tree and reports statistics about them.
+func (t *Tree) KeyStats(samples int) KeyStats {
+	keys := make([][]byte, 0, samples)
+	for i := 0; i < samples; i++ {
+		l := t.sample()
+		if l == nil {
+			break
+		}
+		keys = append(keys, l.key)
+	}
+	return analyzeKeys(keys)
+}
+
+func analyzeKeys(keys [][]byte) KeyStats {
+	stats := KeyStats{Samples: len(keys)}
+	if len(keys) == 0 {
+		return stats
+	}
+	stats.MinLength, stats.MaxLength = len(keys[0]), len(keys[0])
+	stats.CommonPrefix = keys[0]
+	for _, key := range keys[1:] {
+		if len(key) < stats.MinLength {
+			stats.MinLength = len(key)
+		}
+		if len(key) > stats.MaxLength {
+			stats.MaxLength = len(key)
+		}
+		stats.CommonPrefix = stats.CommonPrefix[:commonPrefix(stats.CommonPrefix, key)]
+	}
+	stats.CommonPrefix = append([]byte(nil), stats.CommonPrefix...)
+
+	stats.Entropy = make([]float64, stats.MaxLength)
+	for pos := range stats.Entropy {
+		var (
+			counts [256]int
+			total  int
+		)
+		for _, key := range keys {
+			if pos < len(key) {
+				counts[key[pos]]++
+				total++
+			}
+		}
+		for _, c := range counts {
+			if c > 0 {
+				p := float64(c) / float64(total)
+				stats.Entropy[pos] -= p * math.Log2(p)
+			}
+		}
+	}
+
+	stats.Prefixes = frequentPrefixes(keys)
+	return stats
+}
+
+// frequentPrefixes returns longest prefixes that are shared by at least prefixThreshold
+// of the keys. Prefix is omitted if it is shared by the same keys as a longer prefix.
+func frequentPrefixes(keys [][]byte) []PrefixCount {
+	min := int(math.Ceil(prefixThreshold * float64(len(keys))))
+	if min < 2 {
+		min = 2
+	}
+	var rst []PrefixCount
+	for lth := maxPrefixLen; lth > 0; lth-- {
+		counts := map[string]int{}
+		for _, key := range keys {
+			if len(key) >= lth {
+				counts[string(key[:lth])]++
+			}
+		}
+		for prefix, count := range counts {
+			if count < min {
+				continue
+			}
+			covered := false
+			for _, longer := range rst {
+				if longer.Count == count && bytes.HasPrefix(longer.Prefix, []byte(prefix)) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				rst = append(rst, PrefixCount{Prefix: []byte(prefix), Count: count})
+			}
+		}
+	}
+	sort.Slice(rst, func(i, j int) bool {
+		if rst[i].Count != rst[j].Count {
+			return rst[i].Count > rst[j].Count
+		}
+		return bytes.Compare(rst[i].Prefix, rst[j].Prefix) < 0
+	})
+	return rst
+}
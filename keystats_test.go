@@ -0,0 +1,46 @@
+package art
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeKeys(t *testing.T) {
+	keys := [][]byte{}
+	for i := 0; i < 256; i++ {
+		prefix := []byte("user")
+		if i%4 == 0 {
+			prefix = []byte("item")
+		}
+		keys = append(keys, append(prefix, 0, byte(i)))
+	}
+	stats := analyzeKeys(keys)
+	require.Equal(t, 256, stats.Samples)
+	require.True(t, stats.FixedLength())
+	require.Empty(t, stats.CommonPrefix)
+	require.Equal(t, []PrefixCount{
+		{Prefix: []byte("user\x00"), Count: 192},
+		{Prefix: []byte("item\x00"), Count: 64},
+	}, stats.Prefixes)
+	require.Equal(t, 0.0, stats.Entropy[4])
+	require.InDelta(t, 8.0, stats.Entropy[5], 0.001)
+	require.Greater(t, stats.Entropy[0], 0.5)
+}
+
+func TestTreeKeyStats(t *testing.T) {
+	var tree Tree
+	require.Equal(t, 0, tree.KeyStats(10).Samples)
+	for i := 0; i < 1000; i++ {
+		key := make([]byte, 10)
+		copy(key, "prefix")
+		rand.Read(key[6:])
+		tree.Insert(key, i)
+	}
+	stats := tree.KeyStats(100)
+	require.Equal(t, 100, stats.Samples)
+	require.Equal(t, []byte("prefix"), stats.CommonPrefix[:6])
+	require.Equal(t, 10, stats.MinLength)
+	require.Greater(t, stats.Entropy[6], 4.0)
+}
@@ -0,0 +1,173 @@
+package art
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tombstone is stored in the mutable tree in place of the deleted key,
+// to hide the key in the frozen tree until merge.
+type tombstoneType struct{}
+
+var tombstone ValueType = &tombstoneType{}
+
+// layers is an immutable set of trees, replaced atomically on merge.
+type layers struct {
+	// active receives all writes.
+	active *Tree
+	// merging is the previous active tree, that is being merged into frozen tree.
+	// Not modified anymore.
+	merging *Tree
+	// frozen is never modified after it was published.
+	frozen *Tree
+}
+
+// LayeredTree maintains a small mutable tree in front of a large frozen tree.
+// Writes go to the mutable tree, reads consult the mutable tree and then the frozen one.
+// Merge replaces mutable tree with an empty one and builds a new frozen tree from
+// both of them, readers are never blocked by the merge, and the frozen tree is never
+// locked for writing.
+type LayeredTree struct {
+	// mu is held for reading by writers and for writing when active tree is replaced,
+	// so that no writes are lost in the tree that is being merged.
+	mu     sync.RWMutex
+	layers atomic.Pointer[layers]
+	// merge serializes merges, layers are replaced only while it is held.
+	merge sync.Mutex
+}
+
+func NewLayeredTree() *LayeredTree {
+	t := &LayeredTree{}
+	t.layers.Store(&layers{active: New(), frozen: New()})
+	return t
+}
+
+func (t *LayeredTree) Insert(key []byte, value ValueType) {
+	t.mu.RLock()
+	t.layers.Load().active.Insert(key, value)
+	t.mu.RUnlock()
+}
+
+func (t *LayeredTree) Delete(key []byte) {
+	t.mu.RLock()
+	t.layers.Load().active.Insert(key, tombstone)
+	t.mu.RUnlock()
+}
+
+func (t *LayeredTree) Get(key []byte) (ValueType, bool) {
+	l := t.layers.Load()
+	for _, tree := range []*Tree{l.active, l.merging, l.frozen} {
+		if tree == nil {
+			continue
+		}
+		value, found := tree.Get(key)
+		if found {
+			if value == tombstone {
+				return nil, false
+			}
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Pending returns number of modifications in the mutable tree.
+func (t *LayeredTree) Pending() int {
+	return t.layers.Load().active.Len()
+}
+
+// Merge replaces mutable tree with an empty one, and merges it into the new frozen tree.
+// Readers observe the old frozen tree until the new one is complete.
+func (t *LayeredTree) Merge() {
+	t.merge.Lock()
+	defer t.merge.Unlock()
+
+	t.mu.Lock()
+	prev := t.layers.Load()
+	merging := &layers{active: New(), merging: prev.active, frozen: prev.frozen}
+	t.layers.Store(merging)
+	t.mu.Unlock()
+
+	// merged stream is sorted, frozen tree is built bottom-up instead of inserting every key
+	frozen, err := Build(context.Background(), &mergedIterator{
+		lower: prev.frozen.Iterator(nil, nil),
+		upper: prev.active.Iterator(nil, nil),
+	}, 1)
+	if err != nil {
+		// context is never canceled, keys are rejected only if one is a prefix of another
+		panic(fmt.Sprintf("art: merge failed: %v", err))
+	}
+
+	t.layers.Store(&layers{active: merging.active, frozen: frozen})
+}
+
+// MergeWhenFull runs Merge every time when the mutable tree has at least pending
+// modifications, checked every interval.
+// Blocks until context is canceled.
+func (t *LayeredTree) MergeWhenFull(ctx context.Context, interval time.Duration, pending int) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if t.Pending() >= pending {
+				t.Merge()
+			}
+		}
+	}
+}
+
+// mergedIterator visits keys of both iterators in lexicographic order, skipping tombstones.
+// If key is present in both iterators value from the upper is used.
+type mergedIterator struct {
+	lower, upper *iterator
+	started      bool
+	lok, uok     bool
+
+	key   []byte
+	value ValueType
+}
+
+func (m *mergedIterator) Next() bool {
+	if !m.started {
+		m.started = true
+		m.lok, m.uok = m.lower.Next(), m.upper.Next()
+	}
+	for m.lok || m.uok {
+		cmp := 1
+		if m.lok && m.uok {
+			cmp = bytes.Compare(m.lower.Key(), m.upper.Key())
+		} else if m.lok {
+			cmp = -1
+		}
+		switch {
+		case cmp < 0:
+			m.key, m.value = m.lower.Key(), m.lower.Value()
+			m.lok = m.lower.Next()
+		case cmp > 0:
+			m.key, m.value = m.upper.Key(), m.upper.Value()
+			m.uok = m.upper.Next()
+		default:
+			m.key, m.value = m.upper.Key(), m.upper.Value()
+			m.lok, m.uok = m.lower.Next(), m.upper.Next()
+		}
+		if m.value != tombstone {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mergedIterator) Key() []byte {
+	return m.key
+}
+
+func (m *mergedIterator) Value() ValueType {
+	return m.value
+}
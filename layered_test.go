@@ -0,0 +1,86 @@
+package art
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayeredTree(t *testing.T) {
+	tree := NewLayeredTree()
+	key := func(i int) []byte {
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(i))
+		return buf
+	}
+	for i := 0; i < 100; i++ {
+		tree.Insert(key(i), i)
+	}
+	require.Equal(t, 100, tree.Pending())
+	tree.Merge()
+	require.Equal(t, 0, tree.Pending())
+
+	for i := 0; i < 100; i += 2 {
+		tree.Delete(key(i))
+	}
+	for i := 100; i < 150; i++ {
+		tree.Insert(key(i), i)
+	}
+	tree.Insert(key(1), -1)
+
+	check := func() {
+		for i := 0; i < 150; i++ {
+			value, found := tree.Get(key(i))
+			switch {
+			case i == 1:
+				require.True(t, found)
+				require.Equal(t, -1, value)
+			case i < 100 && i%2 == 0:
+				require.False(t, found, "key %d", i)
+			default:
+				require.True(t, found, "key %d", i)
+				require.Equal(t, i, value)
+			}
+		}
+	}
+	check()
+	tree.Merge()
+	check()
+	require.Equal(t, 100, countKeys(tree.layers.Load().frozen))
+}
+
+func TestLayeredTreeConcurrentMerge(t *testing.T) {
+	skipUnsafe(t)
+	tree := NewLayeredTree()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	merged := make(chan error, 1)
+	go func() {
+		merged <- tree.MergeWhenFull(ctx, time.Millisecond, 10)
+	}()
+
+	n := 4
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := []byte{byte(w), byte(i >> 8), byte(i)}
+				tree.Insert(key, i)
+				value, found := tree.Get(key)
+				require.True(t, found)
+				require.Equal(t, i, value)
+			}
+		}(w)
+	}
+	wg.Wait()
+	cancel()
+	require.Equal(t, context.Canceled, <-merged)
+	tree.Merge()
+	require.Equal(t, n*2000, countKeys(tree.layers.Load().frozen))
+}
@@ -71,6 +71,7 @@ var singleKeyModel = porcupine.Model{
 }
 
 func TestLinearGetInsert(t *testing.T) {
+	skipUnsafe(t)
 	var tree Tree
 	for i := 0; i < 1_000_000; i++ {
 		key := make([]byte, 10)
@@ -162,7 +163,12 @@ func TestLinearGetInsert(t *testing.T) {
 	}()
 
 	wg.Wait()
+	checkHistory(t, singleKeyModel, histories, "single-key")
+}
 
+// checkHistory checks that history is linearizable with respect to the model.
+// History is visualized into a temporary file if the check failed, or if requested by the flag.
+func checkHistory(t *testing.T, model porcupine.Model, histories []timestampedEvent, name string) {
 	sort.Slice(histories, func(i, j int) bool {
 		return histories[i].timestamp < histories[j].timestamp
 	})
@@ -170,12 +176,179 @@ func TestLinearGetInsert(t *testing.T) {
 	for i := range histories {
 		events[i] = histories[i].event
 	}
-	result, info := porcupine.CheckEventsVerbose(singleKeyModel, events, 0)
+	result, info := porcupine.CheckEventsVerbose(model, events, 0)
 	if !assert.True(t, porcupine.Ok == result, "history is not linearizable") || *printHistory {
-		tmpfile, err := ioutil.TempFile("", "lintest-single-key-*.html")
+		tmpfile, err := ioutil.TempFile("", "lintest-"+name+"-*.html")
 		defer tmpfile.Close()
 		require.NoError(t, err)
-		require.NoError(t, porcupine.Visualize(singleKeyModel, info, tmpfile))
+		require.NoError(t, porcupine.Visualize(model, info, tmpfile))
 		t.Logf("history is written to %v", tmpfile.Name())
 	}
 }
+
+// recorder collects history of the operations executed by concurrent clients.
+type recorder struct {
+	mu     sync.Mutex
+	id     int
+	events []timestampedEvent
+}
+
+// record executes the operation of the client and appends its call and return events.
+func (r *recorder) record(client int, input interface{}, op func() interface{}) {
+	r.mu.Lock()
+	id := r.id
+	r.id++
+	r.mu.Unlock()
+	call := timestampedEvent{
+		event:     porcupine.Event{ClientId: client, Id: id, Kind: porcupine.CallEvent, Value: input},
+		timestamp: time.Now().UnixNano(),
+	}
+	output := op()
+	ret := timestampedEvent{
+		event:     porcupine.Event{ClientId: client, Id: id, Kind: porcupine.ReturnEvent, Value: output},
+		timestamp: time.Now().UnixNano(),
+	}
+	r.mu.Lock()
+	r.events = append(r.events, call, ret)
+	r.mu.Unlock()
+}
+
+// snapshotKeys is a number of keys that are read and written together by the snapshot model.
+const snapshotKeys = 4
+
+// snapshotState is a value of every key, arrays are compared by porcupine.ShallowEqual.
+type snapshotState [snapshotKeys]int
+
+// snapshotInput either reads all keys, or writes the keys selected by the mask atomically.
+type snapshotInput struct {
+	op     int
+	values snapshotState
+	mask   [snapshotKeys]bool
+}
+
+// snapshotModel checks that snapshot reads are mutually consistent: every read observes
+// the state after some prefix of the atomic writes, never a part of the write.
+var snapshotModel = porcupine.Model{
+	Init: func() interface{} {
+		return snapshotState{}
+	},
+	Step: func(state, input, output interface{}) (bool, interface{}) {
+		inp := input.(snapshotInput)
+		current := state.(snapshotState)
+		switch inp.op {
+		case readOp:
+			return output.(snapshotState) == current, current
+		case writeOp:
+			for i, write := range inp.mask {
+				if write {
+					current[i] = inp.values[i]
+				}
+			}
+			return true, current
+		default:
+			panic(fmt.Sprintf("unknown op: %v", inp.op))
+		}
+	},
+	Equal: porcupine.ShallowEqual,
+	DescribeOperation: func(input, output interface{}) string {
+		inp := input.(snapshotInput)
+		switch inp.op {
+		case readOp:
+			return fmt.Sprintf("snapshot() -> %v", output)
+		case writeOp:
+			return fmt.Sprintf("commit(%v, %v)", inp.mask, inp.values)
+		default:
+			panic(fmt.Sprintf("unknown op: %v", inp.op))
+		}
+	},
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 10)
+		rand.Read(key)
+		tree.Insert(key, 0)
+	}
+	keys := make([][]byte, snapshotKeys)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("snapshot/%d", i))
+		tree.Insert(keys[i], 0)
+	}
+	var (
+		r       recorder
+		wg      sync.WaitGroup
+		opCount = 50
+	)
+	for client := 0; client < 2; client++ {
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(client)))
+			for i := 0; i < opCount; i++ {
+				var inp snapshotInput
+				inp.op = writeOp
+				for j := range keys {
+					inp.mask[j] = rng.Intn(2) == 0
+					inp.values[j] = client*opCount + i + 1
+				}
+				r.record(client, inp, func() interface{} {
+					txn := tree.Begin()
+					for j, key := range keys {
+						if inp.mask[j] {
+							txn.Insert(key, inp.values[j])
+						}
+					}
+					require.NoError(t, txn.Commit())
+					return nil
+				})
+				time.Sleep(time.Duration(rng.Int63n(100)) * time.Microsecond)
+			}
+		}(client)
+	}
+	readers := []func() snapshotState{
+		func() snapshotState {
+			var state snapshotState
+			for i, rst := range tree.GetAllAtSnapshot(keys, nil) {
+				state[i] = rst.Value.(int)
+			}
+			return state
+		},
+		func() snapshotState {
+			var state snapshotState
+			i := 0
+			for iter := tree.Prefix([]byte("snapshot/")).Consistent(); iter.Next(); i++ {
+				state[i] = iter.Value().(int)
+			}
+			return state
+		},
+	}
+	for client, read := range readers {
+		wg.Add(1)
+		go func(client int, read func() snapshotState) {
+			defer wg.Done()
+			for i := 0; i < opCount; i++ {
+				r.record(client, snapshotInput{op: readOp}, func() interface{} {
+					return read()
+				})
+				time.Sleep(time.Duration(rand.Int63n(100)) * time.Microsecond)
+			}
+		}(2+client, read)
+	}
+	wg.Wait()
+	checkHistory(t, snapshotModel, r.events, "snapshot")
+}
+
+func TestSnapshotModelTornRead(t *testing.T) {
+	write := snapshotInput{op: writeOp, values: snapshotState{1, 1, 1, 1}, mask: [snapshotKeys]bool{true, true, true, true}}
+	events := []porcupine.Event{
+		{ClientId: 0, Id: 0, Kind: porcupine.CallEvent, Value: write},
+		{ClientId: 1, Id: 1, Kind: porcupine.CallEvent, Value: snapshotInput{op: readOp}},
+		{ClientId: 1, Id: 1, Kind: porcupine.ReturnEvent, Value: snapshotState{1, 1, 0, 0}},
+		{ClientId: 0, Id: 0, Kind: porcupine.ReturnEvent, Value: nil},
+	}
+	require.False(t, porcupine.CheckEvents(snapshotModel, events))
+	events[2].Value = snapshotState{1, 1, 1, 1}
+	require.True(t, porcupine.CheckEvents(snapshotModel, events))
+}
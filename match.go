@@ -0,0 +1,138 @@
+package art
+
+import "bytes"
+
+// Prefix returns iterator over keys with the prefix.
+// Iteration starts from the node that holds the prefix, subtrees with unrelated keys
+// are not visited.
+func (t *Tree) Prefix(prefix []byte) *iterator {
+	return t.prefix(t.keyOf(prefix))
+}
+
+func (t *Tree) prefix(prefix []byte) *iterator {
+	iter := t.Iterator(nil, nil)
+	if prefix == nil {
+		prefix = []byte{}
+	}
+	iter.prefix = prefix
+	return iter
+}
+
+// CountPrefix returns number of keys with the prefix. Keys are counted by visiting
+// the subtree of the node that holds the prefix, the cost is proportional to the number
+// of matching keys. Count is not a snapshot if the tree is modified concurrently.
+func (t *Tree) CountPrefix(prefix []byte) int {
+	count := 0
+	for iter := t.Prefix(prefix); iter.Next(); {
+		count++
+	}
+	return count
+}
+
+// Match returns iterator over keys matching the pattern. Pattern is a literal prefix,
+// optionally followed by '*' and a literal suffix, e.g. "user:*:name".
+// Without '*' only the key equal to the pattern is matched.
+// Iteration starts from the node that holds the literal prefix and suffix is checked
+// for every visited key.
+func (t *Tree) Match(pattern []byte) *iterator {
+	star := bytes.IndexByte(pattern, '*')
+	if star < 0 {
+		pattern = t.keyOf(pattern)
+		iter := t.prefix(pattern)
+		iter.filter = func(key []byte) bool {
+			return len(key) == len(pattern)
+		}
+		return iter
+	}
+	prefix, suffix := t.keyOf(pattern[:star]), t.keyOf(pattern[star+1:])
+	iter := t.prefix(prefix)
+	if len(suffix) > 0 {
+		iter.filter = func(key []byte) bool {
+			return len(key) >= len(prefix)+len(suffix) && bytes.HasSuffix(key, suffix)
+		}
+	}
+	return iter
+}
+
+// PathMatches visits every stored key that is a prefix of the key, in order, until fn
+// returns false. Keys are not allowed to be prefixes of each other, therefore a key that
+// ends with the null terminator matches if the key without terminator is a prefix,
+// e.g. "/a\x00" and "/a/b\x00" both match "/a/b/c". Matches are collected in a single
+// descent along the path of the key, expired matches are removed and not visited.
+func (t *Tree) PathMatches(key []byte, fn func(key []byte, value ValueType) bool) {
+	t.checkPoisoned()
+	key = t.keyOf(key)
+	for _, l := range t.pathMatches(key) {
+		if t.expired(l) {
+			t.expire(l)
+			continue
+		}
+		if !fn(l.key, l.value) {
+			return
+		}
+	}
+}
+
+func (t *Tree) pathMatches(key []byte) []*leaf {
+	var (
+		buf     [16]observed
+		matches []*leaf
+	)
+restart:
+	matches = matches[:0]
+	version, _ := t.lock.RLock()
+	path := append(buf[:0], observed{lock: &t.lock, version: version})
+	next := t.root
+	depth := 0
+	for next != nil {
+		n, isInner := next.(*inner)
+		if !isInner {
+			if l := next.(*leaf); pathMatch(l.key, key) {
+				matches = append(matches, l)
+			}
+			break
+		}
+		version, obsolete := n.lock.RLock()
+		if obsolete {
+			goto restart
+		}
+		path = append(path, observed{lock: &n.lock, version: version})
+		if comparePrefix(n.prefix[:n.prefixLen], key, 0, depth) != n.prefixLen {
+			break
+		}
+		depth += n.prefixLen
+		if depth >= len(key) {
+			break
+		}
+		if key[depth] != 0 {
+			// terminated key that ends at this node
+			if _, child := n.node.child(0); child != nil && child.isLeaf() {
+				if l := child.(*leaf); pathMatch(l.key, key) {
+					matches = append(matches, l)
+				}
+			}
+		}
+		_, next = n.node.child(key[depth])
+		depth++
+	}
+	// every lock is released, pessimistic lock in race builds is held until RUnlock
+	stale := false
+	for i := range path {
+		if path[i].lock.RUnlock(path[i].version, nil) {
+			stale = true
+		}
+	}
+	if stale {
+		goto restart
+	}
+	return matches
+}
+
+// pathMatch returns true if stored key, without the null terminator, is a prefix of the key.
+func pathMatch(stored, key []byte) bool {
+	if bytes.HasPrefix(key, stored) {
+		return true
+	}
+	last := len(stored) - 1
+	return last >= 0 && stored[last] == 0 && bytes.HasPrefix(key, stored[:last])
+}
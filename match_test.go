@@ -0,0 +1,164 @@
+package art
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	var tree Tree
+	keys := []string{
+		"user:1:age",
+		"user:1:name",
+		"user:2:name",
+		"user:10:name",
+		"user:10:nick",
+		"item:1:name",
+		"u:name",
+	}
+	for _, key := range keys {
+		tree.Insert([]byte(key), key)
+	}
+	for i := 0; i < 300; i++ {
+		tree.Insert([]byte{'x', byte(i >> 8), byte(i)}, i)
+	}
+
+	for _, tc := range []struct {
+		pattern string
+		rst     []string
+	}{
+		{"user:*:name", []string{"user:10:name", "user:1:name", "user:2:name"}},
+		{"user:1*", []string{"user:10:name", "user:10:nick", "user:1:age", "user:1:name"}},
+		{"user:1:*", []string{"user:1:age", "user:1:name"}},
+		{"*:name", []string{"item:1:name", "u:name", "user:10:name", "user:1:name", "user:2:name"}},
+		{"u*name", []string{"u:name", "user:10:name", "user:1:name", "user:2:name"}},
+		{"user:1:name", []string{"user:1:name"}},
+		{"user:1:nam", []string{}},
+		{"user:3*", []string{}},
+		{"z*", []string{}},
+		{"item*", []string{"item:1:name"}},
+	} {
+		tc := tc
+		t.Run(tc.pattern, func(t *testing.T) {
+			rst := []string{}
+			iter := tree.Match([]byte(tc.pattern))
+			for iter.Next() {
+				rst = append(rst, string(iter.Key()))
+			}
+			require.Equal(t, tc.rst, rst)
+		})
+	}
+
+	rst := []string{}
+	iter := tree.Match([]byte("user:*:name")).Reverse()
+	for iter.Next() {
+		rst = append(rst, string(iter.Key()))
+	}
+	require.Equal(t, []string{"user:2:name", "user:1:name", "user:10:name"}, rst)
+
+	count := 0
+	iter = tree.Match([]byte{'x', 1, '*'})
+	for iter.Next() {
+		count++
+	}
+	require.Equal(t, 300-256, count)
+}
+
+func TestPrefix(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte(fmt.Sprintf("user:%d:%d\x00", i%10, i)), i)
+	}
+	expected := []string{}
+	for i := 3; i < 1000; i += 10 {
+		expected = append(expected, fmt.Sprintf("user:3:%d\x00", i))
+	}
+	sort.Strings(expected)
+
+	rst := []string{}
+	iter := tree.Prefix([]byte("user:3:"))
+	for iter.Next() {
+		rst = append(rst, string(iter.Key()))
+	}
+	require.Equal(t, expected, rst)
+
+	rst = rst[:0]
+	iter = tree.Prefix([]byte("user:3:")).Reverse()
+	for iter.Next() {
+		rst = append(rst, string(iter.Key()))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(expected)))
+	require.Equal(t, expected, rst)
+
+	for _, prefix := range []string{"user:3:5\x00", "user:30", "z", "user:3:99999"} {
+		iter = tree.Prefix([]byte(prefix))
+		require.False(t, iter.Next(), prefix)
+	}
+	iter = tree.Prefix([]byte("user:3:53\x00"))
+	require.True(t, iter.Next())
+	require.Equal(t, "user:3:53\x00", string(iter.Key()))
+	require.False(t, iter.Next())
+
+	count := 0
+	iter = tree.Prefix(nil)
+	for iter.Next() {
+		count++
+	}
+	require.Equal(t, 1000, count)
+
+	for prefix, expected := range map[string]int{
+		"":             1000,
+		"user:3:":      100,
+		"user:3:5":     11,
+		"user:3:53":    2,
+		"user:30":      0,
+		"user:3:5\x00": 0,
+	} {
+		require.Equal(t, expected, tree.CountPrefix([]byte(prefix)), prefix)
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	var tree Tree
+	for _, key := range []string{"/a", "/a/b", "/a/b/c", "/a/bc", "/b", "/a/b/c/d/e"} {
+		tree.Insert([]byte(key+"\x00"), key)
+	}
+	for _, tc := range []struct {
+		key      string
+		expected []string
+	}{
+		{"/a/b/c/d", []string{"/a", "/a/b", "/a/b/c"}},
+		{"/a/b/c\x00", []string{"/a", "/a/b", "/a/b/c"}},
+		{"/a/bc/d", []string{"/a", "/a/b", "/a/bc"}},
+		{"/a/b/c/d/e/f", []string{"/a", "/a/b", "/a/b/c", "/a/b/c/d/e"}},
+		{"/c", nil},
+		{"/", nil},
+	} {
+		var rst []string
+		tree.PathMatches([]byte(tc.key), func(key []byte, value ValueType) bool {
+			require.Equal(t, string(key), value.(string)+"\x00")
+			rst = append(rst, value.(string))
+			return true
+		})
+		require.Equal(t, tc.expected, rst, tc.key)
+	}
+
+	var rst []string
+	tree.PathMatches([]byte("/a/b/c"), func(key []byte, value ValueType) bool {
+		rst = append(rst, value.(string))
+		return len(rst) < 2
+	})
+	require.Equal(t, []string{"/a", "/a/b"}, rst)
+
+	var single Tree
+	single.Insert([]byte("/a\x00"), 1)
+	count := 0
+	single.PathMatches([]byte("/a/b"), func([]byte, ValueType) bool {
+		count++
+		return true
+	})
+	require.Equal(t, 1, count)
+}
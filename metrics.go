@@ -0,0 +1,79 @@
+package art
+
+import "expvar"
+
+// Metric is a counter reported to the MetricsSink.
+type Metric uint8
+
+const (
+	// MetricInserts counts keys stored by Insert and other modifications of the values.
+	MetricInserts Metric = iota
+	// MetricGets counts lookups of the keys.
+	MetricGets
+	// MetricDeletes counts removed keys, keys removed by Clear and DetachPrefix are not counted.
+	MetricDeletes
+	// MetricRestarts counts descents restarted from the root of the tree, because
+	// the version of the node on the path changed while it was read optimistically.
+	MetricRestarts
+	// MetricGrows counts inner nodes replaced by a larger kind.
+	MetricGrows
+	// MetricShrinks counts inner nodes replaced by a smaller kind.
+	MetricShrinks
+	// MetricIteratorRestarts counts iterators that resumed from the root, because all
+	// checkpoints of the iterator were invalidated by writers.
+	MetricIteratorRestarts
+	metricsCount
+)
+
+var metricNames = [metricsCount]string{
+	"inserts", "gets", "deletes", "restarts", "grows", "shrinks", "iterator_restarts",
+}
+
+func (m Metric) String() string {
+	if m >= metricsCount {
+		return "unknown"
+	}
+	return metricNames[m]
+}
+
+// MetricsSink receives counters of the tree operations. Add is called by every operation
+// of the tree, it must be safe for concurrent use and must not block. The sink may forward
+// counters to any metrics system, e.g. to the prometheus counters named by Metric.String.
+type MetricsSink interface {
+	Add(m Metric, delta int64)
+}
+
+// WithMetrics reports counters of the tree operations to the sink.
+// Sink can be replaced by Reconfigure, WithMetrics(nil) disables metrics.
+func WithMetrics(m MetricsSink) Option {
+	return func(t *Tree) {
+		t.metrics.Store(&metricsSink{sink: m})
+	}
+}
+
+// metricsSink wraps the interface, so that it can be replaced atomically.
+type metricsSink struct {
+	sink MetricsSink
+}
+
+// count reports the counter if metrics are enabled.
+func (t *Tree) count(m Metric, delta int64) {
+	if s := t.metrics.Load(); s != nil {
+		s.sink.Add(m, delta)
+	}
+}
+
+// ExpvarMetrics returns a sink that publishes counters in the expvar map with the name,
+// keys of the map are the names of the metrics. Same as expvar.NewMap it panics if the name
+// is already published.
+func ExpvarMetrics(name string) MetricsSink {
+	return expvarSink{m: expvar.NewMap(name)}
+}
+
+type expvarSink struct {
+	m *expvar.Map
+}
+
+func (s expvarSink) Add(m Metric, delta int64) {
+	s.m.Add(m.String(), delta)
+}
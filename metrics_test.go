@@ -0,0 +1,67 @@
+package art
+
+import (
+	"expvar"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingSink [metricsCount]int64
+
+func (s *countingSink) Add(m Metric, delta int64) {
+	atomic.AddInt64(&s[m], delta)
+}
+
+func TestMetrics(t *testing.T) {
+	var sink countingSink
+	tree := New(WithMetrics(&sink))
+	require.True(t, tree.Config().Metrics)
+	for i := 0; i < 256; i++ {
+		tree.Insert([]byte{byte(i), 0}, i)
+	}
+	// node4 -> node16 -> node48 -> node256
+	require.EqualValues(t, 3, sink[MetricGrows])
+	require.EqualValues(t, 256, sink[MetricInserts])
+
+	for i := 0; i < 10; i++ {
+		_, _ = tree.Get([]byte{byte(i), 0})
+	}
+	_, _ = tree.Get([]byte{1, 1})
+	require.EqualValues(t, 11, sink[MetricGets])
+
+	for i := 0; i < 250; i++ {
+		tree.Delete([]byte{byte(i), 0})
+	}
+	tree.Delete([]byte{1, 1})
+	require.EqualValues(t, 250, sink[MetricDeletes])
+	// node256 -> node48 -> node16, 6 keys remain
+	require.EqualValues(t, 2, sink[MetricShrinks])
+
+	require.Equal(t, 4, tree.DeleteRange(nil, []byte{0xfd, 0}))
+	require.EqualValues(t, 254, sink[MetricDeletes])
+	require.EqualValues(t, 3, sink[MetricShrinks])
+
+	var replaced countingSink
+	require.NoError(t, tree.Reconfigure(WithMetrics(&replaced)))
+	_, _ = tree.Get([]byte{1, 1})
+	require.EqualValues(t, 1, replaced[MetricGets])
+	require.EqualValues(t, 11, sink[MetricGets])
+
+	require.NoError(t, tree.Reconfigure(WithMetrics(nil)))
+	require.False(t, tree.Config().Metrics)
+	_, _ = tree.Get([]byte{1, 1})
+	require.EqualValues(t, 1, replaced[MetricGets])
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	tree := New(WithMetrics(ExpvarMetrics("art_test_metrics")))
+	tree.Insert([]byte("a"), 1)
+	_, _ = tree.Get([]byte("a"))
+	_, _ = tree.Get([]byte("b"))
+	published := expvar.Get("art_test_metrics").(*expvar.Map)
+	require.Equal(t, "1", published.Get("inserts").String())
+	require.Equal(t, "2", published.Get("gets").String())
+	require.Nil(t, published.Get("deletes"))
+}
@@ -0,0 +1,114 @@
+package art
+
+import "bytes"
+
+// Min returns the smallest key in the tree, together with its value.
+// Descent follows leftmost children, without visiting other keys.
+func (t *Tree) Min() ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.edge(nil, false)
+}
+
+// Max returns the largest key in the tree, together with its value.
+// Descent follows rightmost children, without visiting other keys.
+func (t *Tree) Max() ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.edge(nil, true)
+}
+
+// MinPrefix returns the smallest key with the prefix, together with its value.
+func (t *Tree) MinPrefix(prefix []byte) ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.edge(t.keyOf(prefix), false)
+}
+
+// MaxPrefix returns the largest key with the prefix, together with its value.
+func (t *Tree) MaxPrefix(prefix []byte) ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.edge(t.keyOf(prefix), true)
+}
+
+// edge returns the leftmost or rightmost key with the prefix. Expired leaves are removed,
+// and the search is repeated for the remaining keys.
+func (t *Tree) edge(prefix []byte, rightmost bool) ([]byte, ValueType, bool) {
+	for {
+		l := t.edgeLeaf(prefix, rightmost)
+		if l == nil {
+			return nil, nil, false
+		}
+		if !t.expired(l) {
+			return l.key, l.value, true
+		}
+		t.expire(l)
+	}
+}
+
+// edgeLeaf descends to the subtree where all keys have the prefix, and then follows
+// leftmost or rightmost child until leaf is found.
+func (t *Tree) edgeLeaf(prefix []byte, rightmost bool) *leaf {
+restart:
+	version, _ := t.lock.RLock()
+	parent := &t.lock
+	next := t.root
+	depth := 0
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			break
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		parent, version = &n.lock, nversion
+		// prefix may end in the middle of the node prefix
+		cmp := comparePrefix(n.prefix[:n.prefixLen], prefix, 0, depth)
+		if cmp != n.prefixLen && depth+cmp != len(prefix) {
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return nil
+		}
+		depth += n.prefixLen
+		if depth >= len(prefix) {
+			_, next = first(n, rightmost)
+			break
+		}
+		_, next = n.node.child(prefix[depth])
+		depth++
+	}
+	l, restart := extreme(parent, version, next, rightmost)
+	if restart {
+		goto restart
+	}
+	if l == nil || !bytes.HasPrefix(l.key, prefix) {
+		return nil
+	}
+	return l
+}
+
+// first returns leftmost or rightmost child of the node.
+func first(n *inner, rightmost bool) (byte, node) {
+	if rightmost {
+		return n.node.prev(nil)
+	}
+	return n.node.next(nil)
+}
+
+// extreme follows leftmost or rightmost children starting from the child of the node
+// locked for reading, until leaf is found. Returns true if descent needs to be restarted.
+func extreme(parent *olock, version uint64, next node, rightmost bool) (*leaf, bool) {
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l, _ := next.(*leaf)
+			return l, parent.RUnlock(version, nil)
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			return nil, true
+		}
+		parent, version = &n.lock, nversion
+		_, next = first(n, rightmost)
+	}
+}
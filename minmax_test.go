@@ -0,0 +1,82 @@
+package art
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinMaxPrefix(t *testing.T) {
+	var tree Tree
+	_, _, found := tree.MinPrefix(nil)
+	require.False(t, found)
+
+	keys := [][]byte{
+		[]byte("namespace/a/1\x00"),
+		[]byte("namespace/a/2\x00"),
+		[]byte("namespace/b/1\x00"),
+		[]byte("other\x00"),
+	}
+	for i := 0; i < 300; i++ {
+		keys = append(keys, []byte{'n', 'u', 'm', byte(i >> 8), byte(i), 0})
+	}
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+
+	for _, tc := range []struct {
+		prefix   string
+		min, max []byte
+	}{
+		{"", keys[0], keys[3]},
+		{"n", keys[0], keys[len(keys)-1]},
+		{"namespace/", keys[0], keys[2]},
+		{"namespace/a", keys[0], keys[1]},
+		{"namespace/a/2\x00", keys[1], keys[1]},
+		{"namespace/b", keys[2], keys[2]},
+		{"num", keys[4], keys[len(keys)-1]},
+		{"num\x00", keys[4], keys[4+255]},
+		{"names", keys[0], keys[2]},
+		{"namespace/c", nil, nil},
+		{"namespace/a/2\x00\x00", nil, nil},
+		{"z", nil, nil},
+	} {
+		tc := tc
+		t.Run(tc.prefix, func(t *testing.T) {
+			key, _, found := tree.MinPrefix([]byte(tc.prefix))
+			require.Equal(t, tc.min != nil, found)
+			require.Equal(t, tc.min, key)
+			key, _, found = tree.MaxPrefix([]byte(tc.prefix))
+			require.Equal(t, tc.max != nil, found)
+			require.Equal(t, tc.max, key)
+		})
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	var tree Tree
+	_, _, found := tree.Min()
+	require.False(t, found)
+	_, _, found = tree.Max()
+	require.False(t, found)
+
+	tree.Insert([]byte{0, 0}, -1)
+	key, _, found := tree.Max()
+	require.True(t, found)
+	require.Equal(t, []byte{0, 0}, key)
+
+	// grow root through all node kinds
+	for _, n := range []int{4, 16, 48, 256} {
+		for i := 0; i < n; i++ {
+			tree.Insert([]byte{byte(i), byte(n >> 2)}, i)
+		}
+		key, value, found := tree.Min()
+		require.True(t, found)
+		require.Equal(t, []byte{0, 0}, key)
+		require.Equal(t, -1, value)
+		key, value, found = tree.Max()
+		require.True(t, found)
+		require.Equal(t, []byte{byte(n - 1), byte(n >> 2)}, key)
+		require.Equal(t, n-1, value)
+	}
+}
@@ -0,0 +1,100 @@
+package art
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync"
+)
+
+// MisuseError is used as a panic value when misuse detection is enabled
+// and incorrect use of the API is detected.
+type MisuseError struct {
+	// Op is the operation that detected misuse.
+	Op string
+	// Key is involved in the misuse, if any. It may be already modified by the caller.
+	Key    []byte
+	Reason string
+}
+
+func (e *MisuseError) Error() string {
+	if e.Key == nil {
+		return fmt.Sprintf("%v: %s: %s", ErrMisuse, e.Op, e.Reason)
+	}
+	return fmt.Sprintf("%v: %s %x: %s", ErrMisuse, e.Op, e.Key, e.Reason)
+}
+
+func (e *MisuseError) Unwrap() error {
+	return ErrMisuse
+}
+
+// WithMisuseDetection enables detection of the API misuse, such as modification
+// of the keys that are owned by the tree, reuse of the inserted key buffers and
+// use of the closed iterators. Misuse is reported by panicking with MisuseError,
+// instead of corrupting the tree silently.
+// Checksum of every stored key is tracked, meant for tests and debugging.
+func WithMisuseDetection() Option {
+	return func(t *Tree) {
+		t.guard = &guard{keys: map[*byte]uint32{}}
+	}
+}
+
+// guard tracks checksums of the stored keys by the address of the first byte of the key.
+type guard struct {
+	mu   sync.Mutex
+	keys map[*byte]uint32
+}
+
+func checksum(key []byte) uint32 {
+	return crc32.ChecksumIEEE(key)
+}
+
+// inserting checks that buffer of the key isn't owned by the tree with different content.
+func (g *guard) inserting(op string, key []byte) {
+	if len(key) == 0 {
+		return
+	}
+	g.mu.Lock()
+	sum, exists := g.keys[&key[0]]
+	g.mu.Unlock()
+	if exists && sum != checksum(key) {
+		panic(&MisuseError{Op: op, Key: key, Reason: "buffer of the stored key was modified and reused, keys must not be modified after insert"})
+	}
+}
+
+// verify checks that the stored key wasn't modified.
+func (g *guard) verify(op string, key []byte) {
+	if len(key) == 0 {
+		return
+	}
+	g.mu.Lock()
+	sum, exists := g.keys[&key[0]]
+	g.mu.Unlock()
+	if exists && sum != checksum(key) {
+		panic(&MisuseError{Op: op, Key: key, Reason: "stored key was modified, keys returned by the tree must not be modified"})
+	}
+}
+
+func (g *guard) stored(l *leaf) {
+	if len(l.key) == 0 {
+		return
+	}
+	sum := checksum(l.key)
+	g.mu.Lock()
+	g.keys[&l.key[0]] = sum
+	g.mu.Unlock()
+}
+
+func (g *guard) forget(l *leaf) {
+	if l == nil || len(l.key) == 0 {
+		return
+	}
+	g.mu.Lock()
+	delete(g.keys, &l.key[0])
+	g.mu.Unlock()
+}
+
+func (g *guard) reset() {
+	g.mu.Lock()
+	g.keys = map[*byte]uint32{}
+	g.mu.Unlock()
+}
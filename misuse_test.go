@@ -0,0 +1,75 @@
+package art
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireMisuse(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		require.NotNil(t, r, "misuse wasn't detected")
+		err, ok := r.(*MisuseError)
+		require.True(t, ok, "unexpected panic %v", r)
+		require.True(t, errors.Is(err, ErrMisuse))
+	}()
+	fn()
+}
+
+func TestMisuseDetection(t *testing.T) {
+	t.Run("reused buffer", func(t *testing.T) {
+		tree := New(WithMisuseDetection())
+		key := []byte("key1")
+		tree.Insert(key, 1)
+		// replacing the value with the same buffer is allowed
+		tree.Insert(key, 2)
+		key[3] = '2'
+		requireMisuse(t, func() { tree.Insert(key, 3) })
+	})
+	t.Run("modified key", func(t *testing.T) {
+		tree := New(WithMisuseDetection())
+		tree.Insert([]byte("key1"), 1)
+		iter := tree.Iterator(nil, nil)
+		require.True(t, iter.Next())
+		iter.Key()[0] = 'x'
+		requireMisuse(t, func() { iter.Next() })
+		requireMisuse(t, func() { tree.Get([]byte("xey1")) })
+	})
+	t.Run("iterator after close", func(t *testing.T) {
+		tree := New(WithMisuseDetection())
+		tree.Insert([]byte("key1"), 1)
+		iter := tree.Iterator(nil, nil)
+		iter.Close()
+		requireMisuse(t, func() { iter.Next() })
+	})
+	t.Run("deleted buffer", func(t *testing.T) {
+		tree := New(WithMisuseDetection())
+		key := []byte("key1")
+		tree.Insert(key, 1)
+		tree.Delete(key)
+		key[3] = '2'
+		tree.Insert(key, 2)
+
+		other := []byte("key3")
+		tree.Insert(other, 3)
+		require.Equal(t, 2, tree.DeleteRange(nil, nil))
+		other[3] = '4'
+		tree.Insert(other, 4)
+		require.Equal(t, 1, tree.Len())
+	})
+}
+
+func TestIteratorClose(t *testing.T) {
+	var tree Tree
+	tree.Insert([]byte("key1"), 1)
+	tree.Insert([]byte("key2"), 2)
+	iter := tree.Iterator(nil, nil)
+	require.True(t, iter.Next())
+	iter.Close()
+	require.False(t, iter.Next())
+	iter.Seek([]byte("key1"))
+	require.False(t, iter.Next())
+}
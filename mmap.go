@@ -0,0 +1,468 @@
+package art
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+)
+
+// Flat layout of the tree served by MmapTree. All integers are little endian,
+// nodes reference children by absolute offsets in the file instead of pointers.
+// Children are written before the parent, root offset is stored in the footer.
+//
+//	file:  magic | node... | root offset u64 | number of keys u64
+//	leaf:  tagLeaf | key length u32 | value length u32 | key | value
+//	inner: tagInner | prefix length byte | prefix | number of children u16 | edges | offsets u64...
+//
+// Root offset is zero if the tree is empty.
+const (
+	mmapMagic      = "artmmap\x01"
+	mmapFooterSize = 16
+)
+
+// WriteMmap writes the tree into w in the layout that is served by OpenMmap.
+// Values are encoded by the codec configured with WithValueCodec, or must be []byte or nil.
+// Concurrent writers must be stopped, ErrConcurrentModification is returned if modification
+// was detected while the tree was written.
+func (t *Tree) WriteMmap(w io.Writer) error {
+	writes := atomic.LoadUint64(&t.writes)
+	fw := flatWriter{w: bufio.NewWriter(w), encode: t.encode}
+	fw.write([]byte(mmapMagic))
+	var root uint64
+	if t.root != nil {
+		root = fw.node(t.root)
+	}
+	var footer [mmapFooterSize]byte
+	binary.LittleEndian.PutUint64(footer[:], root)
+	binary.LittleEndian.PutUint64(footer[8:], uint64(atomic.LoadInt64(&t.size)))
+	fw.write(footer[:])
+	if fw.err != nil {
+		return fw.err
+	}
+	if err := t.unmodifiedSince(writes); err != nil {
+		return err
+	}
+	return fw.w.Flush()
+}
+
+type flatWriter struct {
+	w       *bufio.Writer
+	encode  func([]byte, ValueType) []byte
+	offset  uint64
+	scratch []byte
+	err     error
+}
+
+func (fw *flatWriter) write(data []byte) {
+	if fw.err != nil {
+		return
+	}
+	n, err := fw.w.Write(data)
+	fw.offset += uint64(n)
+	fw.err = err
+}
+
+// node writes the subtree of n and returns offset of n.
+func (fw *flatWriter) node(n node) uint64 {
+	switch n := n.(type) {
+	case *leaf:
+		var value []byte
+		if fw.encode != nil {
+			fw.scratch = fw.encode(fw.scratch[:0], n.value)
+			value = fw.scratch
+		} else if v, ok := n.value.([]byte); ok || n.value == nil {
+			value = v
+		} else if fw.err == nil {
+			fw.err = fmt.Errorf("%w: value of type %T can't be serialized without codec", ErrUnsupportedValue, n.value)
+		}
+		offset := fw.offset
+		var header [9]byte
+		header[0] = tagLeaf
+		binary.LittleEndian.PutUint32(header[1:], uint32(len(n.key)))
+		binary.LittleEndian.PutUint32(header[5:], uint32(len(value)))
+		fw.write(header[:])
+		fw.write(n.key)
+		fw.write(value)
+		return offset
+	case *inner:
+		var (
+			edges   []byte
+			offsets []uint64
+			pointer *byte
+		)
+		for {
+			b, child := n.node.next(pointer)
+			if child == nil {
+				break
+			}
+			edges = append(edges, b)
+			offsets = append(offsets, fw.node(child))
+			pointer = &b
+		}
+		offset := fw.offset
+		buf := []byte{tagInner, byte(n.prefixLen)}
+		buf = append(buf, n.prefix[:n.prefixLen]...)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(edges)))
+		buf = append(buf, edges...)
+		for _, o := range offsets {
+			buf = binary.LittleEndian.AppendUint64(buf, o)
+		}
+		fw.write(buf)
+		return offset
+	}
+	return 0
+}
+
+// MmapTree is a read-only tree served from the file written by WriteMmap.
+// Keys and values returned by MmapTree point into the mapped file, they must not be
+// modified and are valid only until Close. Accessing them after Close faults, copy
+// the ones that must outlive the tree.
+// Layout of the file is validated by OpenMmap, so that corrupted offsets are reported
+// as ErrCorrupt instead of a panic in Get or Ascend.
+type MmapTree struct {
+	data  []byte
+	root  uint64
+	count int
+	// unmap releases data, nil if data is on the heap.
+	unmap func() error
+}
+
+// OpenMmap maps the file written by WriteMmap into memory.
+func OpenMmap(path string) (*MmapTree, error) {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newMmapTree(data)
+	if err != nil {
+		if unmap != nil {
+			_ = unmap()
+		}
+		return nil, err
+	}
+	t.unmap = unmap
+	return t, nil
+}
+
+func newMmapTree(data []byte) (*MmapTree, error) {
+	if len(data) < len(mmapMagic)+mmapFooterSize || string(data[:len(mmapMagic)]) != mmapMagic {
+		return nil, fmt.Errorf("%w: invalid header", ErrCorrupt)
+	}
+	footer := data[len(data)-mmapFooterSize:]
+	t := &MmapTree{
+		data:  data,
+		root:  binary.LittleEndian.Uint64(footer),
+		count: int(binary.LittleEndian.Uint64(footer[8:])),
+	}
+	if t.root == 0 {
+		if t.count != 0 {
+			return nil, fmt.Errorf("%w: %d keys in the empty tree", ErrCorrupt, t.count)
+		}
+		return t, nil
+	}
+	end := uint64(len(data) - mmapFooterSize)
+	if t.root < uint64(len(mmapMagic)) || t.root >= end {
+		return nil, fmt.Errorf("%w: root offset %d out of bounds", ErrCorrupt, t.root)
+	}
+	leaves, err := t.validate(t.root, end, t.count)
+	if err != nil {
+		return nil, err
+	}
+	if leaves != t.count {
+		return nil, fmt.Errorf("%w: footer has %d keys, found %d", ErrCorrupt, t.count, leaves)
+	}
+	return t, nil
+}
+
+// validate checks that the node at offset and its subtree fit into data before end,
+// and returns number of leaves in the subtree. Children are written before the parent,
+// offsets of the children must be lower than offset of the parent. Validation fails
+// if subtree has more than limit leaves.
+func (t *MmapTree) validate(offset, end uint64, limit int) (int, error) {
+	corrupt := func(what string) (int, error) {
+		return 0, fmt.Errorf("%w: %s at offset %d", ErrCorrupt, what, offset)
+	}
+	switch t.data[offset] {
+	case tagLeaf:
+		if end-offset < 9 {
+			return corrupt("truncated leaf header")
+		}
+		klth := uint64(binary.LittleEndian.Uint32(t.data[offset+1:]))
+		vlth := uint64(binary.LittleEndian.Uint32(t.data[offset+5:]))
+		if end-offset-9 < klth+vlth {
+			return corrupt("truncated leaf")
+		}
+		return 1, nil
+	case tagInner:
+		if end-offset < 4 {
+			return corrupt("truncated inner header")
+		}
+		plth := uint64(t.data[offset+1])
+		if plth > uint64(maxPrefixLen) || end-offset < 4+plth {
+			return corrupt("invalid prefix")
+		}
+		count := uint64(binary.LittleEndian.Uint16(t.data[offset+2+plth:]))
+		if count == 0 || end-offset-4-plth < count*9 {
+			return corrupt("truncated children")
+		}
+		_, edges, offsets := t.inner(offset)
+		total := 0
+		for i := range edges {
+			if i > 0 && edges[i-1] >= edges[i] {
+				return corrupt("unsorted edges")
+			}
+			child := t.child(offsets, i)
+			if child < uint64(len(mmapMagic)) || child >= offset {
+				return corrupt("child offset out of bounds")
+			}
+			leaves, err := t.validate(child, offset, limit-total)
+			if err != nil {
+				return 0, err
+			}
+			total += leaves
+			if total > limit {
+				return corrupt("too many keys")
+			}
+		}
+		return total, nil
+	}
+	return corrupt("unknown tag")
+}
+
+// Close unmaps the file.
+func (t *MmapTree) Close() error {
+	if t.unmap == nil {
+		return nil
+	}
+	unmap := t.unmap
+	t.unmap = nil
+	t.data = nil
+	return unmap()
+}
+
+// Len returns number of keys stored in the tree.
+func (t *MmapTree) Len() int {
+	return t.count
+}
+
+func (t *MmapTree) isLeaf(offset uint64) bool {
+	return t.data[offset] == tagLeaf
+}
+
+func (t *MmapTree) leaf(offset uint64) ([]byte, []byte) {
+	klth := uint64(binary.LittleEndian.Uint32(t.data[offset+1:]))
+	vlth := uint64(binary.LittleEndian.Uint32(t.data[offset+5:]))
+	start := offset + 9
+	return t.data[start : start+klth : start+klth], t.data[start+klth : start+klth+vlth : start+klth+vlth]
+}
+
+// inner returns prefix, edges and the offset of the first child offset.
+func (t *MmapTree) inner(offset uint64) ([]byte, []byte, uint64) {
+	plth := uint64(t.data[offset+1])
+	prefix := t.data[offset+2 : offset+2+plth]
+	offset += 2 + plth
+	count := uint64(binary.LittleEndian.Uint16(t.data[offset:]))
+	offset += 2
+	return prefix, t.data[offset : offset+count], offset + count
+}
+
+func (t *MmapTree) child(offsets uint64, i int) uint64 {
+	return binary.LittleEndian.Uint64(t.data[offsets+8*uint64(i):])
+}
+
+// Get returns value stored for the key. Value points into the mapped file.
+func (t *MmapTree) Get(key []byte) ([]byte, bool) {
+	if t.root == 0 {
+		return nil, false
+	}
+	offset, depth := t.root, 0
+	for !t.isLeaf(offset) {
+		prefix, edges, offsets := t.inner(offset)
+		if comparePrefix(prefix, key, 0, depth) != len(prefix) {
+			return nil, false
+		}
+		depth += len(prefix)
+		if depth >= len(key) {
+			return nil, false
+		}
+		i := sort.Search(len(edges), func(i int) bool {
+			return edges[i] >= key[depth]
+		})
+		if i == len(edges) || edges[i] != key[depth] {
+			return nil, false
+		}
+		offset = t.child(offsets, i)
+		depth++
+	}
+	stored, value := t.leaf(offset)
+	if !bytes.Equal(stored, key) {
+		return nil, false
+	}
+	return value, true
+}
+
+// Ascend calls fn for every key that is >= start in ascending order, until fn returns false.
+// nil start visits all keys. Key and value point into the mapped file.
+func (t *MmapTree) Ascend(start []byte, fn func(key, value []byte) bool) {
+	if t.root == 0 {
+		return
+	}
+	t.ascend(t.root, 0, start, len(start) > 0, fn)
+}
+
+// ascend returns false if fn requested to stop. bounded is true if the path to the node
+// is a prefix of start.
+func (t *MmapTree) ascend(offset uint64, depth int, start []byte, bounded bool, fn func(key, value []byte) bool) bool {
+	if t.isLeaf(offset) {
+		key, value := t.leaf(offset)
+		if bounded && bytes.Compare(key, start) < 0 {
+			return true
+		}
+		return fn(key, value)
+	}
+	prefix, edges, offsets := t.inner(offset)
+	first := 0
+	if bounded {
+		cmp := comparePrefix(prefix, start, 0, depth)
+		if cmp != len(prefix) || depth+len(prefix) >= len(start) {
+			if depth+cmp < len(start) && prefix[cmp] < start[depth+cmp] {
+				// subtree precedes start
+				return true
+			}
+			bounded = false
+		} else {
+			b := start[depth+len(prefix)]
+			first = sort.Search(len(edges), func(i int) bool {
+				return edges[i] >= b
+			})
+		}
+	}
+	for i := first; i < len(edges); i++ {
+		if !t.ascend(t.child(offsets, i), depth+len(prefix)+1, start, bounded && edges[i] == start[depth+len(prefix)], fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Iterator returns iterator over keys in range (start, end], nil start or end means that
+// range is unbounded on that side. Iterator reads the mapped layout directly, nodes are
+// not decoded into the tree. Key and value point into the mapped file.
+func (t *MmapTree) Iterator(start, end []byte) *mmapIterator {
+	return &mmapIterator{tree: t, start: start, end: end}
+}
+
+// mmapIterator visits leaves of the mapped layout in ascending order.
+type mmapIterator struct {
+	tree       *MmapTree
+	start, end []byte
+	started    bool
+	stack      []mmapFrame
+	key, value []byte
+}
+
+// mmapFrame is an inner node on the path of the iterator.
+type mmapFrame struct {
+	edges   []byte
+	offsets uint64
+	// next is an index of the next child to visit.
+	next int
+	// depth is an offset in the key of the edges.
+	depth int
+	// bounded is true if the path to the node is a prefix of start.
+	bounded bool
+}
+
+// Next moves the iterator to the next key, returns false if there are no more keys in range.
+func (i *mmapIterator) Next() bool {
+	if !i.started {
+		i.started = true
+		if i.tree.root == 0 {
+			return false
+		}
+		if i.tree.isLeaf(i.tree.root) {
+			visited, _ := i.visit(i.tree.root)
+			return visited
+		}
+		i.enter(i.tree.root, 0, len(i.start) > 0)
+	}
+	for len(i.stack) > 0 {
+		f := &i.stack[len(i.stack)-1]
+		if f.next == len(f.edges) {
+			i.stack = i.stack[:len(i.stack)-1]
+			continue
+		}
+		idx := f.next
+		f.next++
+		offset := i.tree.child(f.offsets, idx)
+		if !i.tree.isLeaf(offset) {
+			i.enter(offset, f.depth+1, f.bounded && f.edges[idx] == i.start[f.depth])
+			continue
+		}
+		visited, done := i.visit(offset)
+		if done {
+			i.stack = i.stack[:0]
+		}
+		if visited || done {
+			return visited
+		}
+	}
+	return false
+}
+
+// visit makes the leaf at offset current if its key is in range.
+// done is true if the key is after the end of the range.
+func (i *mmapIterator) visit(offset uint64) (visited, done bool) {
+	key, value := i.tree.leaf(offset)
+	if len(i.end) > 0 && bytes.Compare(key, i.end) > 0 {
+		return false, true
+	}
+	if len(i.start) > 0 && bytes.Compare(key, i.start) <= 0 {
+		return false, false
+	}
+	i.key, i.value = key, value
+	return true, false
+}
+
+// enter pushes inner node at offset, that starts at depth, on the stack.
+// Children that precede start are skipped if node is bounded, same as by Ascend.
+func (i *mmapIterator) enter(offset uint64, depth int, bounded bool) {
+	prefix, edges, offsets := i.tree.inner(offset)
+	first := 0
+	if bounded {
+		cmp := comparePrefix(prefix, i.start, 0, depth)
+		if cmp != len(prefix) || depth+len(prefix) >= len(i.start) {
+			if depth+cmp < len(i.start) && prefix[cmp] < i.start[depth+cmp] {
+				// subtree precedes start
+				return
+			}
+			bounded = false
+		} else {
+			b := i.start[depth+len(prefix)]
+			first = sort.Search(len(edges), func(j int) bool {
+				return edges[j] >= b
+			})
+		}
+	}
+	i.stack = append(i.stack, mmapFrame{
+		edges:   edges,
+		offsets: offsets,
+		next:    first,
+		depth:   depth + len(prefix),
+		bounded: bounded,
+	})
+}
+
+// Key returns the current key, it points into the mapped file.
+func (i *mmapIterator) Key() []byte {
+	return i.key
+}
+
+// Value returns the current value, it points into the mapped file.
+func (i *mmapIterator) Value() []byte {
+	return i.value
+}
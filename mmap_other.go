@@ -0,0 +1,12 @@
+//go:build !unix
+// +build !unix
+
+package art
+
+import "os"
+
+// mmapFile reads the whole file on platforms without mmap support.
+func mmapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	return data, nil, err
+}
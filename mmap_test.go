@@ -0,0 +1,194 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeMmap(t *testing.T, tree *Tree) string {
+	path := filepath.Join(t.TempDir(), "tree")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, tree.WriteMmap(f))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestMmapTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var (
+		tree Tree
+		keys []string
+	)
+	seen := map[string]bool{}
+	for len(keys) < 5000 {
+		key := make([]byte, 1+rng.Intn(12))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 60)
+		}
+		key = append(key, 1)
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		tree.Insert(key, append([]byte("value:"), key...))
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	mt, err := OpenMmap(writeMmap(t, &tree))
+	require.NoError(t, err)
+	defer mt.Close()
+	require.Equal(t, len(keys), mt.Len())
+
+	for _, key := range keys {
+		value, found := mt.Get([]byte(key))
+		require.True(t, found)
+		require.Equal(t, append([]byte("value:"), key...), value)
+		_, found = mt.Get([]byte(key)[:len(key)-1])
+		require.False(t, found)
+	}
+	for i := 0; i < 100; i++ {
+		var start []byte
+		if i > 0 {
+			start = make([]byte, 1+rng.Intn(8))
+			for j := range start {
+				start[j] = byte(rng.Intn(4) * 60)
+			}
+		}
+		idx := sort.SearchStrings(keys, string(start))
+		limit := rng.Intn(50)
+		rst := []string{}
+		mt.Ascend(start, func(key, value []byte) bool {
+			require.True(t, bytes.HasSuffix(value, key))
+			rst = append(rst, string(key))
+			return len(rst) < limit
+		})
+		expected := keys[idx:]
+		if len(expected) > limit {
+			expected = expected[:limit]
+		}
+		if limit == 0 && idx < len(keys) {
+			expected = keys[idx : idx+1]
+		}
+		require.Equal(t, expected, rst, "start %v", start)
+	}
+}
+
+func TestMmapTreeIterator(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tree Tree
+	for i := 0; i < 5000; i++ {
+		key := make([]byte, 1+rng.Intn(12))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 60)
+		}
+		key = append(key, 1)
+		tree.Insert(key, append([]byte("value:"), key...))
+	}
+	mt, err := OpenMmap(writeMmap(t, &tree))
+	require.NoError(t, err)
+	defer mt.Close()
+
+	random := func() []byte {
+		if rng.Intn(4) == 0 {
+			return nil
+		}
+		key := make([]byte, 1+rng.Intn(8))
+		for j := range key {
+			key[j] = byte(rng.Intn(4) * 60)
+		}
+		return key
+	}
+	for i := 0; i < 100; i++ {
+		start, end := random(), random()
+		expected := []string{}
+		iter := tree.Iterator(start, end)
+		for iter.Next() {
+			expected = append(expected, string(iter.Key()))
+		}
+		rst := []string{}
+		miter := mt.Iterator(start, end)
+		for miter.Next() {
+			require.Equal(t, append([]byte("value:"), miter.Key()...), miter.Value())
+			rst = append(rst, string(miter.Key()))
+		}
+		require.False(t, miter.Next())
+		require.Equal(t, expected, rst, "start %v end %v", start, end)
+	}
+}
+
+func TestMmapTreeEmpty(t *testing.T) {
+	var tree Tree
+	mt, err := OpenMmap(writeMmap(t, &tree))
+	require.NoError(t, err)
+	_, found := mt.Get([]byte{1})
+	require.False(t, found)
+	mt.Ascend(nil, func(_, _ []byte) bool {
+		require.Fail(t, "tree is empty")
+		return true
+	})
+	require.False(t, mt.Iterator(nil, nil).Next())
+	require.NoError(t, mt.Close())
+
+	tree.Insert([]byte{1}, []byte{2})
+	mt, err = OpenMmap(writeMmap(t, &tree))
+	require.NoError(t, err)
+	value, found := mt.Get([]byte{1})
+	require.True(t, found)
+	require.Equal(t, []byte{2}, value)
+	iter := mt.Iterator(nil, nil)
+	require.True(t, iter.Next())
+	require.Equal(t, []byte{1}, iter.Key())
+	require.False(t, iter.Next())
+	require.False(t, mt.Iterator([]byte{1}, nil).Next())
+	require.NoError(t, mt.Close())
+}
+
+func TestMmapTreeCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	require.NoError(t, os.WriteFile(path, []byte("not a tree"), 0o600))
+	_, err := OpenMmap(path)
+	require.True(t, errors.Is(err, ErrCorrupt), err)
+}
+
+func TestMmapTreeCorruptLayout(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 20; i++ {
+		tree.Insert([]byte{byte(i % 3), byte(i), 0}, []byte{byte(i)})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, tree.WriteMmap(&buf))
+	valid := buf.Bytes()
+	use := func(data []byte) {
+		mt, err := newMmapTree(data)
+		if err != nil {
+			require.True(t, errors.Is(err, ErrCorrupt), err)
+			return
+		}
+		for i := 0; i < 20; i++ {
+			_, _ = mt.Get([]byte{byte(i % 3), byte(i), 0})
+		}
+		mt.Ascend(nil, func(_, _ []byte) bool { return true })
+		mt.Ascend([]byte{1, 5}, func(_, _ []byte) bool { return true })
+	}
+	for lth := 0; lth < len(valid); lth++ {
+		// truncated file keeps the footer
+		data := append(append([]byte(nil), valid[:lth]...), valid[len(valid)-mmapFooterSize:]...)
+		use(data)
+	}
+	for i := len(mmapMagic); i < len(valid); i++ {
+		for _, b := range []byte{0, 1, 2, 0x7f, 0xff} {
+			data := append([]byte(nil), valid...)
+			data[i] = b
+			use(data)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package art
+
+import "bytes"
+
+// Next returns the smallest key that is larger than the key, together with its value.
+// Key doesn't have to be present in the tree.
+func (t *Tree) Next(key []byte) ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.neighbor(t.keyOf(key), false)
+}
+
+// Prev returns the largest key that is smaller than the key, together with its value.
+// Key doesn't have to be present in the tree.
+func (t *Tree) Prev(key []byte) ([]byte, ValueType, bool) {
+	t.checkPoisoned()
+	return t.neighbor(t.keyOf(key), true)
+}
+
+// neighbor returns the first key after the key in the direction of the search.
+// Expired leaves are removed, and the search continues from their keys.
+func (t *Tree) neighbor(key []byte, backward bool) ([]byte, ValueType, bool) {
+	for {
+		l := t.neighborLeaf(key, backward)
+		if l == nil {
+			return nil, nil, false
+		}
+		if !t.expired(l) {
+			return l.key, l.value, true
+		}
+		t.expire(l)
+		key = l.key
+	}
+}
+
+// neighborLeaf descends along the path of the key and remembers the deepest node that
+// has a child following the key in the direction of the search. If the path of the key
+// doesn't have a neighbor, descent resumes from that child, after the versions
+// of the nodes on the path were validated.
+func (t *Tree) neighborLeaf(key []byte, backward bool) *leaf {
+	var (
+		path        []step
+		sibling     int
+		siblingByte byte
+	)
+restart:
+	path = path[:0]
+	sibling = -1
+	rootVersion, _ := t.lock.RLock()
+	parent, version := &t.lock, rootVersion
+	next := t.root
+	depth := 0
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l, _ := next.(*leaf)
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			if l != nil && follows(l.key, key, backward) {
+				return l
+			}
+			break
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		path = append(path, step{node: n, version: nversion, depth: depth})
+		parent, version = &n.lock, nversion
+
+		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
+		var greater bool
+		if cmp != n.prefixLen {
+			// either all keys in the subtree follow the key, or none of them
+			greater = depth+cmp >= len(key) || n.prefix[cmp] > key[depth+cmp]
+		} else if depth+n.prefixLen >= len(key) {
+			// all keys in the subtree have the key as a prefix
+			greater = true
+		} else {
+			depth += n.prefixLen
+			b := key[depth]
+			if _, child := sideways(n, &b, backward); child != nil {
+				sibling, siblingByte = len(path)-1, b
+			}
+			_, next = n.node.child(b)
+			depth++
+			continue
+		}
+		if greater != backward {
+			_, child := first(n, backward)
+			l, restart := extreme(parent, version, child, backward)
+			if restart {
+				goto restart
+			}
+			return l
+		}
+		if parent.RUnlock(version, nil) {
+			goto restart
+		}
+		break
+	}
+	if sibling < 0 {
+		return nil
+	}
+	s := path[sibling]
+	version, obsolete := s.node.lock.RLock()
+	if obsolete || version != s.version || t.lock.Check(rootVersion) {
+		_ = s.node.lock.RUnlock(version, nil)
+		goto restart
+	}
+	for _, prev := range path[:sibling] {
+		if prev.node.lock.Check(prev.version) {
+			_ = s.node.lock.RUnlock(version, nil)
+			goto restart
+		}
+	}
+	_, child := sideways(s.node, &siblingByte, backward)
+	l, restart := extreme(&s.node.lock, version, child, backward)
+	if restart {
+		goto restart
+	}
+	return l
+}
+
+// follows is true if key is after the other key in the direction of the search.
+func follows(key, other []byte, backward bool) bool {
+	if backward {
+		return bytes.Compare(key, other) < 0
+	}
+	return bytes.Compare(key, other) > 0
+}
+
+// sideways returns child of the node next to the byte in the direction of the search.
+func sideways(n *inner, b *byte, backward bool) (byte, node) {
+	if backward {
+		return n.node.prev(b)
+	}
+	return n.node.next(b)
+}
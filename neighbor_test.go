@@ -0,0 +1,103 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextPrev(t *testing.T) {
+	var tree Tree
+	_, _, found := tree.Next(nil)
+	require.False(t, found)
+
+	rng := rand.New(rand.NewSource(1))
+	keys := [][]byte{}
+	for i := 0; i < 2000; i++ {
+		// null terminated keys of variable length, none of them is a prefix of another
+		key := make([]byte, 1+rng.Intn(12))
+		for j := range key {
+			key[j] = byte(1 + rng.Intn(8))
+		}
+		key = append(key, 0)
+		tree.Insert(key, string(key))
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	uniq := keys[:1]
+	for _, key := range keys[1:] {
+		if !bytes.Equal(key, uniq[len(uniq)-1]) {
+			uniq = append(uniq, key)
+		}
+	}
+	keys = uniq
+
+	probes := [][]byte{nil, {0}, {9}, {1}, {8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}}
+	for i := 0; i < 2000; i++ {
+		probe := make([]byte, rng.Intn(14))
+		for j := range probe {
+			probe[j] = byte(rng.Intn(10))
+		}
+		probes = append(probes, probe)
+	}
+	probes = append(probes, keys...)
+
+	for _, probe := range probes {
+		i := sort.Search(len(keys), func(i int) bool {
+			return bytes.Compare(keys[i], probe) > 0
+		})
+		key, value, found := tree.Next(probe)
+		if i == len(keys) {
+			require.False(t, found, "next %v", probe)
+		} else {
+			require.True(t, found, "next %v", probe)
+			require.Equal(t, keys[i], key, "next %v", probe)
+			require.Equal(t, string(key), value)
+		}
+
+		i = sort.Search(len(keys), func(i int) bool {
+			return bytes.Compare(keys[i], probe) >= 0
+		})
+		key, _, found = tree.Prev(probe)
+		if i == 0 {
+			require.False(t, found, "prev %v", probe)
+		} else {
+			require.True(t, found, "prev %v", probe)
+			require.Equal(t, keys[i-1], key, "prev %v", probe)
+		}
+	}
+}
+
+func TestNextPrevLongPrefix(t *testing.T) {
+	var tree Tree
+	prefix := bytes.Repeat([]byte{7}, 20)
+	first := append(append([]byte{}, prefix...), 1, 0)
+	second := append(append([]byte{}, prefix...), 2, 0)
+	tree.Insert(first, 1)
+	tree.Insert(second, 2)
+
+	key, _, found := tree.Next(prefix[:5])
+	require.True(t, found)
+	require.Equal(t, first, key)
+	key, _, found = tree.Next(first)
+	require.True(t, found)
+	require.Equal(t, second, key)
+	_, _, found = tree.Next(second)
+	require.False(t, found)
+	_, _, found = tree.Next(append(append([]byte{}, prefix[:15]...), 8))
+	require.False(t, found)
+
+	key, _, found = tree.Prev(append(append([]byte{}, prefix[:15]...), 8))
+	require.True(t, found)
+	require.Equal(t, second, key)
+	key, _, found = tree.Prev(second)
+	require.True(t, found)
+	require.Equal(t, first, key)
+	_, _, found = tree.Prev(prefix)
+	require.False(t, found)
+}
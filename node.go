@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"sync/atomic"
 )
 
 const (
@@ -33,23 +34,32 @@ func comparePrefix(k1, k2 []byte, off1, off2 int) int {
 type walkFn func(node, int) bool
 
 type node interface {
-	insert(*leaf, int, *olock, uint64) (node, bool)
-	del([]byte, int, *olock, uint64, func(node)) bool
-	get([]byte, int, *olock, uint64) (ValueType, bool, bool)
+	del([]byte, *leaf, int, *olock, uint64, func(node), *Tree) (*leaf, bool)
+	get([]byte, int, *olock, uint64) (*leaf, bool)
 	walk(walkFn, int) bool
-	inherit([maxPrefixLen]byte, int) node
+	inherit([maxPrefixLen]byte, int, Allocator) node
 	isLeaf() bool
 	String() string
 }
 
 type inner struct {
-	lock olock
+	// canary is empty unless built with artdebug tag.
+	canary canary
+	lock   olock
 
 	prefix    [maxPrefixLen]byte
 	prefixLen int
 	node      inode
 }
 
+// unlock releases write lock, validating the node in debug builds.
+func (n *inner) unlock() {
+	if debug {
+		n.validate()
+	}
+	n.lock.Unlock()
+}
+
 func (n *inner) isLeaf() bool {
 	return false
 }
@@ -61,18 +71,18 @@ func (n *inner) walk(fn walkFn, depth int) bool {
 	return n.node.walk(fn, depth+n.prefixLen+1)
 }
 
-func (n *inner) get(key []byte, depth int, parent *olock, parentVersion uint64) (ValueType, bool, bool) {
+func (n *inner) get(key []byte, depth int, parent *olock, parentVersion uint64) (*leaf, bool) {
 	for {
 		version, obsolete := n.lock.RLock()
 		if obsolete || parent.RUnlock(parentVersion, nil) {
-			return nil, false, true
+			return nil, true
 		}
 		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
 		if cmp != n.prefixLen {
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return nil, false, false
+			return nil, false
 		}
 
 		nextDepth := depth + n.prefixLen
@@ -82,32 +92,32 @@ func (n *inner) get(key []byte, depth int, parent *olock, parentVersion uint64)
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return nil, false, false
+			return nil, false
 		}
 		if next.isLeaf() {
-			value, found, _ := next.get(key, nextDepth+1, &n.lock, version)
+			l, _ := next.get(key, nextDepth+1, &n.lock, version)
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return value, found, false
+			return l, false
 		}
-		value, found, restart := next.get(key, nextDepth+1, &n.lock, version)
+		l, restart := next.get(key, nextDepth+1, &n.lock, version)
 		if restart {
 			continue
 		}
-		return value, found, false
+		return l, false
 	}
-	panic("unreachable")
 }
 
-// insert ...
-func (n *inner) insert(l *leaf, depth int, parent *olock, parentVersion uint64) (node, bool) {
+// upsert applies modification to the key in the subtree of the node.
+// Modification is applied when locks that are required to update the tree are acquired.
+func (n *inner) upsert(op *upsert, depth int, parent *olock, parentVersion uint64) (node, bool) {
 	for {
 		version, obsolete := n.lock.RLock()
 		if obsolete {
 			return n, true
 		}
-		cmp := comparePrefix(n.prefix[:n.prefixLen], l.key, 0, depth)
+		cmp := comparePrefix(n.prefix[:n.prefixLen], op.key, 0, depth)
 		if cmp != n.prefixLen {
 			// parent lock is required
 			// because parent may collapse and child will have
@@ -119,24 +129,30 @@ func (n *inner) insert(l *leaf, depth int, parent *olock, parentVersion uint64)
 			if n.lock.Upgrade(version, parent) {
 				return nil, true
 			}
-
-			child := &inner{
-				prefixLen: n.prefixLen - cmp - 1,
-				node:      n.node,
+			l := op.apply(nil, parent, &n.lock)
+			if l == nil {
+				n.unlock()
+				parent.Unlock()
+				return n, false
 			}
+
+			a := op.tree.allocator()
+			child := a.inner()
+			child.prefixLen = n.prefixLen - cmp - 1
+			child.node = n.node
 			copy(child.prefix[:], n.prefix[cmp+1:])
-			n.node = &node4{}
+			n.node = a.node4()
 			n.node.addChild(l.key[depth+cmp], l)
 			n.node.addChild(n.prefix[cmp], child)
 			n.prefixLen = cmp
 
-			n.lock.Unlock()
+			n.unlock()
 			parent.Unlock()
 			return n, false
 		}
 
 		nextDepth := depth + n.prefixLen
-		idx, next := n.node.child(l.key[nextDepth])
+		idx, next := n.node.child(op.key[nextDepth])
 
 		if next == nil {
 			if n.lock.Upgrade(version, nil) {
@@ -145,11 +161,17 @@ func (n *inner) insert(l *leaf, depth int, parent *olock, parentVersion uint64)
 			if parent.RUnlock(parentVersion, &n.lock) {
 				return n, true
 			}
+			l := op.apply(nil, &n.lock)
+			if l == nil {
+				n.unlock()
+				return n, false
+			}
 			if n.node.full() {
-				n.node = n.node.grow()
+				n.node = n.node.grow(op.tree.allocator())
+				op.tree.count(MetricGrows, 1)
 			}
 			n.node.addChild(l.key[nextDepth], l)
-			n.lock.Unlock()
+			n.unlock()
 			return n, false
 		}
 		if parent.RUnlock(parentVersion, nil) {
@@ -160,30 +182,32 @@ func (n *inner) insert(l *leaf, depth int, parent *olock, parentVersion uint64)
 				continue
 			}
 
-			replacement, _ := next.insert(l, nextDepth+1, &n.lock, version)
+			replacement, _ := next.(*leaf).upsert(op, nextDepth+1, &n.lock, version)
 			n.node.replace(idx, replacement)
-			n.lock.Unlock()
+			n.unlock()
 			return n, false
 		}
 
-		_, restart := next.insert(l, nextDepth+1, &n.lock, version)
+		// upsert is not a part of the node interface, calls through interface
+		// would force allocation of the upsert on the heap
+		_, restart := next.(*inner).upsert(op, nextDepth+1, &n.lock, version)
 		if restart {
 			continue
 		}
 		return n, false
 	}
-	panic("unreachable")
 }
 
 // del deletes the node with key and returns pointer for the parent for update.
 // pointer may change if path is comressed:
 // - either completely, pointer to the leaf will be returned
 // - partially, e.g. prefixLen will be increased and prefixes merged
-func (n *inner) del(key []byte, depth int, parent *olock, parentVersion uint64, replace func(node)) bool {
+// If expected is not nil the key is deleted only if it is stored by the expected leaf.
+func (n *inner) del(key []byte, expected *leaf, depth int, parent *olock, parentVersion uint64, replace func(node), t *Tree) (*leaf, bool) {
 	for {
 		version, obsolete := n.lock.RLock()
 		if obsolete {
-			return true
+			return nil, true
 		}
 
 		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
@@ -192,7 +216,7 @@ func (n *inner) del(key []byte, depth int, parent *olock, parentVersion uint64,
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return parent.RUnlock(parentVersion, nil)
+			return nil, parent.RUnlock(parentVersion, nil)
 		}
 
 		nextDepth := depth + n.prefixLen
@@ -202,20 +226,20 @@ func (n *inner) del(key []byte, depth int, parent *olock, parentVersion uint64,
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return parent.RUnlock(parentVersion, nil)
+			return nil, parent.RUnlock(parentVersion, nil)
 		}
 
-		if l, isLeaf := next.(*leaf); isLeaf && l.cmp(key) {
+		if l, isLeaf := next.(*leaf); isLeaf && l.cmp(key) && (expected == nil || l == expected) {
 			_, isNode4 := n.node.(*node4)
 			min := n.node.min()
 			if isNode4 && min && n.prefixLen < maxPrefixLen {
 				// update parent pointer. current node will be collapsed.
 				if parent.Upgrade(parentVersion, nil) {
-					return true
+					return nil, true
 				}
 				if n.lock.Upgrade(version, parent) {
 					// need to update parent version
-					return true
+					return nil, true
 				}
 
 				n.node.replace(idx, nil)
@@ -224,48 +248,49 @@ func (n *inner) del(key []byte, depth int, parent *olock, parentVersion uint64,
 				n.prefix[n.prefixLen] = leftb
 				n.prefixLen++
 
-				replace(left.inherit(n.prefix, n.prefixLen))
+				replace(left.inherit(n.prefix, n.prefixLen, t.allocator()))
 
-				n.lock.Unlock()
+				n.unlock()
 				parent.Unlock()
-				return false
+				return l, false
 			}
 			// local change. parent lock won't be required
 			if n.lock.Upgrade(version, nil) {
 				continue
 			}
 			if parent.RUnlock(parentVersion, &n.lock) {
-				return true
+				return nil, true
 			}
 			n.node.replace(idx, nil)
 			if min && !isNode4 {
-				n.node = n.node.shrink()
+				n.node = n.node.shrink(t.allocator())
+				t.count(MetricShrinks, 1)
 			}
-			n.lock.Unlock()
-			return false
+			n.unlock()
+			return l, false
 		} else if isLeaf {
 			// key is not found. check for concurrent writes and exit
 			if n.lock.RUnlock(version, nil) {
 				continue
 			}
-			return parent.RUnlock(parentVersion, nil)
+			return nil, parent.RUnlock(parentVersion, nil)
 		}
 
 		if parent.RUnlock(parentVersion, nil) {
-			return true
+			return nil, true
 		}
 
-		if next.del(key, nextDepth+1, &n.lock, version, func(rn node) {
+		removed, restart := next.del(key, expected, nextDepth+1, &n.lock, version, func(rn node) {
 			n.node.replace(idx, rn)
-		}) {
+		}, t)
+		if restart {
 			continue
 		}
-		return false
+		return removed, false
 	}
-	panic("unreachable")
 }
 
-func (n *inner) inherit(prefix [maxPrefixLen]byte, prefixLen int) node {
+func (n *inner) inherit(prefix [maxPrefixLen]byte, prefixLen int, a Allocator) node {
 	// two cases for inheritance of the prefix
 	// 1. new prefixLen is <= max prefix len
 	total := n.prefixLen + prefixLen
@@ -280,9 +305,8 @@ func (n *inner) inherit(prefix [maxPrefixLen]byte, prefixLen int) node {
 	// second - leftover
 	// pointer should use 9th byte
 	// see long keys test
-	nn := &inner{
-		node: &node4{},
-	}
+	nn := a.inner()
+	nn.node = a.node4()
 	nn.prefix = prefix
 	nn.prefixLen = maxPrefixLen
 	copy(nn.prefix[prefixLen:], n.prefix[:])
@@ -301,6 +325,13 @@ func (n *inner) String() string {
 type leaf struct {
 	key   []byte
 	value ValueType
+
+	// access is a coarse timestamp of the last access, maintained only if eviction is enabled.
+	access int64
+	// version is assigned when leaf is stored, unique within the tree.
+	version uint64
+	// expires is a deadline in unix nanoseconds set by InsertTTL, zero if the key doesn't expire.
+	expires int64
 }
 
 func (l *leaf) isLeaf() bool {
@@ -311,11 +342,11 @@ func (l *leaf) walk(fn walkFn, depth int) bool {
 	return fn(l, depth)
 }
 
-func (l *leaf) get(key []byte, depth int, parent *olock, parentVersion uint64) (ValueType, bool, bool) {
+func (l *leaf) get(key []byte, depth int, parent *olock, parentVersion uint64) (*leaf, bool) {
 	if l.cmp(key) {
-		return l.value, true, false
+		return l, false
 	}
-	return nil, false, false
+	return nil, false
 }
 
 func (l *leaf) cmp(other []byte) bool {
@@ -324,7 +355,7 @@ func (l *leaf) cmp(other []byte) bool {
 
 // insert updates leaf if key matches previous leaf or performs expansion if needed.
 // expansion creates node4 and adds two leafs as childs
-func (l *leaf) insert(other *leaf, depth int, parent *olock, parentVersion uint64) (node, bool) {
+func (l *leaf) insert(other *leaf, depth int, parent *olock, parentVersion uint64, a Allocator) (node, bool) {
 	if other.cmp(l.key) {
 		return other, false
 	}
@@ -335,10 +366,9 @@ func (l *leaf) insert(other *leaf, depth int, parent *olock, parentVersion uint6
 	)
 	for {
 		cmp := comparePrefix(l.key, other.key, depth, depth)
-		nn := &inner{
-			prefixLen: cmp,
-			node:      &node4{},
-		}
+		nn := a.inner()
+		nn.prefixLen = cmp
+		nn.node = a.node4()
 
 		copy(nn.prefix[:], l.key[depth:depth+cmp])
 
@@ -350,7 +380,8 @@ func (l *leaf) insert(other *leaf, depth int, parent *olock, parentVersion uint6
 			prev.node.addChild(l.key[depth-1], nn)
 		}
 
-		if cmp < maxPrefixLen {
+		// prefix is limited by maxPrefixLen, keys may diverge right after it
+		if cmp < maxPrefixLen || l.key[depth+cmp] != other.key[depth+cmp] {
 			nn.node.addChild(l.key[depth+cmp], l)
 			nn.node.addChild(other.key[depth+cmp], other)
 			break
@@ -361,11 +392,27 @@ func (l *leaf) insert(other *leaf, depth int, parent *olock, parentVersion uint6
 	return head, false
 }
 
-func (l *leaf) del([]byte, int, *olock, uint64, func(node)) bool {
+// upsert applies modification to the position occupied by the leaf.
+// Parent must be locked for writing.
+func (l *leaf) upsert(op *upsert, depth int, parent *olock, parentVersion uint64) (node, bool) {
+	if l.cmp(op.key) {
+		if stored := op.apply(l, parent); stored != nil {
+			return stored, false
+		}
+		return l, false
+	}
+	other := op.apply(nil, parent)
+	if other == nil {
+		return l, false
+	}
+	return l.insert(other, depth, parent, parentVersion, op.tree.allocator())
+}
+
+func (l *leaf) del([]byte, *leaf, int, *olock, uint64, func(node), *Tree) (*leaf, bool) {
 	panic("not needed")
 }
 
-func (l *leaf) inherit([maxPrefixLen]byte, int) node {
+func (l *leaf) inherit([maxPrefixLen]byte, int, Allocator) node {
 	return l
 }
 
@@ -373,6 +420,56 @@ func (l *leaf) String() string {
 	return fmt.Sprintf("leaf[%x]", l.key)
 }
 
+// upsert describes modification of a single key.
+type upsert struct {
+	key []byte
+	// leaf is stored unconditionally if resolve is nil.
+	leaf *leaf
+	// resolve is executed when locks required for modification are acquired.
+	// It receives leaf that is currently stored for the key, or nil if there is none
+	// or it expired, and returns leaf that should be stored instead.
+	// If nil is returned tree is not modified.
+	resolve func(old *leaf) *leaf
+	// replaceOnly is true if key must not be added if it doesn't exist or expired.
+	replaceOnly bool
+	// tree is poisoned if resolve panics.
+	tree *Tree
+	// hint is a path of the previous descent, optional. Modification is applied
+	// starting from the deepest valid node on the path.
+	hint *finger
+
+	// old is a leaf that was stored for the key before modification.
+	old *leaf
+	// live is the old leaf, or nil if it expired.
+	live *leaf
+	// stored is a leaf stored by the modification, nil if tree wasn't modified.
+	stored *leaf
+}
+
+// apply resolves the leaf that should be stored. Locks are held for writing by the caller,
+// and released if resolve panics.
+func (op *upsert) apply(old *leaf, locks ...*olock) *leaf {
+	op.old, op.live = old, old
+	if old != nil && op.tree.expired(old) {
+		op.live = nil
+	}
+	if op.live == nil && op.replaceOnly {
+		op.stored = nil
+	} else if op.resolve == nil {
+		op.stored = op.leaf
+	} else {
+		op.tree.callback(func() {
+			op.stored = op.resolve(op.live)
+		}, locks...)
+	}
+	if op.stored != nil && op.stored != old {
+		// version is assigned under the lock of the node that stores the key, so that
+		// it increases in the order in which the leaves of the key are replaced
+		op.stored.version = atomic.AddUint64(&op.tree.writes, 1)
+	}
+	return op.stored
+}
+
 // inode is one of the inner nodes concrete representation
 // node4/node16/node48/node256
 type inode interface {
@@ -393,13 +490,13 @@ type inode interface {
 	full() bool
 	// grow the node to next size
 	// node256 can't grow and will return nil
-	grow() inode
+	grow(Allocator) inode
 
 	// min is true if node reached min size
 	min() bool
 	// shrink is the opposite to grow
 	// if node is of the smallest type (node4) nil will be returned
-	shrink() inode
+	shrink(Allocator) inode
 
 	// walk is internal helper to iterate in depth first order over all nodes, including inner nodes
 	walk(walkFn, int) bool
@@ -487,7 +584,7 @@ func (n *node4) min() bool {
 	return n.lth <= 2
 }
 
-func (n *node4) shrink() inode {
+func (n *node4) shrink(Allocator) inode {
 	panic("can't shrink node4")
 }
 
@@ -495,8 +592,8 @@ func (n *node4) full() bool {
 	return n.lth == 4
 }
 
-func (n *node4) grow() inode {
-	nn := &node16{}
+func (n *node4) grow(a Allocator) inode {
+	nn := a.node16()
 	nn.lth = n.lth
 	copy(nn.keys[:], n.keys[:])
 	copy(nn.childs[:], n.childs[:])
@@ -525,18 +622,11 @@ type node16 struct {
 }
 
 func (n *node16) index(k byte) int {
-	// binary search is slower then loop 23ns > 16ns per op in worst case of scanning whole array
-	// no reason to use binary search for non-vectorized version
-	for i, b := range n.keys {
-		if k <= b {
-			return i
-		}
-	}
-	return int(n.lth)
+	return position(&k, &n.keys, n.lth)
 }
 
 func (n *node16) child(k byte) (int, node) {
-	idx, exist := index(&k, &n.keys)
+	idx, exist := index(&k, &n.keys, n.lth)
 	if !exist {
 		return 0, nil
 	}
@@ -547,26 +637,26 @@ func (n *node16) next(k *byte) (byte, node) {
 	if k == nil {
 		return n.keys[0], n.childs[0]
 	}
-	for i, b := range n.keys {
-		if b > *k {
-			return b, n.childs[i]
-		}
+	idx := after(k, &n.keys, n.lth)
+	if idx >= int(n.lth) {
+		return 0, nil
 	}
-	return 0, nil
+	return n.keys[idx], n.childs[idx]
 }
 
 func (n *node16) prev(k *byte) (byte, node) {
+	if n.lth == 0 {
+		return 0, nil
+	}
 	if k == nil {
 		idx := n.lth - 1
 		return n.keys[idx], n.childs[idx]
 	}
-	for i := n.lth; i >= 0; i-- {
-		idx := i - 1
-		if n.keys[idx] < *k {
-			return n.keys[idx], n.childs[idx]
-		}
+	idx := position(k, &n.keys, n.lth)
+	if idx == 0 {
+		return 0, nil
 	}
-	return 0, nil
+	return n.keys[idx-1], n.childs[idx-1]
 }
 
 func (n *node16) replace(idx int, child node) {
@@ -594,16 +684,16 @@ func (n *node16) addChild(k byte, child node) {
 	n.lth++
 }
 
-func (n *node16) grow() inode {
-	nn := &node48{
-		lth: n.lth,
-	}
+func (n *node16) grow(a Allocator) inode {
+	nn := a.node48()
+	nn.lth = n.lth
 	copy(nn.childs[:], n.childs[:])
 	for i, child := range n.childs {
 		if child == nil {
 			continue
 		}
 		nn.keys[n.keys[i]] = uint16(i) + 1
+		nn.present.set(n.keys[i])
 	}
 	return nn
 }
@@ -612,12 +702,12 @@ func (n *node16) min() bool {
 	return n.lth <= 5
 }
 
-func (n *node16) shrink() inode {
-	nn := node4{}
+func (n *node16) shrink(a Allocator) inode {
+	nn := a.node4()
 	copy(nn.keys[:], n.keys[:])
 	copy(nn.childs[:], n.childs[:])
 	nn.lth = n.lth
-	return &nn
+	return nn
 }
 
 func (n *node16) walk(fn walkFn, depth int) bool {
@@ -636,9 +726,10 @@ func (n *node16) String() string {
 }
 
 type node48 struct {
-	lth    uint8
-	keys   [256]uint16
-	childs [48]node
+	lth     uint8
+	present bitmap
+	keys    [256]uint16
+	childs  [48]node
 }
 
 func (n *node48) child(k byte) (int, node) {
@@ -650,22 +741,36 @@ func (n *node48) child(k byte) (int, node) {
 }
 
 func (n *node48) next(k *byte) (byte, node) {
-	for b, idx := range n.keys {
-		if (k == nil || byte(b) > *k) && idx != 0 {
-			return byte(b), n.childs[idx-1]
+	// skipped is declared outside of the loop, otherwise it is moved to the heap
+	var skipped byte
+	for {
+		b, found := n.present.next(k)
+		if !found {
+			return 0, nil
+		}
+		// bitmap may be observed ahead of the index by an optimistic reader
+		if idx := n.keys[b]; idx != 0 {
+			return b, n.childs[idx-1]
 		}
+		skipped = b
+		k = &skipped
 	}
-	return 0, nil
 }
 
 func (n *node48) prev(k *byte) (byte, node) {
-	for b := n.lth - 1; b >= 0; b-- {
-		idx := n.keys[b]
-		if (k == nil || byte(b) < *k) && idx != 0 {
-			return byte(b), n.childs[idx]
+	// skipped is declared outside of the loop, otherwise it is moved to the heap
+	var skipped byte
+	for {
+		b, found := n.present.prev(k)
+		if !found {
+			return 0, nil
+		}
+		if idx := n.keys[b]; idx != 0 {
+			return b, n.childs[idx-1]
 		}
+		skipped = b
+		k = &skipped
 	}
-	return 0, nil
 }
 
 func (n *node48) full() bool {
@@ -676,6 +781,7 @@ func (n *node48) addChild(k byte, child node) {
 	for idx, existing := range n.childs {
 		if existing == nil {
 			n.keys[k] = uint16(idx + 1)
+			n.present.set(k)
 			n.childs[idx] = child
 			n.lth++
 			return
@@ -684,16 +790,16 @@ func (n *node48) addChild(k byte, child node) {
 	panic("no empty slots")
 }
 
-func (n *node48) grow() inode {
-	nn := &node256{
-		lth: uint16(n.lth),
-	}
+func (n *node48) grow(a Allocator) inode {
+	nn := a.node256()
+	nn.lth = uint16(n.lth)
 	for b, i := range n.keys {
 		if i == 0 {
 			continue
 		}
-		nn.childs[b] = n.childs[i-1]
+		nn.store(byte(b), n.childs[i-1])
 	}
+	nn.present = n.present
 	return nn
 }
 
@@ -705,6 +811,7 @@ func (n *node48) replace(k int, child node) {
 	n.childs[idx-1] = child
 	if child == nil {
 		n.keys[k] = 0
+		n.present.clear(byte(k))
 		n.lth--
 	}
 }
@@ -713,10 +820,9 @@ func (n *node48) min() bool {
 	return n.lth <= 17
 }
 
-func (n *node48) shrink() inode {
-	nn := &node16{
-		lth: n.lth,
-	}
+func (n *node48) shrink(a Allocator) inode {
+	nn := a.node16()
+	nn.lth = n.lth
 	nni := 0
 	for i, index := range n.keys {
 		if index == 0 {
@@ -760,38 +866,85 @@ func (n *node48) String() string {
 	return b.String()
 }
 
+// node256 children are stored as atomic pointers, so that reader always observes
+// a complete child, even if node is concurrently modified. Interface values are two
+// words wide and can't be stored atomically, therefore leaves and inner nodes are
+// stored in separate arrays of typed pointers, at most one of them is set for the key.
 type node256 struct {
-	lth    uint16
-	childs [256]node
+	lth     uint16
+	present bitmap
+	leaves  [256]atomic.Pointer[leaf]
+	inners  [256]atomic.Pointer[inner]
+}
+
+func (n *node256) load(k byte) node {
+	if in := n.inners[k].Load(); in != nil {
+		return in
+	}
+	if l := n.leaves[k].Load(); l != nil {
+		return l
+	}
+	return nil
+}
+
+// store sets the new child before clearing the other array. Optimistic reader may still
+// observe the old child, or an empty slot if inner node was stored between the loads,
+// in both cases it is restarted by the version of the node.
+func (n *node256) store(k byte, child node) {
+	switch child := child.(type) {
+	case *leaf:
+		n.leaves[k].Store(child)
+		n.inners[k].Store(nil)
+	case *inner:
+		n.inners[k].Store(child)
+		n.leaves[k].Store(nil)
+	default:
+		n.inners[k].Store(nil)
+		n.leaves[k].Store(nil)
+	}
 }
 
 func (n *node256) child(k byte) (int, node) {
-	return int(k), n.childs[k]
+	return int(k), n.load(k)
 }
 
 func (n *node256) next(k *byte) (byte, node) {
-	for b, child := range n.childs {
-		if (k == nil || byte(b) > *k) && child != nil {
-			return byte(b), child
+	// skipped is declared outside of the loop, otherwise it is moved to the heap
+	var skipped byte
+	for {
+		b, found := n.present.next(k)
+		if !found {
+			return 0, nil
+		}
+		// bitmap may be observed ahead of the slot by an optimistic reader
+		if child := n.load(b); child != nil {
+			return b, child
 		}
+		skipped = b
+		k = &skipped
 	}
-	return 0, nil
 }
 
 func (n *node256) prev(k *byte) (byte, node) {
-	for idx := n.lth - 1; idx >= 0; idx-- {
-		b := byte(idx)
-		child := n.childs[idx]
-		if (k == nil || b < *k) && child != nil {
+	// skipped is declared outside of the loop, otherwise it is moved to the heap
+	var skipped byte
+	for {
+		b, found := n.present.prev(k)
+		if !found {
+			return 0, nil
+		}
+		if child := n.load(b); child != nil {
 			return b, child
 		}
+		skipped = b
+		k = &skipped
 	}
-	return 0, nil
 }
 
 func (n *node256) replace(idx int, child node) {
-	n.childs[byte(idx)] = child
+	n.store(byte(idx), child)
 	if child == nil {
+		n.present.clear(byte(idx))
 		n.lth--
 	}
 }
@@ -801,11 +954,12 @@ func (n *node256) full() bool {
 }
 
 func (n *node256) addChild(k byte, child node) {
-	n.childs[k] = child
+	n.store(k, child)
+	n.present.set(k)
 	n.lth++
 }
 
-func (n *node256) grow() inode {
+func (n *node256) grow(Allocator) inode {
 	return nil
 }
 
@@ -813,24 +967,26 @@ func (n *node256) min() bool {
 	return n.lth <= 49
 }
 
-func (n *node256) shrink() inode {
-	nn := &node48{
-		lth: uint8(n.lth),
-	}
+func (n *node256) shrink(a Allocator) inode {
+	nn := a.node48()
+	nn.lth = uint8(n.lth)
 	var index uint16
-	for i := range n.childs {
-		if n.childs[i] == nil {
+	for i := range n.leaves {
+		child := n.load(byte(i))
+		if child == nil {
 			continue
 		}
 		index++
 		nn.keys[i] = index
-		nn.childs[index-1] = n.childs[i]
+		nn.present.set(byte(i))
+		nn.childs[index-1] = child
 	}
 	return nn
 }
 
 func (n *node256) walk(fn walkFn, depth int) bool {
-	for _, child := range n.childs {
+	for i := range n.leaves {
+		child := n.load(byte(i))
 		if child != nil {
 			if !child.walk(fn, depth) {
 				return false
@@ -844,8 +1000,8 @@ func (n *node256) String() string {
 	var b bytes.Buffer
 	_, _ = b.WriteString("n256[")
 	encoder := hex.NewEncoder(&b)
-	for i := range n.childs {
-		if n.childs[i] != nil {
+	for i := range n.leaves {
+		if n.load(byte(i)) != nil {
 			_, _ = encoder.Write([]byte{byte(i)})
 		}
 	}
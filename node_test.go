@@ -1,6 +1,10 @@
 package art
 
 import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -108,7 +112,7 @@ func TestNodeChilds(t *testing.T) {
 				}
 			}
 			testChilds(n)
-			if gn := n.grow(); gn != nil {
+			if gn := n.grow(heap); gn != nil {
 				n = gn
 				testChilds(n)
 				expand(n)
@@ -126,7 +130,7 @@ func TestNodeChilds(t *testing.T) {
 				}
 			}
 			reduce(n)
-			n = n.shrink()
+			n = n.shrink(heap)
 			if n != nil {
 				testChilds(n)
 			}
@@ -141,10 +145,165 @@ func TestMaxPrefixRecursive(t *testing.T) {
 
 	l1 := &leaf{key: a[:]}
 	l2 := &leaf{key: b[:]}
-	root, _ := l1.insert(l2, 0, nil, 0)
+	root, _ := l1.insert(l2, 0, nil, 0, heap)
 
 	// test that multiple levels were created
 	root.walk(func(n node, depth int) bool {
 		return true
 	}, 0)
 }
+
+func TestNode256ConcurrentSlot(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		n     node256
+		l     = &leaf{key: []byte{1}}
+		in    = &inner{}
+		done  = make(chan struct{})
+		count = 100_000
+	)
+	n.addChild(1, l)
+	go func() {
+		defer close(done)
+		for i := 0; i < count; i++ {
+			if i%2 == 0 {
+				n.replace(1, in)
+			} else {
+				n.replace(1, l)
+			}
+		}
+	}()
+	for i := 0; i < count; i++ {
+		_, child := n.child(1)
+		switch c := child.(type) {
+		case *leaf:
+			require.Equal(t, l, c)
+		case *inner:
+			require.Equal(t, in, c)
+		}
+	}
+	<-done
+}
+
+func TestNodePrev(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		node inode
+	}{
+		{"node4", &node4{}},
+		{"node16", &node16{}},
+		{"node48", &node48{}},
+		{"node256", &node256{}},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			n := tc.node
+			childs := map[byte]node{}
+			for k := 5; k < 255 && !n.full(); k += 5 {
+				child := &inner{}
+				childs[byte(k)] = child
+				n.addChild(byte(k), child)
+			}
+			last, child := n.prev(nil)
+			require.Equal(t, childs[last], child)
+			for b, expected := range childs {
+				if b > last {
+					t.Fatalf("prev(nil) returned %d, but %d is larger", last, b)
+				}
+				k := b + 1
+				prevb, prev := n.prev(&k)
+				require.Equal(t, b, prevb)
+				require.Equal(t, expected, prev)
+			}
+			first := byte(5)
+			_, child = n.prev(&first)
+			require.Nil(t, child)
+		})
+	}
+}
+
+func TestNode16Search(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for r := 0; r < 1000; r++ {
+		var keys [16]byte
+		lth := rng.Intn(17)
+		perm := rng.Perm(256)[:lth]
+		sort.Ints(perm)
+		for i, k := range perm {
+			keys[i] = byte(k)
+		}
+		for k := 0; k < 256; k++ {
+			key := byte(k)
+			expected := sort.Search(lth, func(i int) bool { return keys[i] >= key })
+			require.Equal(t, expected, position(&key, &keys, uint8(lth)), "key %d in %v", k, keys[:lth])
+			mask := lthMask(uint8(lth))
+			require.Equal(t, bits.OnesCount16(lowerUnrolled(&key, &keys)&mask), expected)
+			require.Equal(t, bits.OnesCount16(lower(&key, &keys)&mask), expected)
+
+			idx, found := index(&key, &keys, uint8(lth))
+			require.Equal(t, expected < lth && keys[expected] == key, found)
+			if found {
+				require.Equal(t, expected, idx)
+			}
+			require.Equal(t, equal(&key, &keys), equalUnrolled(&key, &keys))
+
+			if expected < lth && keys[expected] == key {
+				expected++
+			}
+			require.Equal(t, expected, after(&key, &keys, uint8(lth)), "key %d in %v", k, keys[:lth])
+		}
+	}
+}
+
+func BenchmarkNode16Search(b *testing.B) {
+	var keys [16]byte
+	for i := range keys {
+		keys[i] = byte(i * 10)
+	}
+	scalar := func(key *byte, nkey *[16]byte, lth uint8) int {
+		for i, b := range nkey[:lth] {
+			if *key <= b {
+				return i
+			}
+		}
+		return int(lth)
+	}
+	for _, lth := range []uint8{4, 6, 8, 16} {
+		// key in the middle of the node
+		key := keys[lth/2] - 1
+		mask := lthMask(lth)
+		b.Run(fmt.Sprintf("scalar/%d", lth), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = scalar(&key, &keys, lth)
+			}
+		})
+		b.Run(fmt.Sprintf("unrolled/%d", lth), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = bits.OnesCount16(lowerUnrolled(&key, &keys) & mask)
+			}
+		})
+		b.Run(fmt.Sprintf("lower/%d", lth), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = bits.OnesCount16(lower(&key, &keys) & mask)
+			}
+		})
+	}
+}
+
+func BenchmarkNode256Replace(b *testing.B) {
+	var (
+		n  node256
+		l  = &leaf{key: []byte{1}}
+		in = &inner{}
+	)
+	n.addChild(1, l)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			n.replace(1, in)
+		} else {
+			n.replace(1, l)
+		}
+	}
+}
@@ -1,4 +1,5 @@
-// +build !race
+//go:build !race && !artunsafe
+// +build !race,!artunsafe
 
 package art
 
@@ -8,6 +9,9 @@ import (
 	"sync/atomic"
 )
 
+// optimistic is true if readers validate versions instead of holding the lock.
+const optimistic = true
+
 // olock is a implemention of an Optimistic Lock.
 // As descibed in https://15721.courses.cs.cmu.edu/spring2017/papers/08-oltpindexes2/leis-damon2016.pd// Appendix A: Implementation of Optimistic Locks
 //
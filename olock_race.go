@@ -1,9 +1,13 @@
+//go:build race
 // +build race
 
 package art
 
 import "sync"
 
+// optimistic is false, readers hold the lock until RUnlock.
+const optimistic = false
+
 // olock implements pessimistic locking, golang race detector won't be able
 // to recognize correctness of the optimistic locking and will report races
 // if tests are executed with --race flag
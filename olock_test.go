@@ -1,4 +1,5 @@
-// +build !race
+//go:build !race && !artunsafe
+// +build !race,!artunsafe
 
 package art
 
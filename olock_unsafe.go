@@ -0,0 +1,63 @@
+//go:build artunsafe && !race
+// +build artunsafe,!race
+
+package art
+
+// optimistic is true, versions are still checked to invalidate cached paths.
+const optimistic = true
+
+// olock is used when the tree is accessed by a single goroutine, enabled with
+// the artunsafe build tag. Version is updated with plain loads and stores and
+// readers never wait, it is not safe to use the tree concurrently.
+type olock struct {
+	version uint64
+}
+
+func (ol *olock) RLock() (uint64, bool) {
+	return ol.version, isObsolete(ol.version)
+}
+
+func (ol *olock) RUnlock(version uint64, locked *olock) bool {
+	if ol.version != version {
+		if locked != nil {
+			locked.Unlock()
+		}
+		return true
+	}
+	return false
+}
+
+func (ol *olock) Upgrade(version uint64, locked *olock) bool {
+	if ol.version != version {
+		if locked != nil {
+			locked.Unlock()
+		}
+		return true
+	}
+	ol.version = setLockedBit(version)
+	return false
+}
+
+func (ol *olock) Check(version uint64) bool {
+	return ol.version != version
+}
+
+func (ol *olock) Lock() {
+	ol.version = setLockedBit(ol.version)
+}
+
+func (ol *olock) Unlock() {
+	ol.version += 2
+}
+
+func (ol *olock) UnlockObsolete() {
+	ol.version += 3
+}
+
+func isObsolete(version uint64) bool {
+	return (version & 1) == 1
+}
+
+func setLockedBit(version uint64) uint64 {
+	return version + 2
+}
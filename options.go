@@ -0,0 +1,26 @@
+package art
+
+// Option configures optional behaviour of the Tree.
+type Option func(*Tree)
+
+// New creates a tree with provided options.
+// Zero value of the Tree is valid and equal to the tree created without options.
+func New(opts ...Option) *Tree {
+	t := &Tree{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.evictor != nil && t.clock != nil {
+		t.evictor.clock = t.clock
+	}
+	return t
+}
+
+// WithProfiling enables sampling of every n-th operation. For each sampled
+// operation depth, prefix comparisons and visited node kinds are recorded.
+// Aggregated report is available with Tree.Profile.
+func WithProfiling(n int) Option {
+	return func(t *Tree) {
+		t.profiler = newProfiler(n)
+	}
+}
@@ -0,0 +1,279 @@
+package art
+
+import "bytes"
+
+// Persistent is an immutable tree. Insert and Delete return a new version of the tree,
+// nodes on the path of the key are copied and the rest of the nodes are shared with
+// the previous version. Any number of versions can be retained and read concurrently
+// without synchronization. Zero value is an empty tree.
+// Same as in Tree, keys are stored by reference and none of the keys can be a prefix of another key.
+type Persistent struct {
+	root *pnode
+	size int
+}
+
+// pnode is a node of the persistent tree, either a leaf or an inner node with children
+// ordered by edge byte. Prefix of the inner node is not limited in size.
+type pnode struct {
+	leaf     *leaf
+	prefix   []byte
+	edges    []byte
+	children []*pnode
+}
+
+// Len returns number of keys in this version of the tree.
+func (p Persistent) Len() int {
+	return p.size
+}
+
+func (p Persistent) Get(key []byte) (ValueType, bool) {
+	n, depth := p.root, 0
+	for n != nil {
+		if n.leaf != nil {
+			if bytes.Equal(n.leaf.key, key) {
+				return n.leaf.value, true
+			}
+			return nil, false
+		}
+		if !bytes.HasPrefix(key[depth:], n.prefix) {
+			return nil, false
+		}
+		depth += len(n.prefix)
+		if depth >= len(key) {
+			return nil, false
+		}
+		i, found := n.find(key[depth])
+		if !found {
+			return nil, false
+		}
+		n = n.children[i]
+		depth++
+	}
+	return nil, false
+}
+
+// Insert returns a version of the tree with the key set to the value.
+func (p Persistent) Insert(key []byte, value ValueType) Persistent {
+	root, replaced := p.root.insert(&leaf{key: key, value: value}, 0)
+	if !replaced {
+		p.size++
+	}
+	p.root = root
+	return p
+}
+
+// Delete returns a version of the tree without the key.
+// If the key is not stored the same version is returned.
+func (p Persistent) Delete(key []byte) Persistent {
+	root, removed := p.root.delete(key, 0)
+	if removed {
+		p.root = root
+		p.size--
+	}
+	return p
+}
+
+// Iterate visits all keys in ascending order, until fn returns false.
+func (p Persistent) Iterate(fn func(key []byte, value ValueType) bool) {
+	p.root.iterate(fn)
+}
+
+// find returns position of the child with the edge byte, or the position
+// where it should be inserted.
+func (n *pnode) find(b byte) (int, bool) {
+	i := 0
+	for i < len(n.edges) && n.edges[i] < b {
+		i++
+	}
+	return i, i < len(n.edges) && n.edges[i] == b
+}
+
+// with returns a copy of the inner node where the child at the position is replaced,
+// or inserted if insert is true. If child is nil, it is removed from the copy.
+func (n *pnode) with(i int, b byte, child *pnode, insert bool) *pnode {
+	cp := &pnode{prefix: n.prefix}
+	switch {
+	case insert:
+		cp.edges = make([]byte, 0, len(n.edges)+1)
+		cp.edges = append(append(append(cp.edges, n.edges[:i]...), b), n.edges[i:]...)
+		cp.children = make([]*pnode, 0, len(n.children)+1)
+		cp.children = append(append(append(cp.children, n.children[:i]...), child), n.children[i:]...)
+	case child == nil:
+		cp.edges = append(append(make([]byte, 0, len(n.edges)-1), n.edges[:i]...), n.edges[i+1:]...)
+		cp.children = append(append(make([]*pnode, 0, len(n.children)-1), n.children[:i]...), n.children[i+1:]...)
+	default:
+		cp.edges = n.edges
+		cp.children = append([]*pnode(nil), n.children...)
+		cp.children[i] = child
+	}
+	return cp
+}
+
+// insert returns the node that replaces n in the new version, and true if the key
+// was already stored. depth is an offset in the key where the node starts.
+func (n *pnode) insert(l *leaf, depth int) (*pnode, bool) {
+	if n == nil {
+		return &pnode{leaf: l}, false
+	}
+	key := l.key
+	if n.leaf != nil {
+		if bytes.Equal(n.leaf.key, key) {
+			return &pnode{leaf: l}, true
+		}
+		other := n.leaf.key
+		cmp := commonPrefix(other[depth:], key[depth:])
+		split := depth + cmp
+		if split >= len(key) || split >= len(other) {
+			panic("art: key is a prefix of another key")
+		}
+		parent := &pnode{prefix: key[depth:split]}
+		leaf := &pnode{leaf: l}
+		if key[split] < other[split] {
+			parent.edges = []byte{key[split], other[split]}
+			parent.children = []*pnode{leaf, n}
+		} else {
+			parent.edges = []byte{other[split], key[split]}
+			parent.children = []*pnode{n, leaf}
+		}
+		return parent, false
+	}
+	cmp := commonPrefix(n.prefix, key[depth:])
+	if cmp < len(n.prefix) {
+		// prefix of the node diverges from the key, node is moved under a new parent
+		split := depth + cmp
+		if split >= len(key) {
+			panic("art: key is a prefix of another key")
+		}
+		moved := &pnode{prefix: n.prefix[cmp+1:], edges: n.edges, children: n.children}
+		parent := &pnode{prefix: n.prefix[:cmp]}
+		leaf := &pnode{leaf: l}
+		if key[split] < n.prefix[cmp] {
+			parent.edges = []byte{key[split], n.prefix[cmp]}
+			parent.children = []*pnode{leaf, moved}
+		} else {
+			parent.edges = []byte{n.prefix[cmp], key[split]}
+			parent.children = []*pnode{moved, leaf}
+		}
+		return parent, false
+	}
+	depth += len(n.prefix)
+	if depth >= len(key) {
+		panic("art: key is a prefix of another key")
+	}
+	b := key[depth]
+	i, found := n.find(b)
+	if !found {
+		return n.with(i, b, &pnode{leaf: l}, true), false
+	}
+	child, replaced := n.children[i].insert(l, depth+1)
+	return n.with(i, b, child, false), replaced
+}
+
+// delete returns the node that replaces n in the new version, and true if the key was removed.
+func (n *pnode) delete(key []byte, depth int) (*pnode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	if n.leaf != nil {
+		if bytes.Equal(n.leaf.key, key) {
+			return nil, true
+		}
+		return n, false
+	}
+	if !bytes.HasPrefix(key[depth:], n.prefix) || depth+len(n.prefix) >= len(key) {
+		return n, false
+	}
+	depth += len(n.prefix)
+	b := key[depth]
+	i, found := n.find(b)
+	if !found {
+		return n, false
+	}
+	child, removed := n.children[i].delete(key, depth+1)
+	if !removed {
+		return n, false
+	}
+	if child == nil && len(n.children) == 2 {
+		// path compression, remaining child replaces the node
+		remaining := 1 - i
+		only := n.children[remaining]
+		if only.leaf != nil {
+			return only, true
+		}
+		prefix := make([]byte, 0, len(n.prefix)+1+len(only.prefix))
+		prefix = append(append(append(prefix, n.prefix...), n.edges[remaining]), only.prefix...)
+		return &pnode{prefix: prefix, edges: only.edges, children: only.children}, true
+	}
+	return n.with(i, b, child, false), true
+}
+
+func (n *pnode) iterate(fn func(key []byte, value ValueType) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.leaf != nil {
+		return fn(n.leaf.key, n.leaf.value)
+	}
+	for _, child := range n.children {
+		if !child.iterate(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// SharingStats counts nodes of two versions of the persistent tree, leaves are counted as nodes.
+type SharingStats struct {
+	// Shared is a number of nodes reachable from both versions.
+	Shared int
+	// Private is a number of nodes reachable only from the version that collected the stats.
+	Private int
+	// OtherPrivate is a number of nodes reachable only from the other version.
+	OtherPrivate int
+}
+
+// Sharing reports how many nodes of the version are shared with the other version.
+// Nodes retained by an old version are either shared with the new version or Private,
+// Private nodes are the memory that is released once the old version is dropped.
+// Every node of the version is visited, subtrees of the other version are visited
+// until the shared node.
+func (p Persistent) Sharing(other Persistent) SharingStats {
+	var (
+		stats SharingStats
+		nodes = map[*pnode]struct{}{}
+	)
+	total := p.root.count(func(n *pnode) bool {
+		nodes[n] = struct{}{}
+		return true
+	})
+	var walk func(n *pnode)
+	walk = func(n *pnode) {
+		if n == nil {
+			return
+		}
+		if _, shared := nodes[n]; shared {
+			stats.Shared += n.count(nil)
+			return
+		}
+		stats.OtherPrivate++
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(other.root)
+	stats.Private = total - stats.Shared
+	return stats
+}
+
+// count returns number of nodes in the subtree of n. fn is called for every node, optional,
+// children of the node are not visited if it returns false.
+func (n *pnode) count(fn func(*pnode) bool) int {
+	if n == nil || fn != nil && !fn(n) {
+		return 0
+	}
+	total := 1
+	for _, child := range n.children {
+		total += child.count(fn)
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistent(t *testing.T) {
+	rng := rand.New(rand.NewSource(*seed))
+	var (
+		versions []Persistent
+		models   []map[string]int
+		current  Persistent
+		model    = map[string]int{}
+	)
+	for i := 0; i < 5000; i++ {
+		key := make([]byte, 1+rng.Intn(3), 4)
+		for j := range key {
+			key[j] = byte(rng.Intn(8))
+		}
+		key = append(key, 0xff)
+		if rng.Intn(3) == 0 {
+			current = current.Delete(key)
+			delete(model, string(key))
+		} else {
+			current = current.Insert(key, i)
+			model[string(key)] = i
+		}
+		if i%100 == 0 {
+			versions = append(versions, current)
+			snapshot := map[string]int{}
+			for k, v := range model {
+				snapshot[k] = v
+			}
+			models = append(models, snapshot)
+		}
+	}
+	versions = append(versions, current)
+	models = append(models, model)
+
+	for i, version := range versions {
+		expected := models[i]
+		require.Equal(t, len(expected), version.Len())
+		keys := make([]string, 0, len(expected))
+		for key, value := range expected {
+			rst, found := version.Get([]byte(key))
+			require.True(t, found)
+			require.Equal(t, value, rst)
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var visited []string
+		version.Iterate(func(key []byte, value ValueType) bool {
+			require.Equal(t, expected[string(key)], value)
+			visited = append(visited, string(key))
+			return true
+		})
+		if len(keys) == 0 {
+			require.Empty(t, visited)
+		} else {
+			require.Equal(t, keys, visited)
+		}
+	}
+}
+
+func TestPersistentSharing(t *testing.T) {
+	var base Persistent
+	for i := 0; i < 256; i++ {
+		base = base.Insert([]byte{byte(i), 0}, i)
+	}
+	next := base.Insert([]byte{1, 1}, -1)
+	// only the root and the node on the path of the key are copied
+	require.NotSame(t, base.root, next.root)
+	for i, child := range base.root.children {
+		if i == 1 {
+			require.NotSame(t, child, next.root.children[i])
+		} else {
+			require.Same(t, child, next.root.children[i])
+		}
+	}
+	// root is private to the base, root, node on the path and the new leaf to the next version
+	require.Equal(t, SharingStats{Shared: 256, Private: 1, OtherPrivate: 3}, base.Sharing(next))
+	require.Equal(t, SharingStats{Shared: 256, Private: 3, OtherPrivate: 1}, next.Sharing(base))
+	require.Equal(t, SharingStats{Shared: 257}, base.Sharing(base))
+	require.Equal(t, SharingStats{Private: 257}, base.Sharing(Persistent{}))
+
+	deleted := next.Delete([]byte{1, 1})
+	require.Equal(t, base.Len(), deleted.Len())
+	_, found := next.Get([]byte{1, 1})
+	require.True(t, found)
+	_, found = deleted.Get([]byte{1, 1})
+	require.False(t, found)
+	require.Same(t, deleted.root, deleted.Delete([]byte{1, 2}).root)
+
+	var keys [][]byte
+	deleted.Iterate(func(key []byte, _ ValueType) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	require.Len(t, keys, 3)
+	require.True(t, sort.SliceIsSorted(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	}))
+}
@@ -0,0 +1,50 @@
+package art
+
+import "fmt"
+
+// PoisonError is used as a panic value when the tree is used after user callback
+// panicked while tree locks were held. Tree state may be inconsistent with the intent
+// of the interrupted operation, therefore any following operation will fail.
+type PoisonError struct {
+	// Cause is the value recovered from the callback.
+	Cause interface{}
+}
+
+func (p *PoisonError) Error() string {
+	return fmt.Sprintf("%v: callback panicked with %v", ErrPoisoned, p.Cause)
+}
+
+func (p *PoisonError) Unwrap() error {
+	return ErrPoisoned
+}
+
+// callback executes fn while locks are held by the caller. If fn panics locks are released,
+// tree is marked as poisoned and panic is propagated to the caller.
+// Without recovery locks would be held forever and every other operation will deadlock.
+func (t *Tree) callback(fn func(), locks ...*olock) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.poisoned.Store(&PoisonError{Cause: r})
+			for _, lock := range locks {
+				lock.Unlock()
+			}
+			panic(r)
+		}
+	}()
+	fn()
+}
+
+// checkPoisoned panics with PoisonError if tree was poisoned.
+func (t *Tree) checkPoisoned() {
+	if err := t.poisoned.Load(); err != nil {
+		panic(err)
+	}
+}
+
+// Poisoned returns non-nil error if callback panicked while tree was locked.
+func (t *Tree) Poisoned() error {
+	if err := t.poisoned.Load(); err != nil {
+		return err
+	}
+	return nil
+}
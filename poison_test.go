@@ -0,0 +1,52 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoisonedByCallback(t *testing.T) {
+	var tree Tree
+	tree.Insert([]byte{1}, 1)
+	tree.Insert([]byte{2}, 2)
+	root := tree.root.(*inner)
+
+	require.Nil(t, tree.Poisoned())
+	require.PanicsWithValue(t, "user error", func() {
+		root.lock.Lock()
+		tree.callback(func() {
+			panic("user error")
+		}, &root.lock)
+	})
+
+	// lock was released
+	root.lock.Lock()
+	root.lock.Unlock()
+
+	err := tree.Poisoned()
+	require.True(t, errors.Is(err, ErrPoisoned))
+	require.PanicsWithError(t, err.Error(), func() {
+		tree.Get([]byte{1})
+	})
+	require.PanicsWithError(t, err.Error(), func() {
+		tree.Insert([]byte{3}, 3)
+	})
+}
+
+func TestPoisonedReleasesWALStripe(t *testing.T) {
+	var log bytes.Buffer
+	tree := New(WithWAL(&log))
+	tree.Insert([]byte{1}, 1)
+	require.Panics(t, func() {
+		tree.Update([]byte{1}, func(ValueType, bool) (ValueType, bool) {
+			panic("user error")
+		})
+	})
+	require.NotNil(t, tree.Poisoned())
+	// would deadlock if the stripe of the key remained locked
+	tree.wal.lockAll()
+	tree.wal.unlockAll()
+}
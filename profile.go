@@ -0,0 +1,180 @@
+package art
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	kindNode4 = iota
+	kindNode16
+	kindNode48
+	kindNode256
+	kindsCount
+)
+
+var kindNames = [kindsCount]string{"node4", "node16", "node48", "node256"}
+
+func kindOf(n inode) int {
+	switch n.(type) {
+	case *node4:
+		return kindNode4
+	case *node16:
+		return kindNode16
+	case *node48:
+		return kindNode48
+	default:
+		return kindNode256
+	}
+}
+
+// trace is a summary of a single descent from the root.
+type trace struct {
+	// inner is a number of inner nodes visited
+	inner int
+	// prefixBytes is a number of prefix bytes compared with the key
+	prefixBytes int
+	// fullPrefixes is a number of compared prefixes with length equal to maxPrefixLen
+	fullPrefixes int
+	kinds        [kindsCount]int
+}
+
+// trace descends the tree using the key and records visited nodes.
+// Tree is not modified.
+func (t *Tree) trace(key []byte) (tr trace) {
+restart:
+	tr = trace{}
+	version, _ := t.lock.RLock()
+	n, isInner := t.root.(*inner)
+	if !isInner {
+		if t.lock.RUnlock(version, nil) {
+			goto restart
+		}
+		return tr
+	}
+	parent := &t.lock
+	depth := 0
+	for {
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		tr.inner++
+		tr.kinds[kindOf(n.node)]++
+		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
+		tr.prefixBytes += cmp
+		if n.prefixLen == maxPrefixLen {
+			tr.fullPrefixes++
+		}
+		depth += n.prefixLen
+		var next node
+		if cmp == n.prefixLen && depth < len(key) {
+			_, next = n.node.child(key[depth])
+		}
+		child, isInner := next.(*inner)
+		if !isInner {
+			if n.lock.RUnlock(nversion, nil) {
+				goto restart
+			}
+			return tr
+		}
+		depth++
+		parent, version, n = &n.lock, nversion, child
+	}
+}
+
+// Profile is an aggregated report of the sampled operations.
+type Profile struct {
+	// Samples is a number of sampled operations.
+	Samples uint64
+	// Depth is a histogram of visited inner nodes, Depth[i] is a number of samples
+	// that visited i inner nodes.
+	Depth []uint64
+	// PrefixBytes is a total number of prefix bytes compared with keys.
+	PrefixBytes uint64
+	// FullPrefixes is a number of visited nodes with prefix that reached 8 bytes limit.
+	// Long keys with shared prefixes are split into the chain of such nodes.
+	FullPrefixes uint64
+	// Nodes is a number of visited nodes of every kind (node4, node16, node48, node256).
+	Nodes [kindsCount]uint64
+}
+
+func (p Profile) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "samples: %d\n", p.Samples)
+	if p.Samples == 0 {
+		return b.String()
+	}
+	var visited uint64
+	for _, n := range p.Nodes {
+		visited += n
+	}
+	fmt.Fprintf(&b, "avg depth: %.2f\n", float64(visited)/float64(p.Samples))
+	fmt.Fprintf(&b, "avg prefix bytes: %.2f\n", float64(p.PrefixBytes)/float64(p.Samples))
+	fmt.Fprintf(&b, "full prefixes: %d\n", p.FullPrefixes)
+	for i, n := range p.Nodes {
+		fmt.Fprintf(&b, "%s: %d\n", kindNames[i], n)
+	}
+	_, _ = b.WriteString("depth:")
+	for i, n := range p.Depth {
+		if n > 0 {
+			fmt.Fprintf(&b, " %d=%d", i, n)
+		}
+	}
+	return b.String()
+}
+
+type profiler struct {
+	// every can be changed by Reconfigure, and must be accessed atomically.
+	every uint64
+	ops   uint64
+
+	mu      sync.Mutex
+	profile Profile
+}
+
+func newProfiler(every int) *profiler {
+	if every < 1 {
+		every = 1
+	}
+	return &profiler{every: uint64(every)}
+}
+
+// observe records the descent for the key if operation is sampled.
+func (p *profiler) observe(t *Tree, key []byte) {
+	if atomic.AddUint64(&p.ops, 1)%atomic.LoadUint64(&p.every) != 0 {
+		return
+	}
+	tr := t.trace(key)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profile.Samples++
+	for len(p.profile.Depth) <= tr.inner {
+		p.profile.Depth = append(p.profile.Depth, 0)
+	}
+	p.profile.Depth[tr.inner]++
+	p.profile.PrefixBytes += uint64(tr.prefixBytes)
+	p.profile.FullPrefixes += uint64(tr.fullPrefixes)
+	for i, n := range tr.kinds {
+		p.profile.Nodes[i] += uint64(n)
+	}
+}
+
+func (p *profiler) report() Profile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rst := p.profile
+	rst.Depth = append([]uint64(nil), p.profile.Depth...)
+	return rst
+}
+
+// Profile returns report for sampled operations. If tree was created
+// without profiling empty report is returned.
+func (t *Tree) Profile() Profile {
+	if t.profiler == nil {
+		return Profile{}
+	}
+	return t.profiler.report()
+}
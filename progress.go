@@ -0,0 +1,127 @@
+package art
+
+import "bytes"
+
+// children returns number of children in the inner node and number of children
+// that are ordered before byte b.
+func children(n inode, b byte) (int, int) {
+	var (
+		total, before int
+		pointer       *byte
+	)
+	for {
+		k, child := n.next(pointer)
+		if child == nil {
+			return total, before
+		}
+		total++
+		if k < b {
+			before++
+		}
+		pointer = &k
+	}
+}
+
+// position estimates rank of the key in the tree as a fraction in range [0, 1].
+// Estimate assumes that all children of the inner node hold equal number of keys,
+// e.g. key that follows first child of the node4 at the root will be placed at 0.25.
+func (t *Tree) position(key []byte) float64 {
+restart:
+	var (
+		pos    float64
+		weight = 1.0
+	)
+	version, _ := t.lock.RLock()
+	root := t.root
+	n, isInner := root.(*inner)
+	if !isInner {
+		l, isLeaf := root.(*leaf)
+		if isLeaf && bytes.Compare(key, l.key) > 0 {
+			pos = 1
+		}
+		if t.lock.RUnlock(version, nil) {
+			goto restart
+		}
+		return pos
+	}
+	parent := &t.lock
+	depth := 0
+	for {
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		var next node
+		cmp := comparePrefix(n.prefix[:n.prefixLen], key, 0, depth)
+		if cmp != n.prefixLen {
+			// key is either before or after every key in the subtree
+			if depth+cmp < len(key) && key[depth+cmp] > n.prefix[cmp] {
+				pos += weight
+			}
+		} else if depth+n.prefixLen < len(key) {
+			depth += n.prefixLen
+			total, before := children(n.node, key[depth])
+			weight /= float64(total)
+			pos += weight * float64(before)
+			_, next = n.node.child(key[depth])
+			if l, isLeaf := next.(*leaf); isLeaf && bytes.Compare(key, l.key) > 0 {
+				pos += weight
+			}
+		}
+		child, isInner := next.(*inner)
+		if !isInner {
+			if n.lock.RUnlock(nversion, nil) {
+				goto restart
+			}
+			return pos
+		}
+		depth++
+		parent, version, n = &n.lock, nversion, child
+	}
+}
+
+// Progress returns estimated fraction of the range that was already visited by the iterator.
+// Fraction is computed from the position of the last visited key in the tree and
+// doesn't require to count keys in the range.
+func (i *iterator) Progress() float64 {
+	if i.closed {
+		return 1
+	}
+	if i.visited == 0 {
+		return 0
+	}
+	begin, end := 0.0, 1.0
+	if i.reverse {
+		begin, end = end, begin
+	}
+	if len(i.begin) > 0 {
+		begin = i.tree.position(i.begin)
+	}
+	if len(i.terminate) > 0 {
+		end = i.tree.position(i.terminate)
+	}
+	if begin == end {
+		return 1
+	}
+	fraction := (i.tree.position(i.key) - begin) / (end - begin)
+	if fraction < 0 {
+		return 0
+	} else if fraction > 1 {
+		return 1
+	}
+	return fraction
+}
+
+// EstimatedRemaining returns estimated number of keys that will be visited by the iterator.
+// Estimate is based on the number of keys visited so far and the Progress, therefore
+// it is not available until iterator visited at least one key.
+func (i *iterator) EstimatedRemaining() (uint64, bool) {
+	if i.closed {
+		return 0, true
+	}
+	fraction := i.Progress()
+	if fraction == 0 {
+		return 0, false
+	}
+	return uint64(float64(i.visited) * (1 - fraction) / fraction), true
+}
@@ -0,0 +1,60 @@
+package art
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPosition(t *testing.T) {
+	var tree Tree
+	for _, key := range []string{"a", "b", "c", "d"} {
+		tree.Insert([]byte(key), key)
+	}
+	require.Equal(t, 0.0, tree.position([]byte("a")))
+	require.Equal(t, 0.5, tree.position([]byte("c")))
+	require.Equal(t, 0.75, tree.position([]byte("cc")))
+	require.Equal(t, 1.0, tree.position([]byte("e")))
+}
+
+func TestIteratorProgress(t *testing.T) {
+	var tree Tree
+	n := 10_000
+	for i := 0; i < n; i++ {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(i)*0x9e3779b97f4a7c15)
+		tree.Insert(key, i)
+	}
+	iter := tree.Iterator(nil, nil)
+	require.Equal(t, 0.0, iter.Progress())
+	_, ok := iter.EstimatedRemaining()
+	require.False(t, ok)
+
+	visited := 0
+	for iter.Next() {
+		visited++
+		if visited%1000 == 0 {
+			require.InDelta(t, float64(visited)/float64(n), iter.Progress(), 0.05)
+			remaining, ok := iter.EstimatedRemaining()
+			require.True(t, ok)
+			require.InDelta(t, n-visited, remaining, 0.1*float64(n))
+		}
+	}
+	require.Equal(t, 1.0, iter.Progress())
+}
+
+func TestReverseIteratorProgress(t *testing.T) {
+	var tree Tree
+	for _, key := range []string{"a", "b", "c", "d"} {
+		tree.Insert([]byte(key), key)
+	}
+	iter := tree.Iterator(nil, nil).Reverse()
+	require.True(t, iter.Next())
+	require.True(t, iter.Next())
+	require.Equal(t, []byte("c"), iter.Key())
+	require.Equal(t, 0.5, iter.Progress())
+	remaining, ok := iter.EstimatedRemaining()
+	require.True(t, ok)
+	require.Equal(t, uint64(2), remaining)
+}
@@ -0,0 +1,222 @@
+package art
+
+import "bytes"
+
+// Inclusivity describes which bounds of the Range are included.
+type Inclusivity uint8
+
+const (
+	// IncludeStart includes key equal to the start of the range.
+	IncludeStart Inclusivity = 1 << iota
+	// IncludeEnd includes key equal to the end of the range.
+	IncludeEnd
+	// IncludeBoth includes both bounds.
+	IncludeBoth = IncludeStart | IncludeEnd
+)
+
+// Range of keys. Empty Start or End means that range is unbounded on that side,
+// bound is excluded unless included by Inclusivity.
+// Tree.Iterator and Tree.DeleteRange use range (start, end], which is Range{start, end, IncludeEnd}.
+type Range struct {
+	Start, End  []byte
+	Inclusivity Inclusivity
+}
+
+// All is a range that includes every key.
+func All() Range {
+	return Range{}
+}
+
+// After is a range of keys > key.
+func After(key []byte) Range {
+	return Range{Start: key}
+}
+
+// From is a range of keys >= key.
+func From(key []byte) Range {
+	return Range{Start: key, Inclusivity: IncludeStart}
+}
+
+// Until is a range of keys <= key.
+func Until(key []byte) Range {
+	return Range{End: key, Inclusivity: IncludeEnd}
+}
+
+// Before is a range of keys < key.
+func Before(key []byte) Range {
+	return Range{End: key}
+}
+
+// PrefixRange is a range of keys that have the prefix.
+func PrefixRange(prefix []byte) Range {
+	return Range{Start: prefix, End: successor(prefix), Inclusivity: IncludeStart}
+}
+
+// Bound is a boundary of the range, created by GE, GT, LE or LT.
+type Bound func(*Range)
+
+// GE bounds the range to keys >= key.
+func GE(key []byte) Bound {
+	return func(r *Range) {
+		r.Start = key
+		r.Inclusivity |= IncludeStart
+	}
+}
+
+// GT bounds the range to keys > key.
+func GT(key []byte) Bound {
+	return func(r *Range) {
+		r.Start = key
+		r.Inclusivity &^= IncludeStart
+	}
+}
+
+// LE bounds the range to keys <= key.
+func LE(key []byte) Bound {
+	return func(r *Range) {
+		r.End = key
+		r.Inclusivity |= IncludeEnd
+	}
+}
+
+// LT bounds the range to keys < key.
+func LT(key []byte) Bound {
+	return func(r *Range) {
+		r.End = key
+		r.Inclusivity &^= IncludeEnd
+	}
+}
+
+// RangeOf returns a range with the bounds, e.g. RangeOf(GE(start), LT(end)).
+// Range is unbounded on the side without a bound, later bound overrides the earlier one.
+func RangeOf(bounds ...Bound) Range {
+	var r Range
+	for _, bound := range bounds {
+		bound(&r)
+	}
+	return r
+}
+
+// successor returns the smallest key that is larger than every key with the prefix,
+// nil if there is no such key.
+func successor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			rst := append([]byte(nil), prefix[:i+1]...)
+			rst[i]++
+			return rst
+		}
+	}
+	return nil
+}
+
+func (r Range) includesStart() bool {
+	return r.Inclusivity&IncludeStart != 0
+}
+
+func (r Range) includesEnd() bool {
+	return r.Inclusivity&IncludeEnd != 0
+}
+
+// afterStart is true if key is not before the start bound.
+func (r Range) afterStart(key []byte) bool {
+	if len(r.Start) == 0 {
+		return true
+	}
+	cmp := bytes.Compare(key, r.Start)
+	return cmp > 0 || cmp == 0 && r.includesStart()
+}
+
+// beforeEnd is true if key is not after the end bound.
+func (r Range) beforeEnd(key []byte) bool {
+	if len(r.End) == 0 {
+		return true
+	}
+	cmp := bytes.Compare(key, r.End)
+	return cmp < 0 || cmp == 0 && r.includesEnd()
+}
+
+// Contains is true if key is within the range.
+func (r Range) Contains(key []byte) bool {
+	return r.afterStart(key) && r.beforeEnd(key)
+}
+
+// Empty is true if range can't contain any key.
+func (r Range) Empty() bool {
+	if len(r.Start) == 0 || len(r.End) == 0 {
+		return false
+	}
+	cmp := bytes.Compare(r.Start, r.End)
+	return cmp > 0 || cmp == 0 && r.Inclusivity != IncludeBoth
+}
+
+// Intersect returns a range of keys contained in both ranges.
+func (r Range) Intersect(other Range) Range {
+	rst := r
+	if len(other.Start) > 0 {
+		cmp := 1
+		if len(r.Start) > 0 {
+			cmp = bytes.Compare(other.Start, r.Start)
+		}
+		if cmp > 0 || cmp == 0 && !other.includesStart() {
+			rst.Start = other.Start
+			rst.Inclusivity = rst.Inclusivity&^IncludeStart | other.Inclusivity&IncludeStart
+		}
+	}
+	if len(other.End) > 0 {
+		cmp := -1
+		if len(r.End) > 0 {
+			cmp = bytes.Compare(other.End, r.End)
+		}
+		if cmp < 0 || cmp == 0 && !other.includesEnd() {
+			rst.End = other.End
+			rst.Inclusivity = rst.Inclusivity&^IncludeEnd | other.Inclusivity&IncludeEnd
+		}
+	}
+	return rst
+}
+
+// covers is true if all keys with the prefix are within the range.
+func (r Range) covers(prefix []byte) bool {
+	if len(r.Start) > 0 {
+		cmp := bytes.Compare(r.Start, prefix)
+		if cmp > 0 || cmp == 0 && !r.includesStart() {
+			return false
+		}
+	}
+	return len(r.End) == 0 || bytes.Compare(prefix, r.End) < 0 && !bytes.HasPrefix(r.End, prefix)
+}
+
+// disjoint is true if none of the keys with the prefix are within the range.
+func (r Range) disjoint(prefix []byte) bool {
+	if len(r.Start) > 0 && bytes.Compare(prefix, r.Start) < 0 && !bytes.HasPrefix(r.Start, prefix) {
+		return true
+	}
+	if len(r.End) == 0 {
+		return false
+	}
+	cmp := bytes.Compare(prefix, r.End)
+	return cmp > 0 || cmp == 0 && !r.includesEnd()
+}
+
+// Bounded returns iterator over keys within the bounds, in ascending order,
+// same as Scan(RangeOf(bounds...)).
+func (t *Tree) Bounded(bounds ...Bound) *iterator {
+	return t.Scan(RangeOf(bounds...))
+}
+
+// Scan returns iterator over keys in the range, in ascending order.
+// Reversed iterator visits the same range in descending order.
+func (t *Tree) Scan(r Range) *iterator {
+	t.checkPoisoned()
+	r.Start, r.End = t.keyOf(r.Start), t.keyOf(r.End)
+	return &iterator{
+		tree:         t,
+		cursor:       r.Start,
+		inclusive:    len(r.Start) > 0 && r.includesStart(),
+		terminate:    r.End,
+		exclusiveEnd: !r.includesEnd(),
+		ranged:       true,
+		begin:        r.Start,
+	}
+}
@@ -0,0 +1,152 @@
+package art
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeContains(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		rng     Range
+		in, out [][]byte
+	}{
+		{"all", All(), [][]byte{{0}, {0xff, 0xff}}, nil},
+		{"after", After([]byte{2}), [][]byte{{2, 0}, {3}}, [][]byte{{1}, {2}}},
+		{"from", From([]byte{2}), [][]byte{{2}, {3}}, [][]byte{{1, 0xff}}},
+		{"until", Until([]byte{2}), [][]byte{{1}, {2}}, [][]byte{{2, 0}}},
+		{"before", Before([]byte{2}), [][]byte{{1}, {1, 0xff}}, [][]byte{{2}}},
+		{"prefix", PrefixRange([]byte{1, 0xff}), [][]byte{{1, 0xff}, {1, 0xff, 0xff}}, [][]byte{{1, 0xfe}, {2}}},
+		{"prefix max", PrefixRange([]byte{0xff}), [][]byte{{0xff}, {0xff, 0xff}}, [][]byte{{0xfe}}},
+		{"both", Range{[]byte{1}, []byte{3}, IncludeBoth}, [][]byte{{1}, {2}, {3}}, [][]byte{{0}, {3, 0}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			for _, key := range tc.in {
+				require.True(t, tc.rng.Contains(key), "key %v", key)
+			}
+			for _, key := range tc.out {
+				require.False(t, tc.rng.Contains(key), "key %v", key)
+			}
+		})
+	}
+}
+
+func TestRangeIntersect(t *testing.T) {
+	r := From([]byte{2}).Intersect(Before([]byte{5})).Intersect(After([]byte{2}))
+	require.Equal(t, Range{Start: []byte{2}, End: []byte{5}}, r)
+	require.False(t, r.Empty())
+
+	r = Until([]byte{5}).Intersect(Until([]byte{3}))
+	require.Equal(t, Until([]byte{3}), r)
+
+	require.True(t, Range{Start: []byte{3}, End: []byte{3}, Inclusivity: IncludeStart}.Empty())
+	require.False(t, Range{Start: []byte{3}, End: []byte{3}, Inclusivity: IncludeBoth}.Empty())
+	require.True(t, After([]byte{4}).Intersect(Until([]byte{3})).Empty())
+}
+
+func TestRangeOf(t *testing.T) {
+	a, b := []byte{1}, []byte{3}
+	require.Equal(t, Range{Start: a, End: b, Inclusivity: IncludeBoth}, RangeOf(GE(a), LE(b)))
+	require.Equal(t, Range{Start: a, End: b, Inclusivity: IncludeStart}, RangeOf(GE(a), LT(b)))
+	require.Equal(t, Range{Start: a, End: b, Inclusivity: IncludeEnd}, RangeOf(GT(a), LE(b)))
+	require.Equal(t, Range{Start: a, End: b}, RangeOf(GT(a), LT(b)))
+	require.Equal(t, From(a), RangeOf(GE(a)))
+	require.Equal(t, Before(b), RangeOf(LT(b)))
+	require.Equal(t, After(b), RangeOf(GE(a), GT(b)))
+	require.Equal(t, All(), RangeOf())
+}
+
+func TestBounded(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{{1}, {2}, {3}, {4}}
+	for _, key := range keys {
+		tree.Insert(key, nil)
+	}
+	for _, tc := range []struct {
+		bounds   []Bound
+		expected [][]byte
+	}{
+		{[]Bound{GE(keys[1]), LE(keys[2])}, keys[1:3]},
+		{[]Bound{GE(keys[1]), LT(keys[2])}, keys[1:2]},
+		{[]Bound{GT(keys[1]), LE(keys[2])}, keys[2:3]},
+		{[]Bound{GT(keys[1]), LT(keys[2])}, nil},
+		{[]Bound{LT(keys[2])}, keys[:2]},
+	} {
+		var rst [][]byte
+		for iter := tree.Bounded(tc.bounds...); iter.Next(); {
+			rst = append(rst, iter.Key())
+		}
+		require.Equal(t, tc.expected, rst)
+	}
+}
+
+func TestScan(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{{1}, {2, 1}, {2, 2}, {2, 3}, {3}, {4}}
+	for _, key := range keys {
+		tree.Insert(key, nil)
+	}
+	for _, tc := range []struct {
+		desc string
+		rng  Range
+	}{
+		{"all", All()},
+		{"after", After([]byte{2, 1})},
+		{"from", From([]byte{2, 1})},
+		{"until", Until([]byte{3})},
+		{"before", Before([]byte{3})},
+		{"prefix", PrefixRange([]byte{2})},
+		{"both", Range{[]byte{1}, []byte{3}, IncludeBoth}},
+		{"neither", Range{[]byte{1}, []byte{3}, 0}},
+		{"empty", After([]byte{3}).Intersect(Before([]byte{3}))},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var expected [][]byte
+			for _, key := range keys {
+				if tc.rng.Contains(key) {
+					expected = append(expected, key)
+				}
+			}
+			var forward, reverse [][]byte
+			for iter := tree.Scan(tc.rng); iter.Next(); {
+				forward = append(forward, iter.Key())
+			}
+			for iter := tree.Scan(tc.rng).Reverse(); iter.Next(); {
+				reverse = append([][]byte{iter.Key()}, reverse...)
+			}
+			require.Equal(t, expected, forward)
+			require.Equal(t, expected, reverse)
+		})
+	}
+}
+
+func TestDeleteIn(t *testing.T) {
+	keys := [][]byte{{1}, {2, 1}, {2, 2}, {2, 3}, {3}, {4}}
+	for _, rng := range []Range{
+		All(),
+		From([]byte{2, 1}),
+		Before([]byte{2, 3}),
+		PrefixRange([]byte{2}),
+		Range{[]byte{1}, []byte{3}, IncludeBoth},
+		Range{[]byte{1}, []byte{3}, 0},
+	} {
+		var tree Tree
+		for _, key := range keys {
+			tree.Insert(key, nil)
+		}
+		var remaining [][]byte
+		for _, key := range keys {
+			if !rng.Contains(key) {
+				remaining = append(remaining, key)
+			}
+		}
+		require.Equal(t, len(keys)-len(remaining), tree.DeleteIn(rng))
+		var rst [][]byte
+		for iter := tree.Iterator(nil, nil); iter.Next(); {
+			rst = append(rst, iter.Key())
+		}
+		require.Equal(t, remaining, rst, "range %v", rng)
+		require.Equal(t, len(remaining), tree.Len())
+	}
+}
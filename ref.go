@@ -0,0 +1,61 @@
+package art
+
+// Ref is a handle to the key that re-reads the latest value without descending the tree.
+// Ref remembers the path to the leaf of the key, if none of the nodes on the path were
+// modified since the previous read, the leaf is still stored in the tree and its value
+// is returned directly. Otherwise the tree is descended again and the path is updated.
+// Ref must not be used concurrently.
+type Ref struct {
+	key    []byte
+	finger finger
+	leaf   *leaf
+}
+
+// Ref returns a handle to the key, key may be absent in the tree.
+func (t *Tree) Ref(key []byte) *Ref {
+	r := &Ref{key: t.keyOf(key), finger: finger{tree: t}}
+	r.leaf = t.lookup(r.key, &r.finger)
+	return r
+}
+
+// Key returns key of the handle, transformed if the tree has a key transform.
+func (r *Ref) Key() []byte {
+	return r.key
+}
+
+// Get returns the latest value of the key.
+func (r *Ref) Get() (ValueType, bool) {
+	t := r.finger.tree
+	if r.valid() && (r.leaf == nil || !t.expired(r.leaf)) {
+		t.observe(r.key)
+		t.count(MetricGets, 1)
+		t.accessed(r.leaf)
+	} else {
+		// expired leaf is removed by lookup
+		r.leaf = t.lookup(r.key, &r.finger)
+	}
+	if r.leaf == nil {
+		return nil, false
+	}
+	return r.leaf.value, true
+}
+
+// valid returns true if leaf observed during the last descent is still current.
+// Leaves are never modified, value is replaced together with the leaf, therefore
+// it is enough to validate versions of the nodes on the path.
+func (r *Ref) valid() bool {
+	if !optimistic {
+		// pessimistic lock doesn't maintain versions, path is always descended
+		return false
+	}
+	f := &r.finger
+	if len(f.steps) == 0 || f.tree.lock.Check(f.rootVersion) {
+		return false
+	}
+	for _, s := range f.steps {
+		if s.node.lock.Check(s.version) {
+			return false
+		}
+	}
+	return true
+}
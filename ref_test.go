@@ -0,0 +1,126 @@
+package art
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRef(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 1000; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	key := sequentialKey(500)
+	ref := tree.Ref(key)
+	require.Equal(t, key, ref.Key())
+
+	expect := func(value int, found bool) {
+		t.Helper()
+		rst, exists := ref.Get()
+		require.Equal(t, found, exists)
+		if found {
+			require.Equal(t, value, rst)
+		}
+	}
+	expect(500, true)
+	require.Equal(t, optimistic, ref.valid())
+
+	tree.Insert(key, 1)
+	require.False(t, ref.valid())
+	expect(1, true)
+	require.Equal(t, optimistic, ref.valid())
+
+	// modifications of other parts of the tree don't invalidate the path
+	tree.Insert(sequentialKey(600), 0)
+	require.Equal(t, optimistic, ref.valid())
+
+	tree.Delete(key)
+	expect(0, false)
+	tree.Insert(key, 2)
+	expect(2, true)
+
+	tree.DeleteRange(sequentialKey(400), sequentialKey(600))
+	expect(0, false)
+	tree.Insert(key, 3)
+	expect(3, true)
+
+	tree.Clear()
+	expect(0, false)
+	tree.Insert(key, 4)
+	expect(4, true)
+
+	// path is cached only if the key is stored below an inner node
+	tree.Clear()
+	tree.Insert(sequentialKey(1), 1)
+	expect(0, false)
+	tree.Insert(key, 5)
+	expect(5, true)
+}
+
+func TestRefConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var (
+		tree Tree
+		wg   sync.WaitGroup
+		key  = sequentialKey(1000)
+	)
+	for i := 0; i < 2000; i++ {
+		tree.Insert(sequentialKey(i), 0)
+	}
+	ref := tree.Ref(key)
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 10_000; i++ {
+			tree.Insert(key, i)
+			// grows and shrinks the parent of the key
+			for j := 1990; j < 2030; j++ {
+				tree.Insert(sequentialKey(j), 0)
+			}
+			for j := 1990; j < 2030; j++ {
+				tree.Delete(sequentialKey(j))
+			}
+		}
+		close(done)
+	}()
+	last := 0
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		value, found := ref.Get()
+		require.True(t, found)
+		require.GreaterOrEqual(t, value.(int), last)
+		last = value.(int)
+	}
+	wg.Wait()
+	value, _ := ref.Get()
+	require.Equal(t, 10_000, value)
+}
+
+func BenchmarkRef(b *testing.B) {
+	var tree Tree
+	keys := make([][]byte, 100_000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("tenant:%04d:account:%06d:event:%010d\x00", i/10_000, i/100, i))
+		tree.Insert(keys[i], nil)
+	}
+	hot := keys[len(keys)/2]
+	b.Run("ref", func(b *testing.B) {
+		ref := tree.Ref(hot)
+		for i := 0; i < b.N; i++ {
+			_, _ = ref.Get()
+		}
+	})
+	b.Run("get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = tree.Get(hot)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package art
+
+// Scope is a view of the tree restricted to keys with the prefix. Keys passed to the scope
+// are prepended with the prefix, keys returned by the iterators of the scope are stripped
+// of it. Scope doesn't hold any state besides the prefix, it can be created for every
+// request and is safe for concurrent use same as the tree.
+type Scope struct {
+	tree   *Tree
+	prefix []byte
+}
+
+// Sub returns a scope of keys with the prefix.
+// Prefix is copied, caller may reuse the buffer.
+func (t *Tree) Sub(prefix []byte) *Scope {
+	return &Scope{tree: t, prefix: append([]byte(nil), prefix...)}
+}
+
+// Sub returns a nested scope, with the prefix appended to the prefix of s.
+func (s *Scope) Sub(prefix []byte) *Scope {
+	return &Scope{tree: s.tree, prefix: s.key(prefix)}
+}
+
+// Prefix returns the prefix of the scope, it must not be modified.
+func (s *Scope) Prefix() []byte {
+	return s.prefix
+}
+
+// key returns a new slice with the key prepended with the prefix.
+func (s *Scope) key(key []byte) []byte {
+	rst := make([]byte, 0, len(s.prefix)+len(key))
+	return append(append(rst, s.prefix...), key...)
+}
+
+func (s *Scope) Insert(key []byte, value ValueType) {
+	s.tree.Insert(s.key(key), value)
+}
+
+func (s *Scope) Get(key []byte) (ValueType, bool) {
+	return s.tree.Get(s.key(key))
+}
+
+func (s *Scope) Delete(key []byte) {
+	s.tree.Delete(s.key(key))
+}
+
+// Clear deletes all keys of the scope.
+func (s *Scope) Clear() int {
+	return s.tree.DeleteIn(PrefixRange(s.prefix))
+}
+
+// Iterator in range (start, end] of the scope, empty bound is the bound of the scope.
+func (s *Scope) Iterator(start, end []byte) *scopeIterator {
+	return s.Scan(Range{Start: start, End: end, Inclusivity: IncludeEnd})
+}
+
+// Scan returns iterator over keys of the scope in the range, in ascending order.
+// Range bounds are relative to the prefix, empty bound is the bound of the scope.
+func (s *Scope) Scan(r Range) *scopeIterator {
+	scoped := PrefixRange(s.prefix)
+	if len(r.Start) > 0 {
+		scoped.Start = s.key(r.Start)
+		scoped.Inclusivity = scoped.Inclusivity&^IncludeStart | r.Inclusivity&IncludeStart
+	}
+	if len(r.End) > 0 {
+		scoped.End = s.key(r.End)
+		scoped.Inclusivity = scoped.Inclusivity&^IncludeEnd | r.Inclusivity&IncludeEnd
+	}
+	return &scopeIterator{iter: s.tree.Scan(scoped), strip: len(s.tree.keyOf(s.prefix))}
+}
+
+// scopeIterator visits keys of the scope, keys are returned without the prefix.
+type scopeIterator struct {
+	iter  *iterator
+	strip int
+}
+
+// Reverse changes direction of the iteration, must be called before Next.
+// Reversed iterator visits the same range in descending order.
+func (i *scopeIterator) Reverse() *scopeIterator {
+	i.iter.Reverse()
+	return i
+}
+
+func (i *scopeIterator) Next() bool {
+	return i.iter.Next()
+}
+
+// Key returns the key without the prefix, it shares memory with the leaf and must not be modified.
+func (i *scopeIterator) Key() []byte {
+	return i.iter.Key()[i.strip:]
+}
+
+func (i *scopeIterator) Value() ValueType {
+	return i.iter.Value()
+}
+
+// KV returns a copy of the key without the prefix and the value.
+func (i *scopeIterator) KV() ([]byte, ValueType) {
+	return append([]byte(nil), i.Key()...), i.Value()
+}
+
+func (i *scopeIterator) Close() {
+	i.iter.Close()
+}
+
+// Err returns the error of the context that aborted iteration, or nil.
+func (i *scopeIterator) Err() error {
+	return i.iter.Err()
+}
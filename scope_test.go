@@ -0,0 +1,54 @@
+package art
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope(t *testing.T) {
+	tree := New()
+	tenants := [][]byte{{1}, {1, 0xff}, {2}, {0xff, 0xff}}
+	for i, tenant := range tenants {
+		scope := tree.Sub(tenant)
+		for _, key := range []string{"a\x00", "b\x00", "c\x00"} {
+			scope.Insert([]byte(key), i)
+		}
+	}
+	// keys next to the scope bounds
+	tree.Insert([]byte{1, 0xfe, 0}, -1)
+	tree.Insert([]byte{3, 0}, -1)
+
+	scope := tree.Sub([]byte{1, 0xff})
+	value, found := scope.Get([]byte("b\x00"))
+	require.True(t, found)
+	require.Equal(t, 1, value)
+	_, found = scope.Get([]byte{0xfe, 0})
+	require.False(t, found)
+
+	collect := func(iter *scopeIterator) []string {
+		var keys []string
+		for iter.Next() {
+			key, _ := iter.KV()
+			keys = append(keys, string(key))
+		}
+		return keys
+	}
+	require.Equal(t, []string{"a\x00", "b\x00", "c\x00"}, collect(scope.Iterator(nil, nil)))
+	require.Equal(t, []string{"b\x00", "c\x00"}, collect(scope.Iterator([]byte("a\x00"), nil)))
+	require.Equal(t, []string{"a\x00", "b\x00"}, collect(scope.Iterator(nil, []byte("b\x00"))))
+	require.Equal(t, []string{"c\x00", "b\x00", "a\x00"}, collect(scope.Iterator(nil, nil).Reverse()))
+	require.Equal(t, []string{"b\x00", "a\x00"}, collect(scope.Scan(RangeOf(LT([]byte("c")))).Reverse()))
+	require.Equal(t, []string{"a\x00", "b\x00", "c\x00"}, collect(tree.Sub([]byte{0xff}).Sub([]byte{0xff}).Iterator(nil, nil)))
+	require.Empty(t, collect(tree.Sub([]byte{4}).Iterator(nil, nil)))
+
+	scope.Delete([]byte("a\x00"))
+	require.Equal(t, []string{"b\x00", "c\x00"}, collect(scope.Iterator(nil, nil)))
+	require.Equal(t, 2, scope.Clear())
+	require.Equal(t, 3+3+3+2, tree.Len())
+	_, found = tree.Get([]byte{1, 0xfe, 0})
+	require.True(t, found)
+
+	// whole tree
+	require.Len(t, collect(tree.Sub(nil).Iterator(nil, nil)), tree.Len())
+}
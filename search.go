@@ -0,0 +1,98 @@
+package art
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Keys of the node16 are compared with the searched key all at once, producing
+// a 16 bit mask with a bit per key. Mask is computed with a single SIMD compare
+// if cpu supports it, otherwise by a branch-free compare of two 64 bit words.
+// Cost of the mask is constant, while the scalar loop exits on the first match,
+// therefore nodes with few keys are searched with the scalar loop.
+// Cutoffs were chosen using BenchmarkNode16Search, with the key in the middle of the node.
+const (
+	simdCutoff     = 6
+	unrolledCutoff = 12
+)
+
+func lthMask(lth uint8) uint16 {
+	return uint16(1<<lth - 1)
+}
+
+// index returns position of the key among lth keys.
+func index(key *byte, nkey *[16]byte, lth uint8) (int, bool) {
+	if lth <= scalarCutoff {
+		for i, b := range nkey[:lth] {
+			if b == *key {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	mask := equal(key, nkey) & lthMask(lth)
+	if mask == 0 {
+		return 0, false
+	}
+	return bits.TrailingZeros16(mask), true
+}
+
+// position returns an index of the first of lth sorted keys that is not lower than the key.
+func position(key *byte, nkey *[16]byte, lth uint8) int {
+	if lth <= scalarCutoff {
+		for i, b := range nkey[:lth] {
+			if *key <= b {
+				return i
+			}
+		}
+		return int(lth)
+	}
+	return bits.OnesCount16(lower(key, nkey) & lthMask(lth))
+}
+
+// after returns an index of the first of lth sorted keys that is greater than the key.
+func after(key *byte, nkey *[16]byte, lth uint8) int {
+	if *key == 255 {
+		return int(lth)
+	}
+	next := *key + 1
+	return position(&next, nkey, lth)
+}
+
+const (
+	lows   = 0x0101010101010101
+	highs  = 0x8080808080808080
+	gather = 0x0102040810204080
+)
+
+// movemask packs high bits of every byte of the word into 8 bit mask.
+func movemask(w uint64) uint16 {
+	return uint16((w >> 7 & lows) * gather >> 56)
+}
+
+// equalSWAR compares 8 keys packed into the word with the key broadcasted to every byte.
+func equalSWAR(w, k uint64) uint16 {
+	x := w ^ k
+	nonzero := (x&^highs + ^uint64(highs)) | x
+	return movemask(^nonzero & highs)
+}
+
+// lowerSWAR compares 8 keys packed into the word with the key broadcasted to every byte.
+func lowerSWAR(w, k uint64) uint16 {
+	// high bit of every byte is set if low 7 bits of w are not lower than low 7 bits of k,
+	// w|highs is always larger than k&^highs, therefore borrow doesn't cross bytes
+	notLower := (w | highs) - (k &^ highs)
+	return movemask(^w&k | ^(w^k) & ^notLower)
+}
+
+func equalUnrolled(key *byte, nkey *[16]byte) uint16 {
+	k := uint64(*key) * lows
+	return equalSWAR(binary.LittleEndian.Uint64(nkey[:8]), k) |
+		equalSWAR(binary.LittleEndian.Uint64(nkey[8:]), k)<<8
+}
+
+func lowerUnrolled(key *byte, nkey *[16]byte) uint16 {
+	k := uint64(*key) * lows
+	return lowerSWAR(binary.LittleEndian.Uint64(nkey[:8]), k) |
+		lowerSWAR(binary.LittleEndian.Uint64(nkey[8:]), k)<<8
+}
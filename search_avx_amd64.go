@@ -1,13 +1,53 @@
 package art
 
-import "math/bits"
+var (
+	hasAVX       = detectAVX()
+	scalarCutoff = cutoff()
+)
 
-func index(key *byte, nkey *[16]byte) (int, bool) {
-	bitfield := search(key, nkey)
-	if bitfield == 0 {
-		return 0, false
+func cutoff() uint8 {
+	if hasAVX {
+		return simdCutoff
 	}
-	return bits.TrailingZeros16(bitfield), true
+	return unrolledCutoff
 }
 
+func detectAVX() bool {
+	const (
+		osxsave = 1 << 27
+		avx     = 1 << 28
+	)
+	_, _, ecx, _ := cpuid(1, 0)
+	if ecx&osxsave == 0 || ecx&avx == 0 {
+		return false
+	}
+	// os must preserve xmm and ymm registers
+	eax, _ := xgetbv()
+	return eax&0x6 == 0x6
+}
+
+// equal sets a bit for every element of nkey that is equal to the key.
+func equal(key *byte, nkey *[16]byte) uint16 {
+	if hasAVX {
+		return search(key, nkey)
+	}
+	return equalUnrolled(key, nkey)
+}
+
+// lower sets a bit for every element of nkey that is lower than the key.
+func lower(key *byte, nkey *[16]byte) uint16 {
+	if hasAVX {
+		return lowerAVX(key, nkey)
+	}
+	return lowerUnrolled(key, nkey)
+}
+
+//go:noescape
 func search(key *byte, nkey *[16]byte) uint16
+
+//go:noescape
+func lowerAVX(key *byte, nkey *[16]byte) uint16
+
+func cpuid(leaf, subleaf uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
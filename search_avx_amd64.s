@@ -15,3 +15,42 @@ TEXT ·search(SB), NOSPLIT, $0-18
 	PMOVMSKB X0, AX
 	MOVW     AX, ret+16(FP)
 	RET
+
+// func lowerAVX(key *byte, nkey *[16]byte) uint16
+// Requires: AVX
+TEXT ·lowerAVX(SB), NOSPLIT, $0-18
+	MOVQ      key+0(FP), AX
+	MOVQ      nkey+8(FP), CX
+	VPXOR     X1, X1, X1
+	VMOVD     (AX), X0
+	VPSHUFB   X1, X0, X0
+	MOVL      $0x00000080, AX
+	VMOVD     AX, X2
+	VPSHUFB   X1, X2, X2
+	VLDDQU    (CX), X1
+	VPXOR     X2, X0, X0
+	VPXOR     X2, X1, X1
+	VPCMPGTB  X1, X0, X0
+	VPMOVMSKB X0, AX
+	MOVW      AX, ret+16(FP)
+	RET
+
+// func cpuid(leaf uint32, subleaf uint32) (eax uint32, ebx uint32, ecx uint32, edx uint32)
+// Requires: CPUID
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL leaf+0(FP), AX
+	MOVL subleaf+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax uint32, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	XORL CX, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
@@ -1,14 +1,16 @@
+//go:build !amd64
 // +build !amd64
 
 package art
 
-// binary search is slower then 16 elem loop, 23ns > 16ns per op in worst case of scanning whole array
-// no reason to use binary search for non-vectorized version
-func index(key *byte, nkey *[16]byte) (int, bool) {
-	for i := range nkey {
-		if nkey[i] == *key {
-			return i, true
-		}
-	}
-	return 0, false
+const scalarCutoff = unrolledCutoff
+
+// equal sets a bit for every element of nkey that is equal to the key.
+func equal(key *byte, nkey *[16]byte) uint16 {
+	return equalUnrolled(key, nkey)
+}
+
+// lower sets a bit for every element of nkey that is lower than the key.
+func lower(key *byte, nkey *[16]byte) uint16 {
+	return lowerUnrolled(key, nkey)
 }
@@ -0,0 +1,326 @@
+package art
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+)
+
+// serializationVersion is stored in the header, and must be incremented
+// on incompatible changes of the format.
+const serializationVersion = 1
+
+const serializationMagic = "art"
+
+// Node tags in the serialized format, inner nodes are tagged by kind
+// as tagInner+kindNode4, tagInner+kindNode16 and so on.
+const (
+	tagEmpty byte = iota
+	tagLeaf
+	tagInner
+)
+
+// WithValueCodec configures how values are serialized by MarshalBinary and UnmarshalBinary.
+// By default values must be []byte or nil.
+func WithValueCodec(encode func(dst []byte, value ValueType) []byte, decode func([]byte) (ValueType, error)) Option {
+	return func(t *Tree) {
+		t.encode = encode
+		t.decode = decode
+	}
+}
+
+// MarshalBinary serializes structure of the tree, including prefixes of the inner nodes,
+// so that the tree can be restored by UnmarshalBinary without inserting every key.
+// Leaves store only the part of the key that is not stored on the path.
+// Concurrent writers must be stopped, ErrConcurrentModification is returned if modification
+// was detected during serialization.
+//
+// Format:
+//
+//	header: "art" | version byte | uvarint number of keys | node
+//	leaf:   tagLeaf | uvarint length | key suffix | uvarint length | value
+//	inner:  tagInner+kind | prefix length byte | prefix | uvarint number of children | (edge byte | node)...
+func (t *Tree) MarshalBinary() ([]byte, error) {
+	writes := atomic.LoadUint64(&t.writes)
+	e := encoder{encode: t.encode}
+	e.buf = append(e.buf, serializationMagic...)
+	e.buf = append(e.buf, serializationVersion)
+	e.buf = binary.AppendUvarint(e.buf, uint64(atomic.LoadInt64(&t.size)))
+	if t.root == nil {
+		e.buf = append(e.buf, tagEmpty)
+	} else if err := e.node(t.root, 0); err != nil {
+		return nil, err
+	}
+	if err := t.unmodifiedSince(writes); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// unmodifiedSince returns ErrConcurrentModification if the tree was modified after
+// the number of writes was loaded. Serialization is not synchronized with writers,
+// detection is best effort.
+func (t *Tree) unmodifiedSince(writes uint64) error {
+	if atomic.LoadUint64(&t.writes) != writes {
+		return fmt.Errorf("%w: tree was modified during serialization", ErrConcurrentModification)
+	}
+	return nil
+}
+
+type encoder struct {
+	encode       func([]byte, ValueType) []byte
+	buf, scratch []byte
+}
+
+func (e *encoder) bytes(data []byte) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(data)))
+	e.buf = append(e.buf, data...)
+}
+
+// value appends length-prefixed encoding of the value.
+func (e *encoder) value(value ValueType) error {
+	if e.encode != nil {
+		e.scratch = e.encode(e.scratch[:0], value)
+		e.bytes(e.scratch)
+		return nil
+	}
+	raw, ok := value.([]byte)
+	if !ok && value != nil {
+		return fmt.Errorf("%w: value of type %T can't be serialized without codec", ErrUnsupportedValue, value)
+	}
+	e.bytes(raw)
+	return nil
+}
+
+func (e *encoder) node(n node, depth int) error {
+	switch n := n.(type) {
+	case *leaf:
+		e.buf = append(e.buf, tagLeaf)
+		e.bytes(n.key[depth:])
+		return e.value(n.value)
+	case *inner:
+		total, _ := children(n.node, 0)
+		e.buf = append(e.buf, tagInner+byte(kindOf(n.node)), byte(n.prefixLen))
+		e.buf = append(e.buf, n.prefix[:n.prefixLen]...)
+		e.buf = binary.AppendUvarint(e.buf, uint64(total))
+		var pointer *byte
+		for {
+			b, child := n.node.next(pointer)
+			if child == nil {
+				break
+			}
+			e.buf = append(e.buf, b)
+			if err := e.node(child, depth+n.prefixLen+1); err != nil {
+				return err
+			}
+			pointer = &b
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary replaces content of the tree with the tree serialized by MarshalBinary.
+// Nodes are restored with the same kinds and prefixes, keys are not inserted one by one.
+// Concurrent operations are safe, they observe either previous or restored content.
+func (t *Tree) UnmarshalBinary(data []byte) error {
+	t.checkPoisoned()
+	header := len(serializationMagic) + 1
+	if len(data) < header || string(data[:header-1]) != serializationMagic {
+		return fmt.Errorf("%w: invalid header", ErrCorrupt)
+	}
+	if data[header-1] != serializationVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrCorrupt, data[header-1])
+	}
+	d := decoder{data: data[header:], decode: t.decode, alloc: t.allocator(), version: atomic.AddUint64(&t.writes, 1)}
+	size, err := d.uvarint()
+	if err != nil {
+		return err
+	}
+	root, err := d.node(nil)
+	if err != nil {
+		return err
+	}
+	if len(d.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(d.data))
+	}
+	if uint64(len(d.leaves)) != size {
+		return fmt.Errorf("%w: expected %d keys, decoded %d", ErrCorrupt, size, len(d.leaves))
+	}
+
+	t.lock.Lock()
+	t.root = root
+	atomic.StoreInt64(&t.size, int64(size))
+	t.lock.Unlock()
+	if t.limiter != nil {
+		t.limiter.reset()
+	}
+	if t.evictor != nil {
+		atomic.StoreInt64(&t.evictor.bytes, 0)
+	}
+	if t.guard != nil {
+		t.guard.reset()
+	}
+	t.loaded(d.leaves)
+	return nil
+}
+
+// loaded updates state of the tree after the leaves were stored without inserting them one by one.
+func (t *Tree) loaded(leaves []*leaf) {
+	for _, l := range leaves {
+		if t.limiter != nil {
+			t.limiter.add(leafSize(l))
+		}
+		if t.evictor != nil {
+			t.evictor.add(leafSize(l))
+			t.evictor.touch(l)
+		}
+		if t.guard != nil {
+			t.guard.stored(l)
+		}
+	}
+}
+
+type decoder struct {
+	data    []byte
+	decode  func([]byte) (ValueType, error)
+	alloc   Allocator
+	version uint64
+
+	leaves []*leaf
+}
+
+func (d *decoder) byte() (byte, error) {
+	if len(d.data) == 0 {
+		return 0, fmt.Errorf("%w: unexpected end of data", ErrCorrupt)
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b, nil
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: invalid varint", ErrCorrupt)
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+func (d *decoder) bytes(lth uint64) ([]byte, error) {
+	if uint64(len(d.data)) < lth {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrCorrupt)
+	}
+	rst := d.data[:lth:lth]
+	d.data = d.data[lth:]
+	return rst, nil
+}
+
+// value decodes length-prefixed value encoded by encoder.value.
+func (d *decoder) value() (ValueType, error) {
+	lth, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := d.bytes(lth)
+	if err != nil {
+		return nil, err
+	}
+	if d.decode != nil {
+		return d.decode(encoded)
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	return append([]byte(nil), encoded...), nil
+}
+
+// node decodes the node, path is a part of the key stored by the ancestors.
+func (d *decoder) node(path []byte) (node, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tag == tagEmpty && path == nil:
+		return nil, nil
+	case tag == tagLeaf:
+		return d.leaf(path)
+	case tag >= tagInner && tag < tagInner+kindsCount:
+		return d.inner(int(tag-tagInner), path)
+	}
+	return nil, fmt.Errorf("%w: unknown node tag %d", ErrCorrupt, tag)
+}
+
+func (d *decoder) leaf(path []byte) (node, error) {
+	lth, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := d.bytes(lth)
+	if err != nil {
+		return nil, err
+	}
+	value, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 0, len(path)+len(suffix))
+	key = append(append(key, path...), suffix...)
+	l := d.alloc.leaf()
+	l.key = key
+	l.value = value
+	l.version = d.version
+	d.leaves = append(d.leaves, l)
+	return l, nil
+}
+
+func (d *decoder) inner(kind int, path []byte) (node, error) {
+	lth, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	if int(lth) > maxPrefixLen {
+		return nil, fmt.Errorf("%w: prefix length %d", ErrCorrupt, lth)
+	}
+	prefix, err := d.bytes(uint64(lth))
+	if err != nil {
+		return nil, err
+	}
+	total, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	n := d.alloc.inner()
+	switch kind {
+	case kindNode4:
+		n.node = d.alloc.node4()
+	case kindNode16:
+		n.node = d.alloc.node16()
+	case kindNode48:
+		n.node = d.alloc.node48()
+	default:
+		n.node = d.alloc.node256()
+	}
+	n.prefixLen = int(lth)
+	copy(n.prefix[:], prefix)
+	path = append(path, prefix...)
+	for i := uint64(0); i < total; i++ {
+		b, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if n.node.full() {
+			return nil, fmt.Errorf("%w: too many children for %s", ErrCorrupt, kindNames[kind])
+		}
+		if _, child := n.node.child(b); child != nil {
+			return nil, fmt.Errorf("%w: duplicate edge %x", ErrCorrupt, b)
+		}
+		child, err := d.node(append(path, b))
+		if err != nil {
+			return nil, err
+		}
+		n.node.addChild(b, child)
+	}
+	return n, nil
+}
@@ -0,0 +1,153 @@
+package art
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := New()
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 1+rng.Intn(20))
+		for j := range key {
+			key[j] = byte(rng.Intn(3))
+		}
+		key = append(key, 0xff)
+		tree.Insert(key, key)
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := New()
+	restored.Insert([]byte("previous\x00"), nil)
+	require.NoError(t, restored.UnmarshalBinary(data))
+	require.Equal(t, tree.testView(), restored.testView())
+	require.Equal(t, tree.Len(), restored.Len())
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		value, found := restored.Get(iter.Key())
+		require.True(t, found)
+		require.Equal(t, iter.Value(), value)
+	}
+	_, found := restored.Get([]byte("previous\x00"))
+	require.False(t, found)
+
+	// restored tree remains modifiable
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		restored.Delete(iter.Key())
+	}
+	require.True(t, restored.Empty())
+	require.Equal(t, 0, restored.Len())
+}
+
+func TestMarshalBinaryEmpty(t *testing.T) {
+	var tree Tree
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	var restored Tree
+	restored.Insert([]byte{1}, nil)
+	require.NoError(t, restored.UnmarshalBinary(data))
+	require.True(t, restored.Empty())
+
+	tree.Insert([]byte{1, 2}, []byte{3})
+	data, err = tree.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, restored.UnmarshalBinary(data))
+	value, found := restored.Get([]byte{1, 2})
+	require.True(t, found)
+	require.Equal(t, []byte{3}, value)
+}
+
+func TestMarshalBinaryCodec(t *testing.T) {
+	codec := WithValueCodec(func(dst []byte, value ValueType) []byte {
+		return binary.AppendUvarint(dst, uint64(value.(int)))
+	}, func(data []byte) (ValueType, error) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid value")
+		}
+		return int(v), nil
+	})
+	tree := New(codec)
+	for i := 0; i < 1000; i++ {
+		tree.Insert(sequentialKey(i), i)
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := New(codec)
+	require.NoError(t, restored.UnmarshalBinary(data))
+	for i := 0; i < 1000; i++ {
+		value, found := restored.Get(sequentialKey(i))
+		require.True(t, found)
+		require.Equal(t, i, value)
+	}
+
+	var plain Tree
+	plain.Insert([]byte{1}, 1)
+	_, err = plain.MarshalBinary()
+	require.True(t, errors.Is(err, ErrUnsupportedValue), err)
+}
+
+func TestUnmarshalBinaryCorrupt(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 100; i++ {
+		tree.Insert(sequentialKey(i*7), sequentialKey(i))
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+	for i := 0; i < len(data); i++ {
+		var restored Tree
+		err := restored.UnmarshalBinary(data[:i])
+		require.True(t, errors.Is(err, ErrCorrupt), "length %d: %v", i, err)
+		require.True(t, restored.Empty())
+	}
+	var restored Tree
+	err = restored.UnmarshalBinary(append(data, 0))
+	require.True(t, errors.Is(err, ErrCorrupt), err)
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	var tree Tree
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100_000; i++ {
+		key := make([]byte, 9)
+		rng.Read(key[:8])
+		tree.Insert(key, nil)
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(b, err)
+	b.Run("unmarshal", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var restored Tree
+			_ = restored.UnmarshalBinary(data)
+		}
+	})
+	b.Run("insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var restored Tree
+			for iter := tree.Iterator(nil, nil); iter.Next(); {
+				restored.Insert(iter.Key(), iter.Value())
+			}
+		}
+	})
+}
+
+func TestMarshalBinaryConcurrentModification(t *testing.T) {
+	var tree *Tree
+	tree = New(WithValueCodec(func(dst []byte, value ValueType) []byte {
+		// writer that wasn't stopped before serialization
+		tree.Insert([]byte{2}, 2)
+		return binary.AppendUvarint(dst, uint64(value.(int)))
+	}, nil))
+	tree.Insert([]byte{1}, 1)
+	_, err := tree.MarshalBinary()
+	require.True(t, errors.Is(err, ErrConcurrentModification), err)
+	require.True(t, errors.Is(tree.WriteMmap(io.Discard), ErrConcurrentModification))
+}
@@ -0,0 +1,152 @@
+package art
+
+import "bytes"
+
+// ShardedTree partitions keys across independent trees, writers to different shards
+// never contend on the same nodes, including the root. Shard of the key is selected
+// by the hash of the first prefixLen bytes of the key, keys that share such prefix
+// are stored in the same shard. If shards are created with WithKeyTransform the prefix
+// of the transformed key is hashed, keys that are equal after the transform are stored
+// in the same shard.
+type ShardedTree struct {
+	shards    []*Tree
+	prefixLen int
+}
+
+// NewShardedTree creates a tree with n shards, every shard is created with the options.
+// Limits configured by the options, such as WithSampledEviction, apply to every shard separately.
+func NewShardedTree(n, prefixLen int, opts ...Option) *ShardedTree {
+	if n <= 0 {
+		panic("number of shards must be positive")
+	}
+	t := &ShardedTree{shards: make([]*Tree, n), prefixLen: prefixLen}
+	for i := range t.shards {
+		t.shards[i] = New(opts...)
+	}
+	return t
+}
+
+// shard returns the tree that stores the key, hash is fnv-1a.
+func (t *ShardedTree) shard(key []byte) *Tree {
+	// all shards are created with the same transform
+	key = t.shards[0].keyOf(key)
+	if len(key) > t.prefixLen {
+		key = key[:t.prefixLen]
+	}
+	hash := uint32(2166136261)
+	for _, b := range key {
+		hash ^= uint32(b)
+		hash *= 16777619
+	}
+	return t.shards[hash%uint32(len(t.shards))]
+}
+
+// Shards returns the trees of the shards.
+func (t *ShardedTree) Shards() []*Tree {
+	return t.shards
+}
+
+func (t *ShardedTree) Insert(key []byte, value ValueType) {
+	t.shard(key).Insert(key, value)
+}
+
+func (t *ShardedTree) Get(key []byte) (ValueType, bool) {
+	return t.shard(key).Get(key)
+}
+
+func (t *ShardedTree) Delete(key []byte) {
+	t.shard(key).Delete(key)
+}
+
+// Remove deletes the key and returns value that was stored.
+func (t *ShardedTree) Remove(key []byte) (ValueType, bool) {
+	return t.shard(key).Remove(key)
+}
+
+// Len returns number of keys stored in all shards.
+func (t *ShardedTree) Len() int {
+	total := 0
+	for _, shard := range t.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// Iterator in range (start, end] over all shards, keys are visited in order.
+// Same as the iterator of the Tree it doesn't provide consistent snapshot.
+func (t *ShardedTree) Iterator(start, end []byte) *shardedIterator {
+	iters := make([]*iterator, len(t.shards))
+	for i, shard := range t.shards {
+		iters[i] = shard.Iterator(start, end)
+	}
+	return &shardedIterator{iters: iters, ready: make([]bool, len(iters)), current: -1}
+}
+
+// shardedIterator merges iterators of the shards.
+type shardedIterator struct {
+	iters []*iterator
+	// ready is true if the iterator of the shard is positioned on a key that wasn't visited.
+	ready   []bool
+	started bool
+	reverse bool
+	current int
+}
+
+// Reverse changes direction of the iteration, must be called before Next.
+func (i *shardedIterator) Reverse() *shardedIterator {
+	for _, iter := range i.iters {
+		iter.Reverse()
+	}
+	i.reverse = true
+	return i
+}
+
+func (i *shardedIterator) Next() bool {
+	if !i.started {
+		i.started = true
+		for j, iter := range i.iters {
+			i.ready[j] = iter.Next()
+		}
+	} else if i.current >= 0 {
+		i.ready[i.current] = i.iters[i.current].Next()
+	}
+	i.current = -1
+	for j, iter := range i.iters {
+		if !i.ready[j] {
+			continue
+		}
+		if i.current < 0 {
+			i.current = j
+			continue
+		}
+		cmp := bytes.Compare(iter.Key(), i.iters[i.current].Key())
+		if cmp < 0 && !i.reverse || cmp > 0 && i.reverse {
+			i.current = j
+		}
+	}
+	return i.current >= 0
+}
+
+func (i *shardedIterator) Key() []byte {
+	if i.current < 0 {
+		return nil
+	}
+	return i.iters[i.current].Key()
+}
+
+func (i *shardedIterator) Value() ValueType {
+	if i.current < 0 {
+		return nil
+	}
+	return i.iters[i.current].Value()
+}
+
+// Close releases iterators of the shards, Next returns false afterwards.
+func (i *shardedIterator) Close() {
+	for j, iter := range i.iters {
+		iter.Close()
+		i.ready[j] = false
+	}
+	i.started = true
+	i.current = -1
+}
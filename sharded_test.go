@@ -0,0 +1,117 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedTree(t *testing.T) {
+	tree := NewShardedTree(8, 2)
+	rng := rand.New(rand.NewSource(*seed))
+	keys := map[string]int{}
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 8)
+		rng.Read(key)
+		tree.Insert(key, i)
+		keys[string(key)] = i
+	}
+	require.Equal(t, len(keys), tree.Len())
+	for _, shard := range tree.Shards() {
+		require.NotZero(t, shard.Len())
+	}
+	sorted := make([][]byte, 0, len(keys))
+	for key, value := range keys {
+		rst, found := tree.Get([]byte(key))
+		require.True(t, found)
+		require.Equal(t, value, rst)
+		sorted = append(sorted, []byte(key))
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+
+	t.Run("iterator", func(t *testing.T) {
+		rst := [][]byte{}
+		for iter := tree.Iterator(nil, nil); iter.Next(); {
+			rst = append(rst, iter.Key())
+			require.Equal(t, keys[string(iter.Key())], iter.Value())
+		}
+		require.Equal(t, sorted, rst)
+	})
+	t.Run("reverse", func(t *testing.T) {
+		start, end := sorted[100], sorted[200]
+		rst := [][]byte{}
+		for iter := tree.Iterator(start, end).Reverse(); iter.Next(); {
+			rst = append([][]byte{iter.Key()}, rst...)
+		}
+		require.Equal(t, sorted[100:200], rst)
+	})
+	t.Run("close", func(t *testing.T) {
+		iter := tree.Iterator(nil, nil)
+		require.True(t, iter.Next())
+		iter.Close()
+		require.False(t, iter.Next())
+		require.Nil(t, iter.Key())
+	})
+
+	for key, value := range keys {
+		rst, removed := tree.Remove([]byte(key))
+		require.True(t, removed)
+		require.Equal(t, value, rst)
+	}
+	require.Zero(t, tree.Len())
+}
+
+func TestShardedTreePrefix(t *testing.T) {
+	tree := NewShardedTree(16, 3)
+	for i := 0; i < 100; i++ {
+		tree.Insert([]byte{'a', 'b', 'c', byte(i)}, i)
+	}
+	nonempty := 0
+	for _, shard := range tree.Shards() {
+		if shard.Len() > 0 {
+			nonempty++
+		}
+	}
+	require.Equal(t, 1, nonempty)
+}
+
+func TestShardedTreeKeyTransform(t *testing.T) {
+	tree := NewShardedTree(3, 1, WithKeyTransform(bytes.ToLower))
+	for c := byte('A'); c <= 'Z'; c++ {
+		tree.Insert([]byte{c, 'x', 0}, int(c))
+	}
+	for c := byte('a'); c <= 'z'; c++ {
+		value, found := tree.Get([]byte{c, 'x', 0})
+		require.True(t, found, "key %c", c)
+		require.Equal(t, int(c-'a'+'A'), value)
+	}
+	require.Equal(t, 26, tree.Len())
+}
+
+func BenchmarkShardedInserts(b *testing.B) {
+	skipUnsafe(b)
+	for _, tc := range []struct {
+		desc   string
+		shards int
+	}{
+		{"1", 1},
+		{"16", 16},
+	} {
+		b.Run(tc.desc, func(b *testing.B) {
+			tree := NewShardedTree(tc.shards, 4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rng := rand.New(rand.NewSource(rand.Int63()))
+				for pb.Next() {
+					key := randomKey(rng)
+					tree.Insert(key[:], 1)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package art
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Layout of the directory written by Checkpoint. Every shard is serialized by MarshalBinary
+// into its own file, manifest is replaced after all shard files are synced, so that
+// the directory always holds a complete checkpoint.
+//
+//	MANIFEST:   magic | version byte | uvarint generation | uvarint prefix length |
+//	            uvarint number of shards | (crc32c of the file, little endian)... |
+//	            crc32c of the preceding bytes, little endian
+//	shard file: shard-<generation>-<index>
+const (
+	manifestMagic   = "artm"
+	manifestVersion = 1
+	manifestName    = "MANIFEST"
+)
+
+// manifest describes the shard files of a single checkpoint.
+type manifest struct {
+	generation uint64
+	prefixLen  int
+	// checksums of the shard files, crc32c.
+	checksums []uint32
+}
+
+func shardFileName(generation uint64, i int) string {
+	return fmt.Sprintf("shard-%d-%d", generation, i)
+}
+
+// Checkpoint writes every shard into its own file in dir, shards are serialized concurrently.
+// Manifest with the checksum of every shard file is written last,
+// and replaces the manifest of the previous checkpoint, shard files of the previous
+// checkpoint are removed afterwards. Checkpoint that failed leaves the previous one intact.
+// Same as for MarshalBinary concurrent writers must be stopped.
+func (t *ShardedTree) Checkpoint(dir string) error {
+	var generation uint64
+	previous, err := readManifest(dir)
+	switch {
+	case err == nil:
+		generation = previous.generation + 1
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	m := manifest{generation: generation, prefixLen: t.prefixLen, checksums: make([]uint32, len(t.shards))}
+	err = t.parallel(func(i int, shard *Tree) error {
+		data, err := shard.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		m.checksums[i] = crc32.Checksum(data, castagnoli)
+		return writeFile(filepath.Join(dir, shardFileName(generation, i)), data)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, manifestName), m.encode()); err != nil {
+		return err
+	}
+	if previous != nil {
+		for i := range previous.checksums {
+			_ = os.Remove(filepath.Join(dir, shardFileName(previous.generation, i)))
+		}
+	}
+	return nil
+}
+
+// Restore replaces content of every shard with the checkpoint in dir, shards are restored
+// concurrently. Checkpoint must be written by the tree with the same number of shards
+// and prefix length, otherwise ErrCorrupt is returned. If restore of some shards failed
+// they keep previous content and can be restored one by one with RestoreShard.
+func (t *ShardedTree) Restore(dir string) error {
+	m, err := t.manifest(dir)
+	if err != nil {
+		return err
+	}
+	return t.parallel(func(i int, _ *Tree) error {
+		return t.restoreShard(dir, m, i)
+	})
+}
+
+// RestoreShard replaces content of the shard i with its file from the checkpoint in dir,
+// other shards are not modified.
+func (t *ShardedTree) RestoreShard(dir string, i int) error {
+	m, err := t.manifest(dir)
+	if err != nil {
+		return err
+	}
+	if i < 0 || i >= len(t.shards) {
+		return fmt.Errorf("shard %d is out of range [0, %d)", i, len(t.shards))
+	}
+	return t.restoreShard(dir, m, i)
+}
+
+// manifest reads the manifest from dir and checks that it matches the tree.
+func (t *ShardedTree) manifest(dir string) (*manifest, error) {
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.checksums) != len(t.shards) || m.prefixLen != t.prefixLen {
+		return nil, fmt.Errorf("%w: checkpoint with %d shards and prefix length %d",
+			ErrCorrupt, len(m.checksums), m.prefixLen)
+	}
+	return m, nil
+}
+
+func (t *ShardedTree) restoreShard(dir string, m *manifest, i int) error {
+	data, err := os.ReadFile(filepath.Join(dir, shardFileName(m.generation, i)))
+	if err != nil {
+		return err
+	}
+	if crc32.Checksum(data, castagnoli) != m.checksums[i] {
+		return fmt.Errorf("%w: checksum mismatch in shard %d", ErrCorrupt, i)
+	}
+	if err := t.shards[i].UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("shard %d: %w", i, err)
+	}
+	return nil
+}
+
+// parallel calls fn for every shard concurrently and returns the first error.
+func (t *ShardedTree) parallel(fn func(i int, shard *Tree) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(t.shards))
+	)
+	for i, shard := range t.shards {
+		wg.Add(1)
+		go func(i int, shard *Tree) {
+			defer wg.Done()
+			errs[i] = fn(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manifest) encode() []byte {
+	buf := append([]byte(manifestMagic), manifestVersion)
+	buf = binary.AppendUvarint(buf, m.generation)
+	buf = binary.AppendUvarint(buf, uint64(m.prefixLen))
+	buf = binary.AppendUvarint(buf, uint64(len(m.checksums)))
+	for _, checksum := range m.checksums {
+		buf = binary.LittleEndian.AppendUint32(buf, checksum)
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli))
+}
+
+// readManifest reads the manifest from dir, error wraps os.ErrNotExist if there is none.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	header := len(manifestMagic) + 1
+	if len(data) < header+4 || string(data[:len(manifestMagic)]) != manifestMagic {
+		return nil, fmt.Errorf("%w: invalid manifest header", ErrCorrupt)
+	}
+	if version := data[len(manifestMagic)]; version != manifestVersion {
+		return nil, fmt.Errorf("%w: unsupported manifest version %d", ErrCorrupt, version)
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, castagnoli) != sum {
+		return nil, fmt.Errorf("%w: manifest checksum mismatch", ErrCorrupt)
+	}
+	body = body[header:]
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(body)
+		if n <= 0 {
+			err = fmt.Errorf("%w: invalid manifest", ErrCorrupt)
+			return 0
+		}
+		body = body[n:]
+		return v
+	}
+	m := &manifest{generation: uvarint(), prefixLen: int(uvarint())}
+	count := uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(body)/4) {
+		return nil, fmt.Errorf("%w: %d shards in manifest", ErrCorrupt, count)
+	}
+	m.checksums = make([]uint32, count)
+	for i := range m.checksums {
+		m.checksums[i] = binary.LittleEndian.Uint32(body)
+		body = body[4:]
+	}
+	if len(body) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes in manifest", ErrCorrupt)
+	}
+	return m, nil
+}
+
+// writeFile writes data into a temporary file in the same directory, syncs it and renames
+// it to path, so that path holds either previous or complete content.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
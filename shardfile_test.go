@@ -0,0 +1,58 @@
+package art
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedTreeCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	tree := NewShardedTree(4, 2)
+	for i := 0; i < 1000; i++ {
+		tree.Insert(sequentialKey(i), sequentialKey(i))
+	}
+	require.NoError(t, tree.Checkpoint(dir))
+	tree.Delete(sequentialKey(0))
+	// second checkpoint replaces the files of the first one
+	require.NoError(t, tree.Checkpoint(dir))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"MANIFEST", "shard-1-0", "shard-1-1", "shard-1-2", "shard-1-3"}, names)
+
+	restored := NewShardedTree(4, 2)
+	require.NoError(t, restored.Restore(dir))
+	require.Equal(t, 999, restored.Len())
+	for i := 1; i < 1000; i++ {
+		value, found := restored.Get(sequentialKey(i))
+		require.True(t, found)
+		require.Equal(t, sequentialKey(i), value)
+	}
+
+	// corrupted shard is reported, other shards are restored one by one
+	corrupted := filepath.Join(dir, "shard-1-2")
+	data, err := os.ReadFile(corrupted)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(corrupted, data, 0o644))
+	partial := NewShardedTree(4, 2)
+	require.True(t, errors.Is(partial.Restore(dir), ErrCorrupt))
+	require.True(t, errors.Is(partial.RestoreShard(dir, 2), ErrCorrupt))
+	require.Zero(t, partial.Shards()[2].Len())
+	for _, i := range []int{0, 1, 3} {
+		require.NoError(t, partial.RestoreShard(dir, i))
+		require.Equal(t, restored.Shards()[i].Len(), partial.Shards()[i].Len())
+	}
+
+	require.True(t, errors.Is(NewShardedTree(2, 2).Restore(dir), ErrCorrupt))
+	require.True(t, errors.Is(NewShardedTree(4, 2).Restore(t.TempDir()), os.ErrNotExist))
+}
@@ -0,0 +1,220 @@
+package art
+
+import (
+	"bytes"
+	"sort"
+)
+
+// snapshotAttempts is a number of optimistic collections before writers are blocked
+// by the snapshot.
+const snapshotAttempts = 3
+
+// Consistent makes the iterator visit the keys that were stored at a single point in time,
+// concurrent inserts and deletes never cause skipped or duplicated keys. Must be called before Next.
+// Leaves of the range are collected on the first Next, collection is retried while
+// concurrent writers modify the nodes of the range, after several attempts writers are blocked
+// until collection completes. Memory proportional to the number of keys in the range is used.
+func (i *iterator) Consistent() *iterator {
+	i.consistent = true
+	return i
+}
+
+// GetAllAtSnapshot looks up keys and returns results in the order of the keys, as they
+// were stored at a single point in time. Keys modified together by Txn.Commit are observed
+// either before or after the commit. Results are written into out, which is reallocated
+// if it is shorter than keys. Paths of the keys are collected and validated together,
+// same as the leaves of Consistent iterator, after several attempts writers are blocked
+// until collection completes. Expired keys are not found, but are not removed.
+func (t *Tree) GetAllAtSnapshot(keys [][]byte, out []Result) []Result {
+	t.checkPoisoned()
+	if len(out) < len(keys) {
+		out = make([]Result, len(keys))
+	}
+	out = out[:len(keys)]
+	ranges := make([]Range, len(keys))
+	for i, key := range keys {
+		key = t.keyOf(key)
+		ranges[i] = Range{Start: key, End: key, Inclusivity: IncludeBoth}
+	}
+	// empty key is an unbounded range, leaves are matched to the keys by equality
+	found := map[string]*leaf{}
+	for _, l := range t.snapshot(ranges...) {
+		found[string(l.key)] = l
+	}
+	for i, r := range ranges {
+		out[i] = Result{}
+		if l := found[string(r.Start)]; l != nil && !t.expired(l) {
+			out[i] = Result{Value: l.value, Found: true}
+		}
+	}
+	return out
+}
+
+// nextSnapshot visits the next leaf of the snapshot in the direction of the iteration.
+func (i *iterator) nextSnapshot() bool {
+	if i.snapshot == nil {
+		i.snapshot = i.tree.snapshot(i.bounds())
+		i.seekSnapshot()
+	}
+	for i.pos >= 0 && i.pos < len(i.snapshot) {
+		l := i.snapshot[i.pos]
+		if i.reverse {
+			i.pos--
+		} else {
+			i.pos++
+		}
+		if i.inRange(l.key) {
+			i.visit(l)
+			i.cursor = l.key
+			i.inclusive = false
+			return true
+		}
+	}
+	i.closed = true
+	return false
+}
+
+// seekSnapshot positions the iterator on the first leaf of the snapshot after the cursor.
+func (i *iterator) seekSnapshot() {
+	if !i.reverse {
+		i.pos = sort.Search(len(i.snapshot), func(j int) bool {
+			return bytes.Compare(i.snapshot[j].key, i.cursor) >= 0
+		})
+		return
+	}
+	if len(i.cursor) == 0 {
+		i.pos = len(i.snapshot) - 1
+		return
+	}
+	i.pos = sort.Search(len(i.snapshot), func(j int) bool {
+		return bytes.Compare(i.snapshot[j].key, i.cursor) > 0
+	}) - 1
+}
+
+// bounds returns the range that includes every key visited by the iterator.
+func (i *iterator) bounds() Range {
+	r := Range{Start: i.cursor, End: i.terminate, Inclusivity: IncludeBoth}
+	if i.reverse {
+		r.Start, r.End = r.End, r.Start
+	}
+	if i.prefix != nil {
+		r = r.Intersect(PrefixRange(i.prefix))
+	}
+	return r
+}
+
+// snapshot returns leaves in the ranges, as they were stored at a single point in time.
+// Leaves of every range are in ascending order, ranges are collected one after another.
+// Leaves are immutable, collected leaves are consistent if none of the visited nodes was modified.
+func (t *Tree) snapshot(ranges ...Range) []*leaf {
+	// versions are not validated by the pessimistic lock, tree must be locked
+	for attempt := 0; optimistic && attempt < snapshotAttempts; attempt++ {
+		if leaves, ok := t.collect(ranges, false); ok {
+			return leaves
+		}
+	}
+	// writers that passed the root before it was locked may still modify the nodes,
+	// new writers wait until the collection completes
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for {
+		if leaves, ok := t.collect(ranges, true); ok {
+			return leaves
+		}
+	}
+}
+
+// collect returns leaves in the ranges and true if none of the nodes was modified
+// during the collection. If locked is true the caller holds the lock of the tree.
+func (t *Tree) collect(ranges []Range, locked bool) ([]*leaf, bool) {
+	c := collector{leaves: []*leaf{}}
+	var root node
+	if locked {
+		root = t.root
+	} else {
+		version, _ := t.lock.RLock()
+		root = t.root
+		if t.lock.RUnlock(version, nil) {
+			return nil, false
+		}
+		c.path = append(c.path, observed{lock: &t.lock, version: version})
+	}
+	for _, r := range ranges {
+		c.Range = r
+		switch root := root.(type) {
+		case *leaf:
+			if r.Contains(root.key) {
+				c.leaves = append(c.leaves, root)
+			}
+		case *inner:
+			if !c.collect(root, nil) {
+				return nil, false
+			}
+		}
+	}
+	for _, o := range c.path {
+		if o.lock.Check(o.version) {
+			return nil, false
+		}
+	}
+	return c.leaves, true
+}
+
+// edge is a child of the inner node.
+type edge struct {
+	b     byte
+	child node
+}
+
+// collector accumulates leaves in the range and versions of the visited nodes.
+type collector struct {
+	Range
+	leaves []*leaf
+	path   []observed
+	// edges is a stack of children of the visited nodes.
+	edges []edge
+}
+
+func (c *collector) collect(n *inner, path []byte) bool {
+	version, obsolete := n.lock.RLock()
+	if obsolete {
+		_ = n.lock.RUnlock(version, nil)
+		return false
+	}
+	path = append(path, n.prefix[:n.prefixLen]...)
+	start := len(c.edges)
+	var pointer *byte
+	for {
+		b, child := n.node.next(pointer)
+		if child == nil {
+			break
+		}
+		c.edges = append(c.edges, edge{b: b, child: child})
+		pointer = &b
+	}
+	end := len(c.edges)
+	if n.lock.RUnlock(version, nil) {
+		return false
+	}
+	c.path = append(c.path, observed{lock: &n.lock, version: version})
+	for j := start; j < end; j++ {
+		e := c.edges[j]
+		prefix := append(path, e.b)
+		if c.disjoint(prefix) {
+			continue
+		}
+		switch child := e.child.(type) {
+		case *leaf:
+			if c.Contains(child.key) {
+				c.leaves = append(c.leaves, child)
+			}
+		case *inner:
+			if !c.collect(child, prefix) {
+				return false
+			}
+		}
+		c.edges = c.edges[:end]
+	}
+	c.edges = c.edges[:start]
+	return true
+}
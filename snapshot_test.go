@@ -0,0 +1,135 @@
+package art
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorConsistent(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{}
+	for i := 0; i < 1000; i++ {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, uint32(i*3))
+		tree.Insert(key, i)
+		keys = append(keys, key)
+	}
+	for _, tc := range []struct {
+		desc     string
+		iter     *iterator
+		expected [][]byte
+	}{
+		{"all", tree.Iterator(nil, nil), keys},
+		{"range", tree.Iterator(keys[10], keys[20]), keys[11:21]},
+		{"scan", tree.Scan(Range{Start: keys[10], End: keys[20], Inclusivity: IncludeStart}), keys[10:20]},
+		{"prefix", tree.Prefix([]byte{0, 0, 1}), keys[86:171]},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			var rst [][]byte
+			for iter := tc.iter.Consistent(); iter.Next(); {
+				rst = append(rst, iter.Key())
+			}
+			require.Equal(t, tc.expected, rst)
+		})
+	}
+	t.Run("reverse", func(t *testing.T) {
+		var rst [][]byte
+		for iter := tree.ReverseIterator(keys[10], keys[20]).Consistent(); iter.Next(); {
+			rst = append([][]byte{iter.Key()}, rst...)
+		}
+		require.Equal(t, keys[11:21], rst)
+	})
+	t.Run("seek", func(t *testing.T) {
+		iter := tree.Iterator(nil, nil).Consistent()
+		require.True(t, iter.Next())
+		iter.Seek(keys[500])
+		require.True(t, iter.Next())
+		require.Equal(t, keys[500], iter.Key())
+		require.True(t, iter.Next())
+		require.Equal(t, keys[501], iter.Key())
+	})
+}
+
+func TestIteratorConsistentConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	// keys are inserted in random order, consistent snapshot must contain
+	// every key inserted before the last visible one
+	var tree Tree
+	rng := rand.New(rand.NewSource(*seed))
+	order := rng.Perm(20_000)
+	position := map[uint32]int{}
+	for i, key := range order {
+		position[uint32(key)] = i
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, key := range order {
+			var buf [4]byte
+			binary.BigEndian.PutUint32(buf[:], uint32(key))
+			tree.Insert(buf[:], key)
+		}
+	}()
+	for done := false; !done; {
+		count, last := 0, -1
+		for iter := tree.Iterator(nil, nil).Consistent(); iter.Next(); {
+			count++
+			if pos := position[binary.BigEndian.Uint32(iter.Key())]; pos > last {
+				last = pos
+			}
+		}
+		require.Equal(t, last+1, count)
+		done = count == len(order)
+	}
+	wg.Wait()
+}
+
+func TestGetAllAtSnapshot(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 1000; i++ {
+		tree.Insert(sequentialKey(i*2), i)
+	}
+	keys := [][]byte{sequentialKey(10), sequentialKey(3), sequentialKey(10), sequentialKey(1998), nil}
+	out := tree.GetAllAtSnapshot(keys, nil)
+	require.Equal(t, []Result{{Value: 5, Found: true}, {}, {Value: 5, Found: true}, {Value: 999, Found: true}, {}}, out)
+}
+
+func TestGetAllAtSnapshotTxn(t *testing.T) {
+	skipUnsafe(t)
+	// every commit updates all keys to the same value, reader must never observe a mix
+	var tree Tree
+	keys := make([][]byte, 5000)
+	for i := range keys {
+		keys[i] = sequentialKey(i * 1000)
+		tree.Insert(keys[i], 0)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 100; i++ {
+			txn := tree.Begin()
+			for _, key := range keys {
+				txn.Insert(key, i)
+			}
+			// unrelated writes modify the same nodes outside of the transaction
+			tree.Insert(sequentialKey(i*1000+1), i)
+			require.NoError(t, txn.Commit())
+		}
+	}()
+	var out []Result
+	for done := false; !done; {
+		out = tree.GetAllAtSnapshot(keys, out)
+		for _, rst := range out {
+			require.Equal(t, out[0], rst)
+		}
+		done = out[0].Value == 100
+	}
+	wg.Wait()
+}
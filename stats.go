@@ -0,0 +1,267 @@
+package art
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Stats describes shape of the tree.
+type Stats struct {
+	// Keys is a number of keys, maintained on every modification.
+	Keys int
+	// KeyBytes is a total length of the keys.
+	KeyBytes int
+	// Nodes is a number of inner nodes of every kind.
+	Nodes [kindsCount]int
+	// Children is a number of children of the inner nodes of every kind.
+	Children [kindsCount]int
+	// PrefixBytes is a total length of the prefixes of the inner nodes.
+	PrefixBytes int
+	// Height is a number of inner nodes on the longest path from the root to a leaf.
+	// Sampled stats report the longest sampled path.
+	Height int
+	// Exact is true if stats were collected by visiting every node.
+	Exact bool
+}
+
+// Inner returns a number of inner nodes of all kinds.
+func (s Stats) Inner() int {
+	total := 0
+	for _, n := range s.Nodes {
+		total += n
+	}
+	return total
+}
+
+// AvgPrefix returns an average length of the prefix of the inner node.
+func (s Stats) AvgPrefix() float64 {
+	inner := s.Inner()
+	if inner == 0 {
+		return 0
+	}
+	return float64(s.PrefixBytes) / float64(inner)
+}
+
+// nodeSizes is a size of the inner node of every kind, including the header.
+var nodeSizes = [kindsCount]uint64{
+	uint64(unsafe.Sizeof(inner{}) + unsafe.Sizeof(node4{})),
+	uint64(unsafe.Sizeof(inner{}) + unsafe.Sizeof(node16{})),
+	uint64(unsafe.Sizeof(inner{}) + unsafe.Sizeof(node48{})),
+	uint64(unsafe.Sizeof(inner{}) + unsafe.Sizeof(node256{})),
+}
+
+// Footprint estimates bytes used by inner nodes, leaves and keys. Values and
+// allocator overhead are not included.
+func (s Stats) Footprint() uint64 {
+	total := uint64(s.Keys)*uint64(leafOverhead) + uint64(s.KeyBytes)
+	for kind, n := range s.Nodes {
+		total += uint64(n) * nodeSizes[kind]
+	}
+	return total
+}
+
+// MemoryFootprint estimates bytes used by the tree, see Stats.Footprint.
+// Every node is visited, Stats(samples).Footprint() is a cheaper estimate for large trees.
+func (t *Tree) MemoryFootprint() uint64 {
+	return t.ExactStats().Footprint()
+}
+
+// Stats estimates number of inner nodes and size of keys using random descents from the root,
+// without visiting the whole tree. Keys are counted exactly.
+// At every inner node descent follows one of the children with equal probability,
+// every visited node is weighted by the inverse of the probability to visit it.
+func (t *Tree) Stats(samples int) Stats {
+	var (
+		nodes       [kindsCount]float64
+		childs      [kindsCount]float64
+		keyBytes    float64
+		prefixBytes float64
+		height      int
+		sampled     int
+	)
+	for i := 0; i < samples; i++ {
+		depth := 0
+		if !t.descendRandom(func(kind, prefixLen, children int, weight float64) {
+			nodes[kind] += weight
+			childs[kind] += weight * float64(children)
+			prefixBytes += weight * float64(prefixLen)
+			depth++
+		}, func(l *leaf, weight float64) {
+			keyBytes += weight * float64(len(l.key))
+		}) {
+			break
+		}
+		sampled++
+		if depth > height {
+			height = depth
+		}
+	}
+	stats := Stats{Keys: int(atomic.LoadInt64(&t.size))}
+	if sampled == 0 {
+		return stats
+	}
+	for kind, n := range nodes {
+		stats.Nodes[kind] = int(n/float64(sampled) + 0.5)
+		stats.Children[kind] = int(childs[kind]/float64(sampled) + 0.5)
+	}
+	stats.KeyBytes = int(keyBytes/float64(sampled) + 0.5)
+	stats.PrefixBytes = int(prefixBytes/float64(sampled) + 0.5)
+	stats.Height = height
+	return stats
+}
+
+// ExactStats visits every node of the tree. It is safe to use concurrently with writers,
+// every node is observed in a consistent state, but nodes modified during the walk
+// may be reported before or after modification.
+func (t *Tree) ExactStats() Stats {
+	stats := Stats{Keys: int(atomic.LoadInt64(&t.size)), Exact: true}
+	load := func() node {
+		for {
+			version, _ := t.lock.RLock()
+			root := t.root
+			if !t.lock.RUnlock(version, nil) {
+				return root
+			}
+		}
+	}
+	if root := load(); root != nil {
+		stats.visit(root, 0, load)
+	}
+	return stats
+}
+
+// visit adds the node and its descendants to stats. Children of the inner node are
+// collected under the read lock, that is retried until node is read without modifications.
+// Obsolete node was replaced concurrently, replacement is loaded from the parent.
+func (s *Stats) visit(n node, height int, load func() node) {
+	var (
+		edges     []byte
+		childs    []node
+		kind      int
+		prefixLen int
+	)
+	for {
+		in, isInner := n.(*inner)
+		if !isInner {
+			if l, isLeaf := n.(*leaf); isLeaf {
+				s.KeyBytes += len(l.key)
+			}
+			return
+		}
+		version, obsolete := in.lock.RLock()
+		if obsolete {
+			if n = load(); n == nil {
+				return
+			}
+			continue
+		}
+		kind, prefixLen = kindOf(in.node), in.prefixLen
+		edges, childs = edges[:0], childs[:0]
+		var pointer *byte
+		for {
+			k, child := in.node.next(pointer)
+			if child == nil {
+				break
+			}
+			edges = append(edges, k)
+			childs = append(childs, child)
+			pointer = &k
+		}
+		if !in.lock.RUnlock(version, nil) {
+			break
+		}
+	}
+	in := n.(*inner)
+	height++
+	if height > s.Height {
+		s.Height = height
+	}
+	s.Nodes[kind]++
+	s.Children[kind] += len(childs)
+	s.PrefixBytes += prefixLen
+	for i, child := range childs {
+		edge := edges[i]
+		s.visit(child, height, func() node {
+			return in.load(edge)
+		})
+	}
+}
+
+// load returns the child at the edge, or nil if node is obsolete.
+func (n *inner) load(edge byte) node {
+	for {
+		version, obsolete := n.lock.RLock()
+		if obsolete {
+			return nil
+		}
+		_, child := n.node.child(edge)
+		if !n.lock.RUnlock(version, nil) {
+			return child
+		}
+	}
+}
+
+// descendRandom follows random children from the root to the leaf. Visited nodes
+// are passed to callbacks with the inverse of the probability to visit them.
+// Returns false if tree is empty.
+func (t *Tree) descendRandom(onInner func(kind, prefixLen, children int, weight float64), onLeaf func(*leaf, float64)) bool {
+	var (
+		kinds    []int
+		prefixes []int
+		totals   []int
+		weights  []float64
+	)
+restart:
+	kinds, prefixes, totals, weights = kinds[:0], prefixes[:0], totals[:0], weights[:0]
+	weight := 1.0
+	version, _ := t.lock.RLock()
+	parent := &t.lock
+	next := t.root
+	for {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l, _ := next.(*leaf)
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			if l == nil {
+				return false
+			}
+			for i, kind := range kinds {
+				onInner(kind, prefixes[i], totals[i], weights[i])
+			}
+			onLeaf(l, weight)
+			return true
+		}
+		nversion, obsolete := n.lock.RLock()
+		if obsolete || parent.RUnlock(version, nil) {
+			goto restart
+		}
+		total, _ := children(n.node, 0)
+		if total == 0 {
+			_ = n.lock.RUnlock(nversion, nil)
+			goto restart
+		}
+		kinds = append(kinds, kindOf(n.node))
+		prefixes = append(prefixes, n.prefixLen)
+		totals = append(totals, total)
+		weights = append(weights, weight)
+		weight *= float64(total)
+		next = nth(n.node, rand.Intn(total))
+		parent, version = &n.lock, nversion
+	}
+}
+
+// nth returns child at position i in the order of the keys.
+func nth(n inode, i int) node {
+	var pointer *byte
+	for {
+		k, child := n.next(pointer)
+		if child == nil || i == 0 {
+			return child
+		}
+		i--
+		pointer = &k
+	}
+}
@@ -0,0 +1,105 @@
+package art
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	var tree Tree
+	require.Equal(t, Stats{Exact: true}, tree.ExactStats())
+	require.Equal(t, Stats{}, tree.Stats(10))
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		key := make([]byte, 4+rng.Intn(8))
+		rng.Read(key)
+		tree.Insert(key, i)
+	}
+	exact := tree.ExactStats()
+	require.Equal(t, tree.Len(), exact.Keys)
+
+	approx := tree.Stats(2000)
+	require.False(t, approx.Exact)
+	require.Equal(t, exact.Keys, approx.Keys)
+	require.InEpsilon(t, exact.KeyBytes, approx.KeyBytes, 0.1)
+	for kind, n := range exact.Nodes {
+		if n > 100 {
+			require.InEpsilon(t, n, approx.Nodes[kind], 0.2, kindNames[kind])
+		}
+	}
+}
+
+func TestStatsShape(t *testing.T) {
+	var tree Tree
+	// root with prefix {1} and two children with prefixes {2, 3}
+	for _, key := range [][]byte{{1, 1, 2, 3, 1}, {1, 1, 2, 3, 2}, {1, 2, 2, 3, 1}, {1, 2, 2, 3, 2}} {
+		tree.Insert(key, nil)
+	}
+	stats := tree.ExactStats()
+	require.Equal(t, 3, stats.Inner())
+	require.Equal(t, 3, stats.Nodes[kindNode4])
+	require.Equal(t, 2, stats.Height)
+	require.Equal(t, 5, stats.PrefixBytes)
+	require.InDelta(t, 5.0/3, stats.AvgPrefix(), 0.001)
+
+	approx := tree.Stats(100)
+	require.Equal(t, 2, approx.Height)
+	require.Equal(t, 5, approx.PrefixBytes)
+}
+
+func TestExactStatsConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	for i := 0; i < 10_000; i += 2 {
+		tree.Insert(sequentialKey(i), i)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i < 10_000; i += 2 {
+			tree.Insert(sequentialKey(i), i)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			stats := tree.ExactStats()
+			require.Equal(t, 10_000, stats.Keys)
+			require.Equal(t, 10_000*len(sequentialKey(0)), stats.KeyBytes)
+			return
+		default:
+		}
+		stats := tree.ExactStats()
+		require.GreaterOrEqual(t, stats.KeyBytes, 5_000*len(sequentialKey(0)))
+	}
+}
+
+func TestMemoryFootprint(t *testing.T) {
+	var tree Tree
+	require.Zero(t, tree.MemoryFootprint())
+
+	keys := make([][]byte, 100_000)
+	for i := range keys {
+		keys[i] = sequentialKey(i)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for _, key := range keys {
+		tree.Insert(key, nil)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	used := after.HeapAlloc - before.HeapAlloc
+
+	footprint := tree.MemoryFootprint()
+	// keys were allocated before the measurement
+	footprint -= uint64(len(keys) * len(keys[0]))
+	require.InEpsilon(t, used, footprint, 0.15)
+	require.InEpsilon(t, tree.MemoryFootprint(), tree.Stats(1000).Footprint(), 0.1)
+	runtime.KeepAlive(keys)
+}
@@ -0,0 +1,103 @@
+package art
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
+
+const sketchDepth = 4
+
+// WithTinyLFU enables TinyLFU admission for the tree with eviction.
+// Frequency of the accessed keys is tracked by count-min sketch with the given number
+// of counters per row. When the tree is full a new key is admitted only if it was
+// accessed more frequently than the eviction victim, therefore a single scan over
+// rarely used keys can't flush frequently used keys from the tree.
+// Has no effect if eviction is not enabled.
+func WithTinyLFU(counters int) Option {
+	return func(t *Tree) {
+		t.admission = newSketch(counters)
+	}
+}
+
+// sketch is a count-min sketch with periodic aging. When the number of recorded
+// accesses reaches the sample size all counters are halved, so that recent
+// accesses have more weight.
+type sketch struct {
+	width   uint64
+	rows    [sketchDepth][]uint32
+	records uint64
+	sample  uint64
+}
+
+func newSketch(width int) *sketch {
+	if width < 1 {
+		width = 1
+	}
+	s := &sketch{
+		width:  uint64(width),
+		sample: 10 * uint64(width),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint32, width)
+	}
+	return s
+}
+
+func (s *sketch) hash(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return h.Sum64()
+}
+
+func (s *sketch) index(h uint64, row int) uint64 {
+	return (h + uint64(row)*(h>>32|1)) % s.width
+}
+
+// record increments frequency of the key.
+func (s *sketch) record(key []byte) {
+	h := s.hash(key)
+	for i := range s.rows {
+		atomic.AddUint32(&s.rows[i][s.index(h, i)], 1)
+	}
+	if atomic.AddUint64(&s.records, 1)%s.sample == 0 {
+		s.age()
+	}
+}
+
+// estimate returns approximate frequency of the key.
+func (s *sketch) estimate(key []byte) uint32 {
+	h := s.hash(key)
+	var min uint32
+	for i := range s.rows {
+		count := atomic.LoadUint32(&s.rows[i][s.index(h, i)])
+		if i == 0 || count < min {
+			min = count
+		}
+	}
+	return min
+}
+
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			count := atomic.LoadUint32(&s.rows[i][j])
+			atomic.StoreUint32(&s.rows[i][j], count/2)
+		}
+	}
+}
+
+// admit returns true if a new key should be inserted into the tree, and the victim
+// that should be evicted to make room for it.
+func (t *Tree) admit(key []byte) (bool, *leaf) {
+	if !t.evictor.full(t) {
+		return true, nil
+	}
+	victim := t.evictor.victim(t)
+	if victim == nil {
+		return true, nil
+	}
+	if t.admission.estimate(key) > t.admission.estimate(victim.key) {
+		return true, victim
+	}
+	return false, nil
+}
@@ -0,0 +1,123 @@
+package art
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// tokenVersion is the first byte of the token, must be incremented on incompatible changes.
+const tokenVersion = 1
+
+// Flags of the iterator state stored in the token.
+const (
+	tokenReverse byte = 1 << iota
+	tokenInclusive
+	tokenExclusiveEnd
+	tokenRanged
+	tokenPrefix
+	tokenClosed
+	tokenConsistent
+	tokenKeysOnly
+)
+
+// Token returns an opaque position of the iterator, iterator created by Tree.IteratorFrom
+// continues after the last visited key with the same range and direction.
+// Filter of the iterator created by Match is not stored in the token.
+//
+// Format:
+//
+//	version byte | flags byte | uvarint length | cursor | uvarint length | terminate | [uvarint length | prefix]
+func (i *iterator) Token() []byte {
+	var flags byte
+	for _, f := range []struct {
+		set  bool
+		flag byte
+	}{
+		{i.reverse, tokenReverse},
+		{i.inclusive, tokenInclusive},
+		{i.exclusiveEnd, tokenExclusiveEnd},
+		{i.ranged, tokenRanged},
+		{i.prefix != nil, tokenPrefix},
+		{i.closed && i.err == nil && !i.released, tokenClosed},
+		{i.consistent, tokenConsistent},
+		{i.keysOnly, tokenKeysOnly},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+	token := []byte{tokenVersion, flags}
+	token = binary.AppendUvarint(token, uint64(len(i.cursor)))
+	token = append(token, i.cursor...)
+	token = binary.AppendUvarint(token, uint64(len(i.terminate)))
+	token = append(token, i.terminate...)
+	if i.prefix != nil {
+		token = binary.AppendUvarint(token, uint64(len(i.prefix)))
+		token = append(token, i.prefix...)
+	}
+	return token
+}
+
+// IteratorFrom creates iterator from the token returned by Token. Iterator doesn't visit
+// keys that were visited before the token was created, keys inserted before the position
+// of the token are not visited either. ErrCorrupt is returned if token is malformed.
+func (t *Tree) IteratorFrom(token []byte) (*iterator, error) {
+	t.checkPoisoned()
+	d := decoder{data: token}
+	version, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	if version != tokenVersion {
+		return nil, fmt.Errorf("%w: unsupported token version %d", ErrCorrupt, version)
+	}
+	flags, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := d.key()
+	if err != nil {
+		return nil, err
+	}
+	terminate, err := d.key()
+	if err != nil {
+		return nil, err
+	}
+	iter := &iterator{
+		tree:         t,
+		cursor:       cursor,
+		terminate:    terminate,
+		begin:        cursor,
+		reverse:      flags&tokenReverse != 0,
+		inclusive:    flags&tokenInclusive != 0,
+		exclusiveEnd: flags&tokenExclusiveEnd != 0,
+		ranged:       flags&tokenRanged != 0,
+		closed:       flags&tokenClosed != 0,
+		consistent:   flags&tokenConsistent != 0,
+		keysOnly:     flags&tokenKeysOnly != 0,
+	}
+	if flags&tokenPrefix != 0 {
+		prefix, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		iter.prefix = append([]byte{}, prefix...)
+	}
+	if len(d.data) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes in token", ErrCorrupt, len(d.data))
+	}
+	return iter, nil
+}
+
+// key decodes length prefixed key, the key is copied.
+func (d *decoder) key() ([]byte, error) {
+	lth, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	key, err := d.bytes(lth)
+	if err != nil || lth == 0 {
+		return nil, err
+	}
+	return append([]byte(nil), key...), nil
+}
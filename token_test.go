@@ -0,0 +1,89 @@
+package art
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorToken(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{}
+	for i := 0; i < 100; i++ {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, uint32(i))
+		tree.Insert(key, i)
+		keys = append(keys, key)
+	}
+	// pages visits all keys of the iterator, resuming from the token every n keys
+	pages := func(iter *iterator, n int) [][]byte {
+		var rst [][]byte
+		for {
+			visited := 0
+			for visited < n && iter.Next() {
+				rst = append(rst, iter.Key())
+				visited++
+			}
+			if visited < n {
+				return rst
+			}
+			var err error
+			iter, err = tree.IteratorFrom(iter.Token())
+			require.NoError(t, err)
+		}
+	}
+	reversed := func(keys [][]byte) [][]byte {
+		rst := make([][]byte, len(keys))
+		for i := range keys {
+			rst[len(keys)-1-i] = keys[i]
+		}
+		return rst
+	}
+	for _, tc := range []struct {
+		desc     string
+		iter     func() *iterator
+		expected [][]byte
+	}{
+		{"all", func() *iterator { return tree.Iterator(nil, nil) }, keys},
+		{"range", func() *iterator { return tree.Iterator(keys[10], keys[50]) }, keys[11:51]},
+		{"reverse", func() *iterator { return tree.ReverseIterator(keys[10], keys[50]) }, reversed(keys[11:51])},
+		{"scan", func() *iterator {
+			return tree.Scan(Range{Start: keys[10], End: keys[50], Inclusivity: IncludeStart})
+		}, keys[10:50]},
+		{"prefix", func() *iterator { return tree.Prefix([]byte{0, 0, 0}) }, keys},
+		{"consistent", func() *iterator { return tree.Iterator(nil, nil).Consistent() }, keys},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			for _, n := range []int{1, 7, 40} {
+				require.Equal(t, tc.expected, pages(tc.iter(), n))
+			}
+		})
+	}
+
+	t.Run("exhausted", func(t *testing.T) {
+		iter := tree.Iterator(keys[97], nil)
+		for iter.Next() {
+		}
+		tree.Insert([]byte{1, 0, 0, 0}, nil)
+		defer tree.Delete([]byte{1, 0, 0, 0})
+		iter, err := tree.IteratorFrom(iter.Token())
+		require.NoError(t, err)
+		require.False(t, iter.Next())
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		token := tree.Iterator(keys[10], keys[20]).Token()
+		for _, corrupt := range [][]byte{
+			nil,
+			{tokenVersion + 1, 0, 0, 0},
+			token[:len(token)-1],
+			append(token, 0),
+		} {
+			_, err := tree.IteratorFrom(corrupt)
+			require.True(t, errors.Is(err, ErrCorrupt), "%v", err)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+package art
+
+// WithKeyTransform configures a transform that is applied to every key passed to the tree,
+// on insert, lookup, delete, and to range bounds and prefixes. Keys are stored and ordered
+// by the transformed bytes, e.g. lowercased keys for case-insensitive lookups, or collation
+// sort keys for strings ordered with Unicode collation. Iteration returns transformed keys.
+//
+// Transform must be deterministic, must not retain or modify its argument, and must keep
+// the tree free of keys that are prefixes of other keys. Transform of a prefix of a key
+// must be a prefix of the transformed key, otherwise Prefix and Match won't find the key.
+func WithKeyTransform(transform func([]byte) []byte) Option {
+	return func(t *Tree) {
+		t.transform = transform
+	}
+}
+
+// keyOf returns transformed key. Empty keys are used as unbounded range bounds and
+// are returned as is.
+func (t *Tree) keyOf(key []byte) []byte {
+	if t.transform == nil || len(key) == 0 {
+		return key
+	}
+	return t.transform(key)
+}
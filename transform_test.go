@@ -0,0 +1,73 @@
+package art
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyTransform(t *testing.T) {
+	tree := New(WithKeyTransform(bytes.ToLower))
+	tree.Insert([]byte("Apple\x00"), 1)
+	tree.Insert([]byte("banana\x00"), 2)
+	tree.Insert([]byte("APRICOT\x00"), 3)
+	tree.Insert([]byte("apple\x00"), 4)
+	require.Equal(t, 3, tree.Len())
+
+	value, found := tree.Get([]byte("APPLE\x00"))
+	require.True(t, found)
+	require.Equal(t, 4, value)
+
+	var keys []string
+	for iter := tree.Prefix([]byte("AP")); iter.Next(); {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.Equal(t, []string{"apple\x00", "apricot\x00"}, keys)
+
+	keys = keys[:0]
+	for iter := tree.Match([]byte("A*T\x00")); iter.Next(); {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.Equal(t, []string{"apricot\x00"}, keys)
+
+	next, _, found := tree.Next([]byte("APRICOT\x00"))
+	require.True(t, found)
+	require.Equal(t, []byte("banana\x00"), next)
+
+	require.True(t, tree.Update([]byte("BANANA\x00"), func(old ValueType, exists bool) (ValueType, bool) {
+		require.True(t, exists)
+		return old.(int) + 1, true
+	}))
+	value, _ = tree.Get([]byte("banana\x00"))
+	require.Equal(t, 3, value)
+
+	require.Equal(t, 2, tree.DeleteIn(PrefixRange([]byte("AP"))))
+	tree.Delete([]byte("Banana\x00"))
+	require.Zero(t, tree.Len())
+}
+
+func TestKeyTransformBatch(t *testing.T) {
+	// inverted bytes reverse the order of the keys
+	invert := func(key []byte) []byte {
+		rst := make([]byte, len(key))
+		for i, b := range key {
+			rst[i] = 0xff - b
+		}
+		return rst
+	}
+	tree := New(WithKeyTransform(invert))
+	keys := [][]byte{{1, 1}, {1, 2}, {2, 1}, {3, 1}}
+	tree.InsertBatch(keys, []ValueType{1, 2, 3, 4})
+	require.Equal(t, len(keys), tree.Len())
+	for i, key := range keys {
+		value, found := tree.Get(key)
+		require.True(t, found)
+		require.Equal(t, i+1, value)
+	}
+	var rst [][]byte
+	for iter := tree.Iterator(nil, nil); iter.Next(); {
+		rst = append(rst, invert(iter.Key()))
+	}
+	require.Equal(t, [][]byte{{3, 1}, {2, 1}, {1, 2}, {1, 1}}, rst)
+}
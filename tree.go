@@ -2,100 +2,451 @@ package art
 
 import (
 	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
 )
 
 type ValueType interface{}
 
+// cacheLineSize is a common size of the cache line, used to prevent false sharing.
+const cacheLineSize = 64
+
 type Tree struct {
+	// writes is a number of modifications, used to detect that tree is idle.
+	// first in the struct to guarantee 64-bit alignment for atomic operations.
+	writes uint64
+	// size is a number of keys stored in the tree.
+	size int64
+	// restarts is a number of descents restarted from the root.
+	restarts uint64
+	// counters above are modified by every write, lock and root are loaded by every
+	// operation but modified only when the root is replaced. Padding keeps them on
+	// separate cache lines, otherwise readers miss the cache after every write.
+	_ [cacheLineSize]byte
+
 	lock olock
 	root node
+
+	profiler  *profiler
+	poisoned  atomic.Pointer[PoisonError]
+	evictor   *sampler
+	admission *sketch
+	limiter   *limiter
+	alloc     Allocator
+	clock     Clock
+	guard     *guard
+	copyKeys  bool
+	transform func([]byte) []byte
+	wal       *wal
+	metrics   atomic.Pointer[metricsSink]
+	backoff   atomic.Pointer[backoffPolicy]
+	// ttls are default ttls of the prefixes, replaced by SetPrefixTTL under ttlsMu.
+	ttls   atomic.Pointer[[]prefixTTL]
+	ttlsMu sync.Mutex
+	// encode and decode values for serialization, optional.
+	encode func([]byte, ValueType) []byte
+	decode func([]byte) (ValueType, error)
 }
 
 func (t *Tree) Insert(key []byte, value ValueType) {
-	for {
-		version, restart := t.lock.RLock()
-		l := &leaf{key: key, value: value}
+	t.insert(t.newLeaf(t.keyOf(key), value))
+}
+
+// insert stores the leaf and returns the leaf that was replaced, or nil if the key
+// didn't exist or expired.
+func (t *Tree) insert(l *leaf) *leaf {
+	if t.limiter != nil {
+		t.limiter.wait()
+	}
+	if t.guard != nil {
+		t.guard.inserting("insert", l.key)
+	}
+	op := upsert{key: l.key, leaf: l}
+	t.upsert(&op)
+	return op.live
+}
+
+// upsert applies modification to the key, see upsert type for details.
+func (t *Tree) upsert(op *upsert) {
+	t.checkPoisoned()
+	atomic.AddUint64(&t.writes, 1)
+	op.tree = t
+	if t.profiler != nil {
+		t.profiler.observe(t, op.key)
+	}
+	var victim *leaf
+	if t.evictor != nil && t.admission != nil {
+		t.admission.record(op.key)
+		var admitted bool
+		admitted, victim = t.admit(op.key)
+		op.replaceOnly = !admitted
+	}
+	// stripe is released before eviction, victim may share the stripe with the key
+	t.write(op)
+	if victim != nil && op.stored != nil && op.old == nil {
+		// evict the victim that lost to the new key, instead of sampling another one
+		t.deleteLeaf(victim.key, victim)
+	}
+	t.upserted(op)
+}
+
+// write applies modification and appends it to the log. Stripe of the key is locked
+// until the record is appended, and is released if resolve panics.
+func (t *Tree) write(op *upsert) {
+	if t.wal != nil {
+		stripe := t.wal.stripe(op.key)
+		stripe.Lock()
+		defer stripe.Unlock()
+	}
+	hinted := op.hint != nil && op.hint.upsert(op)
+	if !hinted {
+		t.store(op, &t.lock)
+	}
+	if op.hint != nil {
+		if hinted {
+			op.hint.refresh()
+		}
+		// extend the path for the next hinted modification
+		_ = op.hint.get(op.key)
+	}
+	if t.wal != nil && op.stored != nil && op.stored != op.old {
+		t.wal.insert(t.encode, op.stored)
+	}
+}
+
+// store applies modification starting from the root, lock guards the root pointer.
+// It is the lock of the tree, unless the tree is locked by Commit.
+func (t *Tree) store(op *upsert, lock *olock) {
+	for attempt := 1; ; attempt++ {
+		version, _ := lock.RLock()
 		root := t.root
-		if root == nil {
-			if t.lock.Upgrade(version, nil) {
+		if root == nil || root.isLeaf() {
+			if lock.Upgrade(version, nil) {
 				continue
 			}
-			t.root = l
-			t.lock.Unlock()
-			return
-		}
-		if root.isLeaf() {
-			if t.lock.Upgrade(version, nil) {
-				continue
+			if root == nil {
+				if l := op.apply(nil, lock); l != nil {
+					t.root = l
+				}
+			} else {
+				t.root, _ = root.(*leaf).upsert(op, 0, lock, version)
 			}
-			t.root, _ = root.insert(l, 0, &t.lock, version)
-			t.lock.Unlock()
+			lock.Unlock()
 			return
 		}
-		_, restart = root.insert(l, 0, &t.lock, version)
-		if restart {
-			continue
+		if _, restart := root.(*inner).upsert(op, 0, lock, version); !restart {
+			return
 		}
+		t.restarted(attempt)
+	}
+}
+
+// upserted updates state of the tree after modification.
+func (t *Tree) upserted(op *upsert) {
+	if op.stored == nil {
 		return
 	}
-	panic("unreachable")
+	t.count(MetricInserts, 1)
+	if t.limiter != nil && op.stored != op.old {
+		t.limiter.add(leafSize(op.stored) - leafSize(op.old))
+	}
+	if t.guard != nil && op.stored != op.old {
+		t.guard.forget(op.old)
+		t.guard.stored(op.stored)
+	}
+	if t.evictor != nil {
+		if op.stored != op.old {
+			t.evictor.add(leafSize(op.stored) - leafSize(op.old))
+		}
+		t.evictor.touch(op.stored)
+	}
+	if op.old == nil {
+		atomic.AddInt64(&t.size, 1)
+		if t.evictor != nil {
+			t.evictor.evict(t)
+		}
+	}
 }
 
 func (t *Tree) Get(key []byte) (ValueType, bool) {
-	for {
+	l := t.lookup(t.keyOf(key), nil)
+	if l == nil {
+		return nil, false
+	}
+	return l.value, true
+}
+
+// lookup returns the leaf that stores the key, and records access to the key.
+// If hint is not nil descent resumes from the path cached in the hint.
+func (t *Tree) lookup(key []byte, hint *finger) *leaf {
+	t.observe(key)
+	t.count(MetricGets, 1)
+	var l *leaf
+	if hint != nil {
+		l = hint.get(key)
+	} else {
+		l = t.get(key)
+	}
+	if l != nil && t.expired(l) {
+		t.expire(l)
+		return nil
+	}
+	t.accessed(l)
+	return l
+}
+
+// observe records lookup of the key before the tree is accessed.
+func (t *Tree) observe(key []byte) {
+	t.checkPoisoned()
+	if t.profiler != nil {
+		t.profiler.observe(t, key)
+	}
+	if t.evictor != nil && t.admission != nil {
+		t.admission.record(key)
+	}
+}
+
+// accessed records access to the leaf found by lookup.
+func (t *Tree) accessed(l *leaf) {
+	if l != nil && t.evictor != nil {
+		t.evictor.touch(l)
+	}
+	if l != nil && t.guard != nil {
+		t.guard.verify("get", l.key)
+	}
+}
+
+// get returns the leaf that stores the key or nil.
+func (t *Tree) get(key []byte) *leaf {
+	attempt := 1
+	if optimistic {
+		for ; attempt <= descendAttempts; attempt++ {
+			l, restart := t.descend(key)
+			if !restart {
+				return l
+			}
+			t.restarted(attempt)
+		}
+		// writes are frequent on the path, fallback to the descent that restarts
+		// from the node that was modified instead of the root
+	}
+	for ; ; attempt++ {
 		version, _ := t.lock.RLock()
 		root := t.root
-		if root == nil {
+		if root == nil || root.isLeaf() {
+			var l *leaf
+			if root != nil {
+				l, _ = root.get(key, 0, &t.lock, version)
+			}
 			if t.lock.RUnlock(version, nil) {
 				continue
 			}
-			return nil, false
+			return l
 		}
-		val, found, restart := root.get(key, 0, &t.lock, version)
+		l, restart := root.get(key, 0, &t.lock, version)
 		if restart {
+			t.restarted(attempt)
 			continue
 		}
-		return val, found
+		return l
 	}
-	panic("unreachable")
+}
+
+// descendAttempts is a number of single pass descents before get falls back
+// to the descent that validates parent on every level.
+const descendAttempts = 3
+
+// observed is a version of the node recorded during the descent.
+type observed struct {
+	lock    *olock
+	version uint64
+}
+
+// descend is a single pass optimistic descent. Versions of the visited nodes are recorded
+// and validated once when the leaf is reached, instead of validating the parent on every level.
+// Nodes replaced by writers are never reused, reading them is safe, and stale path
+// is detected by the validation. Returns true if the descent must be restarted.
+func (t *Tree) descend(key []byte) (*leaf, bool) {
+	var buf [16]observed
+	version, _ := t.lock.RLock()
+	path := append(buf[:0], observed{lock: &t.lock, version: version})
+	next := t.root
+	depth := 0
+	var l *leaf
+	for next != nil {
+		n, isInner := next.(*inner)
+		if !isInner {
+			l = next.(*leaf)
+			if !l.cmp(key) {
+				l = nil
+			}
+			break
+		}
+		version, obsolete := n.lock.RLock()
+		if obsolete {
+			return nil, true
+		}
+		path = append(path, observed{lock: &n.lock, version: version})
+		if comparePrefix(n.prefix[:n.prefixLen], key, 0, depth) != n.prefixLen {
+			break
+		}
+		depth += n.prefixLen
+		if depth >= len(key) {
+			break
+		}
+		_, next = n.node.child(key[depth])
+		depth++
+	}
+	for i := range path {
+		if path[i].lock.Check(path[i].version) {
+			return nil, true
+		}
+	}
+	return l, false
 }
 
 func (t *Tree) Delete(key []byte) {
-	for {
-		version, _ := t.lock.RLock()
+	t.Remove(key)
+}
+
+// Remove deletes the key and returns value that was stored.
+// Expired key is removed, but reported as not found.
+func (t *Tree) Remove(key []byte) (ValueType, bool) {
+	t.checkPoisoned()
+	return t.remove(t.keyOf(key))
+}
+
+// remove deletes the key that was already transformed.
+func (t *Tree) remove(key []byte) (ValueType, bool) {
+	atomic.AddUint64(&t.writes, 1)
+	if t.profiler != nil {
+		t.profiler.observe(t, key)
+	}
+	removed := t.delete(key)
+	if removed == nil || t.expired(removed) {
+		return nil, false
+	}
+	return removed.value, true
+}
+
+// delete removes the key and returns the removed leaf, or nil if key wasn't found.
+func (t *Tree) delete(key []byte) *leaf {
+	return t.deleteLeaf(key, nil)
+}
+
+// deleteLeaf removes the key if it is stored by the expected leaf, or unconditionally
+// if expected is nil.
+func (t *Tree) deleteLeaf(key []byte, expected *leaf) *leaf {
+	if t.wal != nil {
+		stripe := t.wal.stripe(key)
+		stripe.Lock()
+		defer stripe.Unlock()
+	}
+	removed := t.del(key, expected, &t.lock)
+	if t.wal != nil && removed != nil {
+		t.wal.delete(key)
+	}
+	t.deleted(removed)
+	return removed
+}
+
+// deleted updates state of the tree after the leaf was removed.
+func (t *Tree) deleted(removed *leaf) {
+	if removed != nil {
+		atomic.AddInt64(&t.size, -1)
+		t.count(MetricDeletes, 1)
+		if t.limiter != nil {
+			t.limiter.add(-leafSize(removed))
+		}
+		if t.evictor != nil {
+			t.evictor.add(-leafSize(removed))
+		}
+		if t.guard != nil {
+			t.guard.forget(removed)
+		}
+	}
+}
+
+// del removes the key starting from the root, lock guards the root pointer.
+// If expected is not nil the key is removed only if it is stored by the expected leaf.
+func (t *Tree) del(key []byte, expected *leaf, lock *olock) *leaf {
+	for attempt := 1; ; attempt++ {
+		version, _ := lock.RLock()
 
 		root := t.root
 		if root == nil {
-			if t.lock.RUnlock(version, nil) {
+			if lock.RUnlock(version, nil) {
 				continue
 			}
-			return
+			return nil
 		}
 
 		l, isLeaf := root.(*leaf)
 		// NOTE(dshulyak) not sure why `l != nil` is necessary
-		if isLeaf && l != nil && l.cmp(key) {
-			if t.lock.Upgrade(version, nil) {
+		if isLeaf && l != nil && l.cmp(key) && (expected == nil || l == expected) {
+			if lock.Upgrade(version, nil) {
 				continue
 			}
 			t.root = nil
-			t.lock.Unlock()
-			return
+			lock.Unlock()
+			return l
 		} else if isLeaf {
-			if t.lock.RUnlock(version, nil) {
+			if lock.RUnlock(version, nil) {
 				continue
 			}
-			return
+			return nil
 		}
 
-		if root.del(key, 0, &t.lock, version, func(rn node) {
+		removed, restart := root.del(key, expected, 0, lock, version, func(rn node) {
 			t.root = rn
-		}) {
+		}, t)
+		if restart {
+			t.restarted(attempt)
 			continue
 		}
-		return
+		return removed
+	}
+}
+
+// Clear removes all keys from the tree.
+// Root is detached under the write lock of the tree, and every inner node of the old root
+// is locked and marked obsolete, so that concurrent readers and writers that observed it
+// restart from the new (empty) root. Writes that completed before are counted together
+// with the other leaves, and subtracted from size and memory accounting. Nodes are not copied,
+// detached structure is left to the garbage collector.
+func (t *Tree) Clear() {
+	atomic.AddUint64(&t.writes, 1)
+	if t.wal != nil {
+		t.wal.lockAll()
+		defer t.wal.unlockAll()
+	}
+	a := t.allocator()
+	d := detaching{alloc: a, removed: pruning{alloc: a, guard: t.guard}}
+	t.lock.Lock()
+	if root, isInner := t.root.(*inner); isInner {
+		root.lock.Lock()
+	}
+	d.drop(t.root)
+	t.root = nil
+	d.release()
+	t.lock.Unlock()
+	if t.wal != nil {
+		t.wal.clear()
 	}
-	panic("unreachable")
+	r := &d.removed
+	atomic.AddInt64(&t.size, -int64(r.count))
+	if t.limiter != nil {
+		t.limiter.add(-r.bytes)
+	}
+	if t.evictor != nil {
+		t.evictor.add(-r.bytes)
+	}
+}
+
+// Len returns number of keys stored in the tree.
+func (t *Tree) Len() int {
+	return int(atomic.LoadInt64(&t.size))
 }
 
 func (t *Tree) Empty() bool {
@@ -107,13 +458,36 @@ func (t *Tree) Empty() bool {
 // Iterator is concurrently safe, but doesn't guarantee to provide consistent
 // snapshot of the tree state.
 func (t *Tree) Iterator(start, end []byte) *iterator {
+	t.checkPoisoned()
+	start, end = t.keyOf(start), t.keyOf(end)
 	return &iterator{
 		tree:      t,
 		cursor:    start,
 		terminate: end,
+		begin:     start,
 	}
 }
 
+// Keys returns iterator over keys in range (start, end] that doesn't load values.
+// Key returned by the iterator is not copied and must not be modified.
+func (t *Tree) Keys(start, end []byte) *iterator {
+	return t.Iterator(start, end).KeysOnly()
+}
+
+// ReverseIterator visits the same range (start, end] as Iterator in descending order,
+// starting from end. Unlike Iterator(start, end).Reverse(), boundaries of the range don't change.
+func (t *Tree) ReverseIterator(start, end []byte) *iterator {
+	return t.Scan(Range{Start: start, End: end, Inclusivity: IncludeEnd}).Reverse()
+}
+
+// IteratorCtx is the same as Iterator, iteration is aborted when the context is cancelled.
+// Next returns false after cancellation and Err returns the error of the context.
+func (t *Tree) IteratorCtx(ctx context.Context, start, end []byte) *iterator {
+	iter := t.Iterator(start, end)
+	iter.ctx = ctx
+	return iter
+}
+
 // testView returns tree structure in the format used for tests.
 // Must preserve:
 // - depth
@@ -8,8 +8,10 @@ import (
 	"math/rand"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,6 +78,16 @@ func TestTreeInsert(t *testing.T) {
 				{[]byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 2}, 2},
 			},
 		},
+		{
+			desc: "long keys diverge after max prefix",
+			pretty: `inner[0100000000000000]n4[0102]
+.........leaf[010000000000000001]
+.........leaf[010000000000000002]`,
+			inserts: []kv{
+				{[]byte{1, 0, 0, 0, 0, 0, 0, 0, 1}, 1},
+				{[]byte{1, 0, 0, 0, 0, 0, 0, 0, 2}, 2},
+			},
+		},
 		{
 			desc: "normal add child",
 			pretty: `inner[]n4[010203]
@@ -298,6 +310,83 @@ func TestTreeDelete(t *testing.T) {
 	}
 }
 
+func TestTreeClear(t *testing.T) {
+	var tree Tree
+	keys := [][]byte{}
+	for i := 0; i < 1000; i++ {
+		key := make([]byte, 8)
+		rand.Read(key)
+		tree.Insert(key, key)
+		keys = append(keys, key)
+	}
+	tree.Clear()
+	require.True(t, tree.Empty())
+	require.Zero(t, tree.Len())
+	for _, key := range keys {
+		_, found := tree.Get(key)
+		require.False(t, found)
+	}
+	iter := tree.Iterator(nil, nil)
+	require.False(t, iter.Next())
+
+	tree.Insert(keys[0], 1)
+	rst, found := tree.Get(keys[0])
+	require.True(t, found)
+	require.Equal(t, 1, rst)
+}
+
+func TestTreeClearConcurrentWrites(t *testing.T) {
+	skipUnsafe(t)
+	// writes that complete into the detached root must not be counted by the new root
+	var (
+		tree    Tree
+		wg      sync.WaitGroup
+		stop    = make(chan struct{})
+		inserts int64
+	)
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				tree.Insert(append(sequentialKey(i), byte(w)), i)
+				atomic.AddInt64(&inserts, 1)
+			}
+		}(w)
+	}
+	for atomic.LoadInt64(&inserts) < 100_000 {
+		tree.Clear()
+	}
+	close(stop)
+	wg.Wait()
+	keys, _ := tree.Dump()
+	require.Equal(t, len(keys), tree.Len())
+}
+
+func TestTreeLen(t *testing.T) {
+	var tree Tree
+	require.Zero(t, tree.Len())
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte{byte(i >> 8), byte(i)}, i)
+	}
+	require.Equal(t, 1000, tree.Len())
+	for i := 0; i < 1000; i++ {
+		tree.Insert([]byte{byte(i >> 8), byte(i)}, -i)
+	}
+	require.Equal(t, 1000, tree.Len())
+	for i := 0; i < 1000; i += 2 {
+		tree.Delete([]byte{byte(i >> 8), byte(i)})
+		tree.Delete([]byte{byte(i >> 8), byte(i)})
+	}
+	tree.Delete([]byte{255, 255})
+	require.Equal(t, 500, tree.Len())
+}
+
 func TestFuzzTree(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -385,6 +474,7 @@ func BenchmarkInserts(b *testing.B) {
 }
 
 func TestTreeConcurrentInsert(t *testing.T) {
+	skipUnsafe(t)
 	var tree Tree
 	keys := []string{
 		"aabd",
@@ -405,6 +495,7 @@ func TestTreeConcurrentInsert(t *testing.T) {
 		}(key)
 	}
 	wg.Wait()
+	require.Equal(t, len(keys), tree.Len())
 
 	for _, key := range keys {
 		rst, exist := tree.Get([]byte(key))
@@ -414,6 +505,7 @@ func TestTreeConcurrentInsert(t *testing.T) {
 }
 
 func TestTreeConcurrentDelete(t *testing.T) {
+	skipUnsafe(t)
 	cnt := 100_000
 	factor := 8
 	keys := [][]byte{}
@@ -441,9 +533,11 @@ func TestTreeConcurrentDelete(t *testing.T) {
 	close(keyc)
 	wg.Wait()
 	require.True(t, tree.Empty())
+	require.Zero(t, tree.Len())
 }
 
 func TestTreeInsertDeleteConcurrent(t *testing.T) {
+	skipUnsafe(t)
 	var (
 		wg   sync.WaitGroup
 		cnt  = 100
@@ -486,6 +580,53 @@ func TestTreeInsertDeleteConcurrent(t *testing.T) {
 	require.True(t, tree.Empty())
 }
 
+func TestTreeGetConcurrentWrites(t *testing.T) {
+	skipUnsafe(t)
+	// stable keys are never modified, writers grow and shrink the nodes on their path
+	var tree Tree
+	stable := [][]byte{}
+	for i := 0; i < 64; i++ {
+		key := []byte{0, byte(i), 0}
+		tree.Insert(key, i)
+		stable = append(stable, key)
+	}
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := []byte{0, byte(rng.Intn(256)), byte(1 + rng.Intn(255))}
+				if rng.Intn(2) == 0 {
+					key = []byte{0, byte(64 + rng.Intn(192)), 0}
+				}
+				if rng.Intn(2) == 0 {
+					tree.Insert(key, -1)
+				} else {
+					tree.Delete(key)
+				}
+			}
+		}(int64(w))
+	}
+	for i := 0; i < 200_000; i++ {
+		key := stable[i%len(stable)]
+		value, found := tree.Get(key)
+		require.True(t, found, "key %v", key)
+		require.Equal(t, int(key[1]), value)
+	}
+	close(stop)
+	wg.Wait()
+}
+
 func randomKey(rng *rand.Rand) [16]byte {
 	b := [16]byte{}
 	binary.LittleEndian.PutUint32(b[:], rng.Uint32())
@@ -494,7 +635,14 @@ func randomKey(rng *rand.Rand) [16]byte {
 	return b
 }
 
+func TestRootCacheLine(t *testing.T) {
+	var tree Tree
+	counters := unsafe.Offsetof(tree.restarts) + unsafe.Sizeof(tree.restarts)
+	require.GreaterOrEqual(t, int(unsafe.Offsetof(tree.lock)-counters), cacheLineSize)
+}
+
 func BenchmarkGetInsert(b *testing.B) {
+	skipUnsafe(b)
 	value := 123
 	for i := 0; i <= 10; i++ {
 		readFrac := float32(i) / 10.0
@@ -537,3 +685,62 @@ func BenchmarkGetInsertSyncMap(b *testing.B) {
 		})
 	}
 }
+
+func TestTreeProfile(t *testing.T) {
+	tree := New(WithProfiling(1))
+	keys := [][]byte{
+		{1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{1, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+		{2},
+	}
+	for _, key := range keys {
+		tree.Insert(key, key)
+	}
+	_, _ = tree.Get(keys[0])
+
+	profile := tree.Profile()
+	require.Equal(t, uint64(4), profile.Samples)
+	// empty tree and leaf root, prefix mismatch at the root, three levels after split
+	require.Equal(t, []uint64{2, 1, 0, 1}, profile.Depth)
+	require.Equal(t, uint64(1), profile.FullPrefixes)
+	require.Equal(t, uint64(4), profile.Nodes[kindNode4])
+	// 0 bytes matched during prefix mismatch, 0 + 7 + 0 during get
+	require.Equal(t, uint64(7), profile.PrefixBytes)
+}
+
+func TestTreeRemove(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	_, found := tree.Remove([]byte{1})
+	require.False(t, found)
+
+	tree.Insert([]byte{1}, 1)
+	value, found := tree.Remove([]byte{1})
+	require.True(t, found)
+	require.Equal(t, 1, value)
+	require.True(t, tree.Empty())
+
+	for i := 0; i < 100; i++ {
+		tree.Insert([]byte{byte(i), 0}, i)
+	}
+	var wg sync.WaitGroup
+	removed := make([]int, 4)
+	for w := range removed {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, found := tree.Remove([]byte{byte(i), 0}); found {
+					removed[w]++
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	total := 0
+	for _, n := range removed {
+		total += n
+	}
+	require.Equal(t, 100, total)
+	require.Zero(t, tree.Len())
+}
@@ -0,0 +1,105 @@
+package art
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// overprovisioned is true if all children will fit into the smaller node.
+// Delete shrinks nodes eagerly, but nodes may be left overprovisioned
+// by bulk operations that remove several children at once.
+func overprovisioned(n inode) bool {
+	switch n := n.(type) {
+	case *node16:
+		return n.lth <= 4
+	case *node48:
+		return n.lth <= 16
+	case *node256:
+		return n.lth <= 48
+	}
+	return false
+}
+
+// Trim shrinks every overprovisioned inner node to the smallest node type
+// that can hold all of its children. Trim is safe to use concurrently with other operations.
+func (t *Tree) Trim() {
+	for {
+		version, _ := t.lock.RLock()
+		root, isInner := t.root.(*inner)
+		if t.lock.RUnlock(version, nil) {
+			continue
+		}
+		if isInner {
+			root.trim(t.allocator())
+		}
+		return
+	}
+}
+
+func (n *inner) trim(a Allocator) {
+	var childs []*inner
+	for {
+		version, obsolete := n.lock.RLock()
+		if obsolete {
+			return
+		}
+		if overprovisioned(n.node) {
+			if n.lock.Upgrade(version, nil) {
+				continue
+			}
+			for overprovisioned(n.node) {
+				n.node = n.node.shrink(a)
+			}
+			n.unlock()
+			continue
+		}
+		childs = childs[:0]
+		var pointer *byte
+		for {
+			b, child := n.node.next(pointer)
+			if child == nil {
+				break
+			}
+			if in, isInner := child.(*inner); isInner {
+				childs = append(childs, in)
+			}
+			pointer = &b
+		}
+		if n.lock.RUnlock(version, nil) {
+			continue
+		}
+		break
+	}
+	for _, child := range childs {
+		child.trim(a)
+	}
+}
+
+// TrimWhenIdle runs Trim every time when the tree wasn't modified for the interval.
+// Blocks until context is canceled.
+func (t *Tree) TrimWhenIdle(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var (
+		last    = atomic.LoadUint64(&t.writes)
+		trimmed bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			writes := atomic.LoadUint64(&t.writes)
+			if writes != last {
+				last = writes
+				trimmed = false
+				continue
+			}
+			if !trimmed {
+				t.Trim()
+				trimmed = true
+			}
+		}
+	}
+}
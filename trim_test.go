@@ -0,0 +1,59 @@
+package art
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrim(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 256; i++ {
+		tree.Insert([]byte{1, byte(i)}, i)
+	}
+	root := tree.root.(*inner)
+	// remove children without shrinking, as if they were removed in bulk
+	for i := 3; i < 256; i++ {
+		root.node.replace(i, nil)
+	}
+	require.Equal(t, "inner[01]n256[000102]", root.String())
+
+	tree.Trim()
+	require.Equal(t, `inner[01]n4[000102]
+..leaf[0100]
+..leaf[0101]
+..leaf[0102]`, tree.testView())
+	for i := 0; i < 3; i++ {
+		rst, found := tree.Get([]byte{1, byte(i)})
+		require.True(t, found)
+		require.Equal(t, i, rst)
+	}
+}
+
+func TestTrimWhenIdle(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	for i := 0; i < 20; i++ {
+		tree.Insert([]byte{byte(i)}, i)
+	}
+	root := tree.root.(*inner)
+	for i := 3; i < 20; i++ {
+		root.node.replace(i, nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tree.TrimWhenIdle(ctx, time.Millisecond)
+	}()
+	require.Eventually(t, func() bool {
+		root.lock.Lock()
+		defer root.lock.Unlock()
+		_, isNode4 := root.node.(*node4)
+		return isNode4
+	}, time.Second, time.Millisecond)
+	cancel()
+	require.Equal(t, context.Canceled, <-errc)
+}
@@ -0,0 +1,197 @@
+package art
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// InsertTTL inserts the key that expires after the ttl, deadline is computed with the clock
+// configured by WithClock. Expired key is not returned by lookups, Min, Max, Next, Prev and
+// PathMatches, and is removed by the first of them that observed it, or by Expire.
+// Modifications treat expired key as absent. Iterators, Len and MarshalBinary include expired keys
+// until they are removed, UnmarshalBinary restores keys without deadlines.
+// Insert of the same key replaces the deadline, key inserted by Insert doesn't expire
+// unless its prefix has a default ttl set by SetPrefixTTL.
+func (t *Tree) InsertTTL(key []byte, value ValueType, ttl time.Duration) {
+	l := t.newLeaf(t.keyOf(key), value)
+	l.expires = t.now() + int64(ttl)
+	t.insert(l)
+}
+
+// TTL returns time left until the key expires, false if the key doesn't exist or doesn't expire.
+func (t *Tree) TTL(key []byte) (time.Duration, bool) {
+	l := t.lookup(t.keyOf(key), nil)
+	if l == nil || l.expires == 0 {
+		return 0, false
+	}
+	return time.Duration(l.expires - t.now()), true
+}
+
+// SetPrefixTTL sets default ttl of the keys with the prefix, keys inserted without InsertTTL
+// expire after the ttl of the longest prefix they have. Zero ttl removes the default.
+// Policy applies to existing keys, deadline of every key with the prefix is replaced by
+// the default deadline computed from the current time, including keys inserted by InsertTTL.
+// Keys are collected in a single pass as by Expire, keys inserted concurrently may keep
+// the deadline computed from the previous policy.
+func (t *Tree) SetPrefixTTL(prefix []byte, ttl time.Duration) {
+	t.checkPoisoned()
+	prefix = t.keyOf(prefix)
+	t.ttlsMu.Lock()
+	var policies []prefixTTL
+	if current := t.ttls.Load(); current != nil {
+		for _, p := range *current {
+			if !bytes.Equal(p.prefix, prefix) {
+				policies = append(policies, p)
+			}
+		}
+	}
+	if ttl > 0 {
+		policies = append(policies, prefixTTL{prefix: append([]byte(nil), prefix...), ttl: int64(ttl)})
+	}
+	if len(policies) == 0 {
+		t.ttls.Store(nil)
+	} else {
+		t.ttls.Store(&policies)
+	}
+	t.ttlsMu.Unlock()
+
+	for _, l := range t.snapshot(PrefixRange(prefix)) {
+		stale := l
+		op := upsert{key: l.key, resolve: func(old *leaf) *leaf {
+			if old != stale {
+				return old
+			}
+			restamped := t.allocator().leaf()
+			restamped.key, restamped.value = old.key, old.value
+			restamped.expires = t.deadline(old.key)
+			return restamped
+		}}
+		t.upsert(&op)
+	}
+}
+
+// prefixTTL is a default ttl of the keys with the prefix.
+type prefixTTL struct {
+	prefix []byte
+	ttl    int64
+}
+
+// deadline returns default deadline of the key, zero if the key doesn't expire.
+func (t *Tree) deadline(key []byte) int64 {
+	policies := t.ttls.Load()
+	if policies == nil {
+		return 0
+	}
+	longest := -1
+	var ttl int64
+	for _, p := range *policies {
+		if len(p.prefix) > longest && bytes.HasPrefix(key, p.prefix) {
+			longest, ttl = len(p.prefix), p.ttl
+		}
+	}
+	if longest < 0 {
+		return 0
+	}
+	return t.now() + ttl
+}
+
+func (t *Tree) now() int64 {
+	if t.clock == nil {
+		return time.Now().UnixNano()
+	}
+	return t.clock.Now().UnixNano()
+}
+
+// expired is true if the deadline of the leaf passed.
+func (t *Tree) expired(l *leaf) bool {
+	return l.expires != 0 && l.expires <= t.now()
+}
+
+// expire removes the expired leaf if it is still stored for its key.
+func (t *Tree) expire(l *leaf) *leaf {
+	atomic.AddUint64(&t.writes, 1)
+	return t.deleteLeaf(l.key, l)
+}
+
+// Expire removes expired keys and returns number of removed keys. Leaves are collected
+// in a single pass, as by Dump, and removed one by one, compressing the path as Delete does.
+// Keys that expire after the pass started are removed by the next call.
+func (t *Tree) Expire() int {
+	t.checkPoisoned()
+	now := t.now()
+	removed := 0
+	for _, l := range t.snapshot(All()) {
+		if l.expires != 0 && l.expires <= now && t.expire(l) != nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Expired returns iterator over expired keys that were not removed yet, in ascending order.
+// Keys are collected when the iterator is created, in a single pass as by Expire, keys that
+// expire later are not visited. Visited keys are not removed, unless Purge is called.
+func (t *Tree) Expired() *expiredIterator {
+	t.checkPoisoned()
+	now := t.now()
+	leaves := t.snapshot(All())
+	expired := leaves[:0]
+	for _, l := range leaves {
+		if l.expires != 0 && l.expires <= now {
+			expired = append(expired, l)
+		}
+	}
+	return &expiredIterator{tree: t, leaves: expired, pos: -1}
+}
+
+// expiredIterator visits expired leaves collected by Expired.
+type expiredIterator struct {
+	tree   *Tree
+	leaves []*leaf
+	pos    int
+}
+
+// Next moves to the next expired key, returns false if there are no more keys.
+func (i *expiredIterator) Next() bool {
+	if i.pos < len(i.leaves) {
+		i.pos++
+	}
+	return i.pos < len(i.leaves)
+}
+
+// Key returns the current key, it must not be modified.
+func (i *expiredIterator) Key() []byte {
+	return i.leaves[i.pos].key
+}
+
+// Value returns the value of the current key.
+func (i *expiredIterator) Value() ValueType {
+	return i.leaves[i.pos].value
+}
+
+// ExpiredAt returns the deadline of the current key.
+func (i *expiredIterator) ExpiredAt() time.Time {
+	return time.Unix(0, i.leaves[i.pos].expires)
+}
+
+// Purge removes the current key, unless it was replaced after the iterator was created.
+// Returns true if the key was removed.
+func (i *expiredIterator) Purge() bool {
+	return i.tree.expire(i.leaves[i.pos]) != nil
+}
+
+// ExpireEvery runs Expire every interval. Blocks until context is canceled.
+func (t *Tree) ExpireEvery(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			t.Expire()
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package art
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertTTL(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			tree.InsertTTL(sequentialKey(i), i, time.Second)
+		} else {
+			tree.Insert(sequentialKey(i), i)
+		}
+	}
+	left, found := tree.TTL(sequentialKey(0))
+	require.True(t, found)
+	require.Equal(t, time.Second, left)
+	_, found = tree.TTL(sequentialKey(1))
+	require.False(t, found)
+
+	clock.advance(time.Second)
+	// expired key is removed by lookup
+	_, found = tree.Get(sequentialKey(0))
+	require.False(t, found)
+	require.Equal(t, 99, tree.Len())
+
+	// replaced key doesn't expire
+	tree.Insert(sequentialKey(2), 2)
+	require.Equal(t, 48, tree.Expire())
+	require.Equal(t, 51, tree.Len())
+	for i := 0; i < 100; i++ {
+		value, found := tree.Get(sequentialKey(i))
+		require.Equal(t, i%2 == 1 || i == 2, found, "key %d", i)
+		if found {
+			require.Equal(t, i, value)
+		}
+	}
+	require.Equal(t, 0, tree.Expire())
+}
+
+func TestExpiredNotReturnedByPathLookups(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	keys := [][]byte{sequentialKey(1), sequentialKey(2), sequentialKey(3)}
+	for _, key := range keys {
+		tree.InsertTTL(key, 1, time.Second)
+	}
+	ref := tree.Ref(keys[1])
+	_, found := ref.Get()
+	require.True(t, found)
+
+	clock.advance(time.Second)
+	_, found = ref.Get()
+	require.False(t, found)
+	out := make([]Result, len(keys))
+	tree.GetSorted(keys, out)
+	require.Equal(t, make([]Result, len(keys)), out)
+	require.Equal(t, 0, tree.Len())
+}
+
+func TestExpireKeepsReplacedLeaf(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	key := []byte("key")
+	tree.InsertTTL(key, 1, time.Second)
+	expired := tree.get(key)
+	clock.advance(time.Second)
+	tree.InsertTTL(key, 2, time.Second)
+	require.Nil(t, tree.expire(expired))
+	value, found := tree.Get(key)
+	require.True(t, found)
+	require.Equal(t, 2, value)
+}
+
+func TestWALReplayTTL(t *testing.T) {
+	var log bytes.Buffer
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock), WithWAL(&log))
+	tree.InsertTTL([]byte("a"), []byte("1"), time.Second)
+	tree.InsertTTL([]byte("b"), []byte("2"), 2*time.Second)
+	clock.advance(time.Second)
+	require.Equal(t, 1, tree.Expire())
+
+	restored := New(WithClock(clock))
+	require.NoError(t, restored.Replay(&log))
+	require.Equal(t, 1, restored.Len())
+	left, found := restored.TTL([]byte("b"))
+	require.True(t, found)
+	require.Equal(t, time.Second, left)
+}
+
+func TestExpiredNotReturnedByOrderedLookups(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	tree.InsertTTL([]byte("aa"), 1, time.Second)
+	tree.Insert([]byte("ab"), 2)
+	tree.Insert([]byte("ba"), 3)
+	tree.InsertTTL([]byte("bb"), 4, time.Second)
+	tree.InsertTTL([]byte("bc"), 5, time.Second)
+	clock.advance(time.Second)
+
+	key, _, found := tree.Min()
+	require.True(t, found)
+	require.Equal(t, []byte("ab"), key)
+	key, _, found = tree.Max()
+	require.True(t, found)
+	require.Equal(t, []byte("ba"), key)
+	key, _, found = tree.MinPrefix([]byte("a"))
+	require.True(t, found)
+	require.Equal(t, []byte("ab"), key)
+	key, _, found = tree.MaxPrefix([]byte("b"))
+	require.True(t, found)
+	require.Equal(t, []byte("ba"), key)
+	require.Equal(t, 2, tree.Len())
+
+	tree.InsertTTL([]byte("aa"), 1, 0)
+	tree.InsertTTL([]byte("bb"), 4, 0)
+	key, _, found = tree.Next([]byte("ab"))
+	require.True(t, found)
+	require.Equal(t, []byte("ba"), key)
+	_, _, found = tree.Next([]byte("ba"))
+	require.False(t, found)
+	_, _, found = tree.Prev([]byte("ab"))
+	require.False(t, found)
+	require.Equal(t, 2, tree.Len())
+
+	matches := New(WithClock(clock))
+	matches.InsertTTL([]byte("/a\x00"), 1, 0)
+	matches.Insert([]byte("/a/b\x00"), 2)
+	var visited [][]byte
+	matches.PathMatches([]byte("/a/b/c"), func(key []byte, _ ValueType) bool {
+		visited = append(visited, key)
+		return true
+	})
+	require.Equal(t, [][]byte{[]byte("/a/b\x00")}, visited)
+	require.Equal(t, 1, matches.Len())
+}
+
+func TestModificationsTreatExpiredAsAbsent(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	key := []byte("aa")
+
+	tree.InsertTTL(key, 1, 0)
+	prev, replaced := tree.Set(key, 2)
+	require.False(t, replaced)
+	require.Nil(t, prev)
+
+	tree.InsertTTL(key, 3, 0)
+	require.True(t, tree.Update(key, func(old ValueType, exists bool) (ValueType, bool) {
+		require.False(t, exists)
+		require.Nil(t, old)
+		return 4, true
+	}))
+	value, found := tree.Get(key)
+	require.True(t, found)
+	require.Equal(t, 4, value)
+
+	tree.InsertTTL(key, 5, 0)
+	_, found = tree.Remove(key)
+	require.False(t, found)
+	require.Equal(t, 0, tree.Len())
+
+	tree.InsertTTL(key, 6, 0)
+	_, stored := tree.InsertIfVersion(key, 7, tree.get(key).version)
+	require.False(t, stored)
+	version, stored := tree.InsertIfVersion(key, 7, 0)
+	require.True(t, stored)
+	value, current, found := tree.GetVersioned(key)
+	require.True(t, found)
+	require.Equal(t, 7, value)
+	require.Equal(t, version, current)
+	require.Equal(t, 1, tree.Len())
+}
+
+func TestExpiredIterator(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	for i := 0; i < 10; i++ {
+		switch {
+		case i%3 == 0:
+			tree.Insert(sequentialKey(i), i)
+		case i%3 == 1:
+			tree.InsertTTL(sequentialKey(i), i, time.Second)
+		default:
+			tree.InsertTTL(sequentialKey(i), i, time.Minute)
+		}
+	}
+	require.False(t, tree.Expired().Next())
+
+	clock.advance(time.Second)
+	// replaced after the iterator was created, not purged
+	iter := tree.Expired()
+	tree.Insert(sequentialKey(4), 4)
+	var keys [][]byte
+	for iter.Next() {
+		keys = append(keys, iter.Key())
+		require.Equal(t, time.Unix(0, 1+int64(time.Second)), iter.ExpiredAt())
+		require.Equal(t, bytes.Equal(iter.Key(), sequentialKey(4)), !iter.Purge())
+	}
+	require.False(t, iter.Next())
+	require.Equal(t, [][]byte{sequentialKey(1), sequentialKey(4), sequentialKey(7)}, keys)
+	require.Equal(t, 8, tree.Len())
+	require.False(t, tree.Expired().Next())
+	require.Zero(t, tree.Expire())
+}
+
+func TestSetPrefixTTL(t *testing.T) {
+	clock := &manualClock{now: 1}
+	tree := New(WithClock(clock))
+	tree.Insert([]byte("session/1"), 1)
+	tree.InsertTTL([]byte("session/2"), 2, time.Hour)
+	tree.Insert([]byte("user/1"), 3)
+
+	// policy applies to existing keys
+	tree.SetPrefixTTL([]byte("session/"), time.Minute)
+	tree.SetPrefixTTL([]byte("session/admin/"), time.Hour)
+	for _, key := range []string{"session/1", "session/2"} {
+		left, found := tree.TTL([]byte(key))
+		require.True(t, found, key)
+		require.Equal(t, time.Minute, left, key)
+	}
+	_, found := tree.TTL([]byte("user/1"))
+	require.False(t, found)
+
+	// longest prefix wins for new keys, explicit ttl overrides the policy
+	tree.Insert([]byte("session/3"), 4)
+	tree.Insert([]byte("session/admin/1"), 5)
+	tree.InsertTTL([]byte("session/4"), 6, time.Second)
+	left, _ := tree.TTL([]byte("session/3"))
+	require.Equal(t, time.Minute, left)
+	left, _ = tree.TTL([]byte("session/admin/1"))
+	require.Equal(t, time.Hour, left)
+	left, _ = tree.TTL([]byte("session/4"))
+	require.Equal(t, time.Second, left)
+
+	clock.advance(time.Minute)
+	require.Equal(t, 4, tree.Expire())
+	keys, _ := tree.Dump()
+	require.Equal(t, [][]byte{[]byte("session/admin/1"), []byte("user/1")}, keys)
+
+	// removed policy makes existing keys persistent
+	tree.SetPrefixTTL([]byte("session/admin/"), 0)
+	tree.SetPrefixTTL([]byte("session/"), 0)
+	_, found = tree.TTL([]byte("session/admin/1"))
+	require.False(t, found)
+	tree.Insert([]byte("session/5"), 7)
+	_, found = tree.TTL([]byte("session/5"))
+	require.False(t, found)
+}
@@ -0,0 +1,127 @@
+package art
+
+import "sync/atomic"
+
+// Txn buffers modifications of the tree, Commit applies all of them atomically.
+// Operations that start during the commit wait until it completes, and consistent
+// iterators collect the keys either before or after it. Iterators that don't use Consistent
+// may observe part of the transaction that is committed while they are visiting the keys.
+// Txn must not be used concurrently.
+type Txn struct {
+	tree   *Tree
+	ops    []txnOp
+	closed bool
+}
+
+type txnOp struct {
+	key    []byte
+	value  ValueType
+	delete bool
+}
+
+// Begin starts a transaction.
+func (t *Tree) Begin() *Txn {
+	return &Txn{tree: t}
+}
+
+// Insert buffers insertion of the key.
+func (x *Txn) Insert(key []byte, value ValueType) {
+	x.ops = append(x.ops, txnOp{key: x.tree.keyOf(key), value: value})
+}
+
+// Delete buffers removal of the key.
+func (x *Txn) Delete(key []byte) {
+	x.ops = append(x.ops, txnOp{key: x.tree.keyOf(key), delete: true})
+}
+
+// Get returns value of the key, including buffered modifications.
+func (x *Txn) Get(key []byte) (ValueType, bool) {
+	key = x.tree.keyOf(key)
+	for i := len(x.ops) - 1; i >= 0; i-- {
+		if op := x.ops[i]; string(op.key) == string(key) {
+			return op.value, !op.delete
+		}
+	}
+	l := x.tree.lookup(key, nil)
+	if l == nil {
+		return nil, false
+	}
+	return l.value, true
+}
+
+// Len returns number of buffered modifications.
+func (x *Txn) Len() int {
+	return len(x.ops)
+}
+
+// Rollback discards buffered modifications.
+func (x *Txn) Rollback() {
+	x.ops = nil
+	x.closed = true
+}
+
+// Commit applies buffered modifications in the order they were made.
+// Lock of the tree is held until all of them are applied, operations that start
+// during the commit wait until it completes. Admission policy configured by WithTinyLFU
+// is not applied to the keys of the transaction.
+// ErrTxnClosed is returned if transaction was already committed or rolled back.
+func (x *Txn) Commit() error {
+	if x.closed {
+		return ErrTxnClosed
+	}
+	x.closed = true
+	t := x.tree
+	t.checkPoisoned()
+	if len(x.ops) == 0 {
+		return nil
+	}
+	if t.limiter != nil {
+		t.limiter.wait()
+	}
+	// upserts[i] is a modification of the ops[i], removed[i] is a leaf removed by the ops[i]
+	upserts := make([]upsert, len(x.ops))
+	removed := make([]*leaf, len(x.ops))
+	for i, op := range x.ops {
+		if t.profiler != nil {
+			t.profiler.observe(t, op.key)
+		}
+		if !op.delete {
+			if t.guard != nil {
+				t.guard.inserting("commit", op.key)
+			}
+			l := t.newLeaf(op.key, op.value)
+			upserts[i] = upsert{key: l.key, leaf: l, tree: t}
+		}
+	}
+	// operations that already passed the root complete concurrently with the commit,
+	// gate stands in for the lock of the tree while it is held
+	var gate olock
+	if t.wal != nil {
+		t.wal.lockAll()
+	}
+	t.lock.Lock()
+	func() {
+		defer t.lock.Unlock()
+		for i, op := range x.ops {
+			if op.delete {
+				atomic.AddUint64(&t.writes, 1)
+				removed[i] = t.del(op.key, nil, &gate)
+				continue
+			}
+			t.store(&upserts[i], &gate)
+		}
+	}()
+	if t.wal != nil {
+		t.wal.batch(t.encode, x.ops)
+		// released before eviction that is triggered by upserted
+		t.wal.unlockAll()
+	}
+	for i, op := range x.ops {
+		if op.delete {
+			t.deleted(removed[i])
+		} else {
+			t.upserted(&upserts[i])
+		}
+	}
+	return nil
+}
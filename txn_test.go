@@ -0,0 +1,117 @@
+package art
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxn(t *testing.T) {
+	tree := New(WithMisuseDetection())
+	tree.Insert([]byte("a"), 1)
+	tree.Insert([]byte("b"), 2)
+
+	txn := tree.Begin()
+	txn.Insert([]byte("c"), 3)
+	txn.Delete([]byte("a"))
+	txn.Insert([]byte("b"), 20)
+	txn.Insert([]byte("d"), 4)
+	txn.Delete([]byte("d"))
+	require.Equal(t, 5, txn.Len())
+
+	value, found := txn.Get([]byte("b"))
+	require.True(t, found)
+	require.Equal(t, 20, value)
+	_, found = txn.Get([]byte("a"))
+	require.False(t, found)
+	_, found = txn.Get([]byte("d"))
+	require.False(t, found)
+	// not visible before commit
+	_, found = tree.Get([]byte("c"))
+	require.False(t, found)
+
+	require.NoError(t, txn.Commit())
+	keys, values := tree.Dump()
+	require.Equal(t, [][]byte{[]byte("b"), []byte("c")}, keys)
+	require.Equal(t, []ValueType{20, 3}, values)
+	require.Equal(t, 2, tree.Len())
+	require.True(t, errors.Is(txn.Commit(), ErrTxnClosed))
+
+	txn = tree.Begin()
+	txn.Delete([]byte("b"))
+	txn.Rollback()
+	require.True(t, errors.Is(txn.Commit(), ErrTxnClosed))
+	require.Equal(t, 2, tree.Len())
+}
+
+func TestTxnGetTransformedKey(t *testing.T) {
+	// transform that is not idempotent
+	tree := New(WithKeyTransform(func(key []byte) []byte {
+		return append(append([]byte(nil), key...), 0)
+	}))
+	tree.Insert([]byte("a"), 1)
+	txn := tree.Begin()
+	value, found := txn.Get([]byte("a"))
+	require.True(t, found)
+	require.Equal(t, 1, value)
+}
+
+func TestTxnAtomicVisibility(t *testing.T) {
+	skipUnsafe(t)
+	// every transaction writes the same value to all keys, readers must never observe
+	// keys with different values
+	const keys = 300
+	var tree Tree
+	key := func(i int) []byte {
+		return []byte{byte(i >> 8), byte(i)}
+	}
+	for i := 0; i < keys; i++ {
+		tree.Insert(key(i), 0)
+	}
+	var (
+		wg        sync.WaitGroup
+		stop      = make(chan struct{})
+		committed int64
+	)
+	for w := 1; w <= 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for gen := w; ; gen += 2 {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				txn := tree.Begin()
+				for _, i := range rng.Perm(keys) {
+					if i%7 == 0 {
+						txn.Delete(key(i))
+					}
+					txn.Insert(key(i), gen)
+				}
+				require.NoError(t, txn.Commit())
+				atomic.AddInt64(&committed, 1)
+			}
+		}(w)
+	}
+	for i := 0; i < 200 || atomic.LoadInt64(&committed) < 100; i++ {
+		count := 0
+		var first ValueType
+		for iter := tree.Iterator(nil, nil).Consistent(); iter.Next(); count++ {
+			if first == nil {
+				first = iter.Value()
+			}
+			require.Equal(t, first, iter.Value())
+		}
+		require.Equal(t, keys, count)
+	}
+	close(stop)
+	wg.Wait()
+	require.Equal(t, keys, tree.Len())
+}
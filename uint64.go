@@ -0,0 +1,93 @@
+package art
+
+import "encoding/binary"
+
+// Uint64Tree is a tree specialized for fixed 8-byte keys.
+// Keys are stored inline in the leaf, encoded in big endian order to preserve
+// natural order of integers during iteration.
+type Uint64Tree struct {
+	tree Tree
+}
+
+// leaf64 is a leaf allocated together with the key storage.
+type leaf64 struct {
+	leaf
+	key [8]byte
+}
+
+func (t *Uint64Tree) Insert(key uint64, value ValueType) {
+	l := &leaf64{}
+	binary.BigEndian.PutUint64(l.key[:], key)
+	l.leaf.key = l.key[:]
+	l.leaf.value = value
+	t.tree.insert(&l.leaf)
+}
+
+// Get uses simplified descent, key bytes are extracted directly from the integer
+// and leaf is compared without length checks.
+func (t *Uint64Tree) Get(key uint64) (ValueType, bool) {
+restart:
+	version, _ := t.tree.lock.RLock()
+	parent := &t.tree.lock
+	next := t.tree.root
+	depth := 0
+	for {
+		switch n := next.(type) {
+		case nil:
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return nil, false
+		case *leaf:
+			found := binary.BigEndian.Uint64(n.key) == key
+			value := n.value
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			if !found {
+				return nil, false
+			}
+			return value, true
+		case *inner:
+			nversion, obsolete := n.lock.RLock()
+			if obsolete || parent.RUnlock(version, nil) {
+				goto restart
+			}
+			next = nil
+			matched := true
+			for i := 0; i < n.prefixLen; i++ {
+				if n.prefix[i] != keyByte(key, depth+i) {
+					matched = false
+					break
+				}
+			}
+			depth += n.prefixLen
+			if matched {
+				_, next = n.node.child(keyByte(key, depth))
+			}
+			depth++
+			parent, version = &n.lock, nversion
+		}
+	}
+}
+
+func (t *Uint64Tree) Delete(key uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], key)
+	t.tree.Delete(buf[:])
+}
+
+// Iterate visits all keys in ascending order, until fn returns false.
+func (t *Uint64Tree) Iterate(fn func(key uint64, value ValueType) bool) {
+	iter := t.tree.Iterator(nil, nil)
+	for iter.Next() {
+		if !fn(binary.BigEndian.Uint64(iter.Key()), iter.Value()) {
+			return
+		}
+	}
+}
+
+// keyByte returns byte at position i of the big endian encoded key.
+func keyByte(key uint64, i int) byte {
+	return byte(key >> (56 - 8*i))
+}
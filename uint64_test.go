@@ -0,0 +1,61 @@
+package art
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUint64Tree(t *testing.T) {
+	var tree Uint64Tree
+	keys := map[uint64]int{}
+	for i := 0; i < 10_000; i++ {
+		key := rand.Uint64()
+		if i%2 == 0 {
+			// shared prefixes
+			key >>= 40
+		}
+		tree.Insert(key, i)
+		keys[key] = i
+	}
+	sorted := make([]uint64, 0, len(keys))
+	for key, value := range keys {
+		rst, found := tree.Get(key)
+		require.True(t, found)
+		require.Equal(t, value, rst)
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	rst := []uint64{}
+	tree.Iterate(func(key uint64, _ ValueType) bool {
+		rst = append(rst, key)
+		return true
+	})
+	require.Equal(t, sorted, rst)
+
+	for key := range keys {
+		tree.Delete(key)
+		_, found := tree.Get(key)
+		require.False(t, found)
+	}
+	require.True(t, tree.tree.Empty())
+}
+
+func BenchmarkUint64Lookups(b *testing.B) {
+	rand.Seed(0)
+	var tree Uint64Tree
+	keys := make([]uint64, 1_000_000)
+	for i := range keys {
+		keys[i] = rand.Uint64()
+		tree.Insert(keys[i], i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tree.Get(keys[i%len(keys)])
+	}
+}
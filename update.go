@@ -0,0 +1,56 @@
+package art
+
+// Set inserts the key and returns previous value, if it was replaced.
+func (t *Tree) Set(key []byte, value ValueType) (ValueType, bool) {
+	old := t.insert(t.newLeaf(t.keyOf(key), value))
+	if old == nil {
+		return nil, false
+	}
+	return old.value, true
+}
+
+// Update atomically replaces value of the key with the value returned by fn.
+// fn receives current value and true if key exists, and returns new value and true
+// if it should be stored. If false is returned the tree is not modified.
+// fn is executed while nodes on the path of the key are locked for writing, it must be
+// short and must not use the tree. If fn panics the tree is poisoned.
+// Returns true if value was stored.
+func (t *Tree) Update(key []byte, fn func(old ValueType, exists bool) (ValueType, bool)) bool {
+	key = t.keyOf(key)
+	op := upsert{key: key, resolve: func(old *leaf) *leaf {
+		var value ValueType
+		if old != nil {
+			value = old.value
+		}
+		value, store := fn(value, old != nil)
+		if !store {
+			return old
+		}
+		return t.newLeaf(key, value)
+	}}
+	t.upsert(&op)
+	return op.stored != nil && op.stored != op.old
+}
+
+// GetOrInsert returns value of the key if it exists, otherwise inserts the value,
+// same as sync.Map.LoadOrStore. loaded is true if the value was loaded.
+// Key is checked and inserted in a single descent, while nodes on the path are locked for writing.
+// If the new key was not admitted by WithTinyLFU, nothing is stored and ErrFull is returned.
+func (t *Tree) GetOrInsert(key []byte, value ValueType) (actual ValueType, loaded bool, err error) {
+	key = t.keyOf(key)
+	l := t.newLeaf(key, value)
+	op := upsert{key: key, resolve: func(old *leaf) *leaf {
+		if old != nil {
+			return old
+		}
+		return l
+	}}
+	t.upsert(&op)
+	switch {
+	case op.stored == nil:
+		return nil, false, ErrFull
+	case op.stored == op.live:
+		return op.live.value, true, nil
+	}
+	return value, false, nil
+}
@@ -0,0 +1,155 @@
+package art
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func increment(old ValueType, exists bool) (ValueType, bool) {
+	if !exists {
+		return 1, true
+	}
+	return old.(int) + 1, true
+}
+
+func TestUpdate(t *testing.T) {
+	var tree Tree
+	key := []byte("key")
+	require.True(t, tree.Update(key, increment))
+	require.True(t, tree.Update(key, increment))
+	value, _ := tree.Get(key)
+	require.Equal(t, 2, value)
+
+	require.False(t, tree.Update(key, func(old ValueType, exists bool) (ValueType, bool) {
+		require.True(t, exists)
+		return nil, false
+	}))
+	require.False(t, tree.Update([]byte("other"), func(old ValueType, exists bool) (ValueType, bool) {
+		require.False(t, exists)
+		return nil, false
+	}))
+	_, found := tree.Get([]byte("other"))
+	require.False(t, found)
+	require.Equal(t, 1, tree.Len())
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	keys := [][]byte{{1, 1}, {1, 2}, {2, 1}, {3}}
+	n, updates := 8, 1000
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates; j++ {
+				tree.Update(keys[j%len(keys)], increment)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, key := range keys {
+		value, _ := tree.Get(key)
+		require.Equal(t, n*updates/len(keys), value)
+	}
+}
+
+func TestUpdatePanicPoisons(t *testing.T) {
+	var tree Tree
+	tree.Insert([]byte{1}, 1)
+	tree.Insert([]byte{2}, 2)
+	require.PanicsWithValue(t, "user error", func() {
+		tree.Update([]byte{1}, func(ValueType, bool) (ValueType, bool) {
+			panic("user error")
+		})
+	})
+	root := tree.root.(*inner)
+	root.lock.Lock()
+	root.lock.Unlock()
+	require.True(t, errors.Is(tree.Poisoned(), ErrPoisoned))
+}
+
+func TestSet(t *testing.T) {
+	var tree Tree
+	prev, replaced := tree.Set([]byte{1}, 1)
+	require.False(t, replaced)
+	require.Nil(t, prev)
+	tree.Insert([]byte{2}, 2)
+
+	prev, replaced = tree.Set([]byte{1}, 10)
+	require.True(t, replaced)
+	require.Equal(t, 1, prev)
+	prev, replaced = tree.Set([]byte{2}, 20)
+	require.True(t, replaced)
+	require.Equal(t, 2, prev)
+
+	value, _ := tree.Get([]byte{1})
+	require.Equal(t, 10, value)
+	require.Equal(t, 2, tree.Len())
+}
+
+func TestGetOrInsert(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	key := []byte("key")
+	actual, loaded, err := tree.GetOrInsert(key, 1)
+	require.NoError(t, err)
+	require.False(t, loaded)
+	require.Equal(t, 1, actual)
+	actual, loaded, err = tree.GetOrInsert(key, 2)
+	require.NoError(t, err)
+	require.True(t, loaded)
+	require.Equal(t, 1, actual)
+	require.Equal(t, 1, tree.Len())
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		stored  int
+		results = map[ValueType]struct{}{}
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual, loaded, err := tree.GetOrInsert([]byte("concurrent"), i)
+			mu.Lock()
+			defer mu.Unlock()
+			require.NoError(t, err)
+			if !loaded {
+				stored++
+			}
+			results[actual] = struct{}{}
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, 1, stored)
+	require.Len(t, results, 1)
+}
+
+func TestGetOrInsertRejected(t *testing.T) {
+	limit := 10
+	tree := New(WithSampledEviction(limit, limit), WithTinyLFU(1024))
+	// sketch is saturated by the stored keys, every one of them is more frequent than the new key
+	for r := 0; r < 10; r++ {
+		for i := 0; i < limit; i++ {
+			tree.Insert(sequentialKey(i), i)
+		}
+	}
+	actual, loaded, err := tree.GetOrInsert(sequentialKey(limit), limit)
+	require.Equal(t, ErrFull, err)
+	require.False(t, loaded)
+	require.Nil(t, actual)
+	_, found := tree.Get(sequentialKey(limit))
+	require.False(t, found)
+
+	// stored key is loaded regardless of admission
+	actual, loaded, err = tree.GetOrInsert(sequentialKey(0), -1)
+	require.NoError(t, err)
+	require.True(t, loaded)
+	require.Equal(t, 0, actual)
+}
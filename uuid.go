@@ -0,0 +1,79 @@
+package art
+
+import "encoding/binary"
+
+// UUIDTree is a tree specialized for fixed 16-byte keys, such as UUIDs or 128-bit integers.
+// Keys are stored inline in the leaf, and are compared as two 64-bit words.
+type UUIDTree struct {
+	tree Tree
+}
+
+// leaf128 is a leaf allocated together with the key storage.
+type leaf128 struct {
+	leaf
+	key [16]byte
+}
+
+func (t *UUIDTree) Insert(key [16]byte, value ValueType) {
+	l := &leaf128{key: key}
+	l.leaf.key = l.key[:]
+	l.leaf.value = value
+	t.tree.insert(&l.leaf)
+}
+
+// Get uses simplified descent, key bytes are read directly from the array
+// and leaf is compared without length checks.
+func (t *UUIDTree) Get(key [16]byte) (ValueType, bool) {
+	hi, lo := binary.BigEndian.Uint64(key[:8]), binary.BigEndian.Uint64(key[8:])
+restart:
+	version, _ := t.tree.lock.RLock()
+	parent := &t.tree.lock
+	next := t.tree.root
+	depth := 0
+	for {
+		switch n := next.(type) {
+		case nil:
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			return nil, false
+		case *leaf:
+			found := binary.BigEndian.Uint64(n.key[:8]) == hi && binary.BigEndian.Uint64(n.key[8:16]) == lo
+			value := n.value
+			if parent.RUnlock(version, nil) {
+				goto restart
+			}
+			if !found {
+				return nil, false
+			}
+			return value, true
+		case *inner:
+			nversion, obsolete := n.lock.RLock()
+			if obsolete || parent.RUnlock(version, nil) {
+				goto restart
+			}
+			next = nil
+			if depth+n.prefixLen < len(key) && string(n.prefix[:n.prefixLen]) == string(key[depth:depth+n.prefixLen]) {
+				_, next = n.node.child(key[depth+n.prefixLen])
+			}
+			depth += n.prefixLen + 1
+			parent, version = &n.lock, nversion
+		}
+	}
+}
+
+func (t *UUIDTree) Delete(key [16]byte) {
+	t.tree.Delete(key[:])
+}
+
+// Iterate visits all keys in ascending order, until fn returns false.
+func (t *UUIDTree) Iterate(fn func(key [16]byte, value ValueType) bool) {
+	var key [16]byte
+	iter := t.tree.Iterator(nil, nil)
+	for iter.Next() {
+		copy(key[:], iter.Key())
+		if !fn(key, iter.Value()) {
+			return
+		}
+	}
+}
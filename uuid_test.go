@@ -0,0 +1,63 @@
+package art
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDTree(t *testing.T) {
+	var tree UUIDTree
+	keys := map[[16]byte]int{}
+	for i := 0; i < 10_000; i++ {
+		var key [16]byte
+		rand.Read(key[:])
+		if i%2 == 0 {
+			// shared prefixes
+			copy(key[:], "prefix-")
+		}
+		tree.Insert(key, i)
+		keys[key] = i
+	}
+	sorted := make([][16]byte, 0, len(keys))
+	for key, value := range keys {
+		rst, found := tree.Get(key)
+		require.True(t, found)
+		require.Equal(t, value, rst)
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	rst := [][16]byte{}
+	tree.Iterate(func(key [16]byte, _ ValueType) bool {
+		rst = append(rst, key)
+		return true
+	})
+	require.Equal(t, sorted, rst)
+
+	for key := range keys {
+		tree.Delete(key)
+		_, found := tree.Get(key)
+		require.False(t, found)
+	}
+	require.True(t, tree.tree.Empty())
+}
+
+func BenchmarkUUIDLookups(b *testing.B) {
+	rng := rand.New(rand.NewSource(0))
+	var tree UUIDTree
+	keys := make([][16]byte, 1_000_000)
+	for i := range keys {
+		rng.Read(keys[i][:])
+		tree.Insert(keys[i], i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = tree.Get(keys[i%len(keys)])
+	}
+}
@@ -0,0 +1,31 @@
+package art
+
+// GetVersioned returns value together with the version of the key.
+// Version increases every time when the value is replaced, and is never reused
+// for the same key, even if key was deleted and inserted again.
+func (t *Tree) GetVersioned(key []byte) (ValueType, uint64, bool) {
+	l := t.lookup(t.keyOf(key), nil)
+	if l == nil {
+		return nil, 0, false
+	}
+	return l.value, l.version, true
+}
+
+// InsertIfVersion replaces value of the key only if current version of the key
+// is equal to the version, zero version requires the key to be absent.
+// Returns new version and true if value was stored.
+func (t *Tree) InsertIfVersion(key []byte, value ValueType, version uint64) (uint64, bool) {
+	key = t.keyOf(key)
+	l := t.newLeaf(key, value)
+	op := upsert{key: key, resolve: func(old *leaf) *leaf {
+		if old == nil && version == 0 || old != nil && old.version == version {
+			return l
+		}
+		return old
+	}}
+	t.upsert(&op)
+	if op.stored != l {
+		return 0, false
+	}
+	return l.version, true
+}
@@ -0,0 +1,111 @@
+package art
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertIfVersion(t *testing.T) {
+	var tree Tree
+	key := []byte("key")
+	_, _, found := tree.GetVersioned(key)
+	require.False(t, found)
+
+	_, stored := tree.InsertIfVersion(key, 1, 1)
+	require.False(t, stored)
+	v1, stored := tree.InsertIfVersion(key, 1, 0)
+	require.True(t, stored)
+	_, stored = tree.InsertIfVersion(key, 2, 0)
+	require.False(t, stored)
+
+	value, version, found := tree.GetVersioned(key)
+	require.True(t, found)
+	require.Equal(t, 1, value)
+	require.Equal(t, v1, version)
+
+	v2, stored := tree.InsertIfVersion(key, 2, v1)
+	require.True(t, stored)
+	require.NotEqual(t, v1, v2)
+	_, stored = tree.InsertIfVersion(key, 3, v1)
+	require.False(t, stored)
+
+	tree.Insert(key, 4)
+	_, v3, _ := tree.GetVersioned(key)
+	require.NotEqual(t, v2, v3)
+
+	tree.Delete(key)
+	tree.Insert(key, 5)
+	_, v4, _ := tree.GetVersioned(key)
+	require.NotEqual(t, v3, v4)
+	_, stored = tree.InsertIfVersion(key, 6, v3)
+	require.False(t, stored)
+}
+
+func TestInsertIfVersionConcurrent(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	key := []byte("counter")
+	tree.Insert(key, 0)
+	// neighbours to have the key stored in the inner node
+	tree.Insert([]byte("other"), 0)
+
+	n, increments := 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; {
+				value, version, _ := tree.GetVersioned(key)
+				if _, stored := tree.InsertIfVersion(key, value.(int)+1, version); stored {
+					j++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	value, _ := tree.Get(key)
+	require.Equal(t, n*increments, value)
+}
+
+func TestVersionIncreasesOnReplace(t *testing.T) {
+	skipUnsafe(t)
+	var tree Tree
+	key := []byte("key")
+	tree.Insert(key, 0)
+	tree.Insert([]byte("other"), 0)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2_000; i++ {
+				tree.Insert(key, w)
+				if i%100 == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	var last uint64
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		_, version, found := tree.GetVersioned(key)
+		require.True(t, found)
+		require.GreaterOrEqual(t, version, last)
+		last = version
+	}
+}
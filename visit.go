@@ -0,0 +1,213 @@
+package art
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SnapshotNode is an inner node of the tree serialized by MarshalBinary.
+type SnapshotNode struct {
+	// Depth is an offset in the key where the node prefix starts.
+	Depth int
+	// Kind is a name of the node type (node4, node16, node48, node256).
+	Kind string
+	// Children is a number of children in the node.
+	Children int
+	// Prefix of the node, valid only during the callback.
+	Prefix []byte
+}
+
+// SnapshotVisitor receives content of the tree serialized by MarshalBinary.
+type SnapshotVisitor struct {
+	// Entry is called for every key in ascending order. Value is encoded as by the codec
+	// configured with WithValueCodec. Key and value are valid only during the call.
+	Entry func(key, value []byte) error
+	// Node is called for every inner node before its children, optional.
+	Node func(SnapshotNode) error
+}
+
+// VisitSnapshot reads the tree serialized by MarshalBinary from r and calls the visitor,
+// without building the tree in memory. Only the longest key and value are buffered.
+// Error returned by the visitor stops the visit and is returned as is.
+// ErrCorrupt is returned if data is malformed, or number of keys doesn't match the header,
+// visitor may be called for the entries that precede the malformed data.
+func VisitSnapshot(r io.Reader, v SnapshotVisitor) error {
+	s := snapshotReader{r: bufio.NewReader(r), visitor: v}
+	header := make([]byte, len(serializationMagic)+1)
+	if _, err := io.ReadFull(s.r, header); err != nil || string(header[:len(serializationMagic)]) != serializationMagic {
+		return fmt.Errorf("%w: invalid header", ErrCorrupt)
+	}
+	if version := header[len(serializationMagic)]; version != serializationVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrCorrupt, version)
+	}
+	size, err := s.uvarint()
+	if err != nil {
+		return err
+	}
+	tag, err := s.byte()
+	if err != nil {
+		return err
+	}
+	if tag != tagEmpty {
+		if err := s.node(tag); err != nil {
+			return err
+		}
+	}
+	if _, err := s.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("%w: trailing bytes", ErrCorrupt)
+	}
+	if s.keys != size {
+		return fmt.Errorf("%w: expected %d keys, visited %d", ErrCorrupt, size, s.keys)
+	}
+	return nil
+}
+
+// snapshotReader decodes the format of MarshalBinary from the stream.
+type snapshotReader struct {
+	r       *bufio.Reader
+	visitor SnapshotVisitor
+
+	// path is a part of the key stored by the ancestors of the current node.
+	path  []byte
+	value []byte
+	keys  uint64
+}
+
+// readChunk limits a single allocation, so that corrupted length fails on the end of data
+// instead of allocating the whole length up front.
+const readChunk = 1 << 16
+
+func unexpectedEnd(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: unexpected end of data", ErrCorrupt)
+	}
+	return err
+}
+
+func (s *snapshotReader) byte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, unexpectedEnd(err)
+	}
+	return b, nil
+}
+
+func (s *snapshotReader) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, unexpectedEnd(err)
+		}
+		return 0, fmt.Errorf("%w: invalid varint", ErrCorrupt)
+	}
+	return v, nil
+}
+
+// read appends length-prefixed bytes to dst.
+func (s *snapshotReader) read(dst []byte) ([]byte, error) {
+	lth, err := s.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for lth > 0 {
+		chunk := lth
+		if chunk > readChunk {
+			chunk = readChunk
+		}
+		start := len(dst)
+		dst = append(dst, make([]byte, chunk)...)
+		if _, err := io.ReadFull(s.r, dst[start:]); err != nil {
+			return nil, unexpectedEnd(err)
+		}
+		lth -= chunk
+	}
+	return dst, nil
+}
+
+func (s *snapshotReader) node(tag byte) error {
+	switch {
+	case tag == tagLeaf:
+		return s.leaf()
+	case tag >= tagInner && tag < tagInner+kindsCount:
+		return s.inner(int(tag - tagInner))
+	}
+	return fmt.Errorf("%w: unknown node tag %d", ErrCorrupt, tag)
+}
+
+func (s *snapshotReader) leaf() error {
+	depth := len(s.path)
+	key, err := s.read(s.path)
+	if err != nil {
+		return err
+	}
+	// suffix is appended to the path, it is truncated back for the next sibling
+	s.path = key[:depth]
+	s.value, err = s.read(s.value[:0])
+	if err != nil {
+		return err
+	}
+	s.keys++
+	if s.visitor.Entry != nil {
+		return s.visitor.Entry(key, s.value)
+	}
+	return nil
+}
+
+func (s *snapshotReader) inner(kind int) error {
+	lth, err := s.byte()
+	if err != nil {
+		return err
+	}
+	if int(lth) > maxPrefixLen {
+		return fmt.Errorf("%w: prefix length %d", ErrCorrupt, lth)
+	}
+	depth := len(s.path)
+	for i := 0; i < int(lth); i++ {
+		b, err := s.byte()
+		if err != nil {
+			return err
+		}
+		s.path = append(s.path, b)
+	}
+	total, err := s.uvarint()
+	if err != nil {
+		return err
+	}
+	if total == 0 || total > 256 {
+		return fmt.Errorf("%w: %d children", ErrCorrupt, total)
+	}
+	if s.visitor.Node != nil {
+		err := s.visitor.Node(SnapshotNode{
+			Depth:    depth,
+			Kind:     kindNames[kind],
+			Children: int(total),
+			Prefix:   s.path[depth:],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	prefixed := len(s.path)
+	for i := uint64(0); i < total; i++ {
+		b, err := s.byte()
+		if err != nil {
+			return err
+		}
+		if i > 0 && b <= s.path[prefixed] {
+			return fmt.Errorf("%w: edge %x is out of order", ErrCorrupt, b)
+		}
+		s.path = append(s.path[:prefixed], b)
+		tag, err := s.byte()
+		if err != nil {
+			return err
+		}
+		if err := s.node(tag); err != nil {
+			return err
+		}
+	}
+	s.path = s.path[:depth]
+	return nil
+}
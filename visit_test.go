@@ -0,0 +1,99 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisitSnapshot(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := New()
+	for i := 0; i < 10_000; i++ {
+		key := make([]byte, 1+rng.Intn(20))
+		for j := range key {
+			key[j] = byte(rng.Intn(3))
+		}
+		key = append(key, 0xff)
+		tree.Insert(key, key[:rng.Intn(len(key))])
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	var (
+		keys   [][]byte
+		values []ValueType
+		nodes  [kindsCount]int
+	)
+	require.NoError(t, VisitSnapshot(bytes.NewReader(data), SnapshotVisitor{
+		Entry: func(key, value []byte) error {
+			keys = append(keys, append([]byte(nil), key...))
+			if len(value) == 0 {
+				values = append(values, nil)
+			} else {
+				values = append(values, append([]byte(nil), value...))
+			}
+			return nil
+		},
+		Node: func(n SnapshotNode) error {
+			for kind, name := range kindNames {
+				if name == n.Kind {
+					nodes[kind]++
+				}
+			}
+			return nil
+		},
+	}))
+	expectedKeys, expectedValues := tree.Dump()
+	require.Equal(t, expectedKeys, keys)
+	for i, value := range expectedValues {
+		if len(value.([]byte)) == 0 {
+			expectedValues[i] = nil
+		}
+	}
+	require.Equal(t, expectedValues, values)
+	require.Equal(t, tree.ExactStats().Nodes, nodes)
+}
+
+func TestVisitSnapshotEmpty(t *testing.T) {
+	var tree Tree
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, VisitSnapshot(bytes.NewReader(data), SnapshotVisitor{
+		Entry: func(_, _ []byte) error {
+			return errors.New("tree is empty")
+		},
+	}))
+}
+
+func TestVisitSnapshotErrors(t *testing.T) {
+	var tree Tree
+	for i := 0; i < 100; i++ {
+		tree.Insert(sequentialKey(i*7), sequentialKey(i))
+	}
+	data, err := tree.MarshalBinary()
+	require.NoError(t, err)
+	for i := 0; i < len(data); i++ {
+		err := VisitSnapshot(bytes.NewReader(data[:i]), SnapshotVisitor{})
+		require.True(t, errors.Is(err, ErrCorrupt), "length %d: %v", i, err)
+	}
+	err = VisitSnapshot(bytes.NewReader(append(data, 0)), SnapshotVisitor{})
+	require.True(t, errors.Is(err, ErrCorrupt), err)
+
+	stop := errors.New("stop")
+	visited := 0
+	err = VisitSnapshot(bytes.NewReader(data), SnapshotVisitor{
+		Entry: func(_, _ []byte) error {
+			visited++
+			if visited == 10 {
+				return stop
+			}
+			return nil
+		},
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 10, visited)
+}
@@ -0,0 +1,327 @@
+package art
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sync"
+)
+
+// Record tags in the write-ahead log.
+const (
+	walInsert byte = iota + 1
+	walDelete
+	walDeleteRange
+	walClear
+	walBatch
+	walExpiring
+)
+
+// walStripes is a number of locks that order records of the modifications of the same key.
+const walStripes = 64
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WithWAL logs every modification of the tree to w, so that content of the tree can be
+// restored by Replay, for example on top of the tree restored by UnmarshalBinary.
+// Record is written after the modification is applied, before the modifying call returns.
+// Modifications of the same key are logged in the order they were applied, DeleteIn, Clear
+// and Commit are ordered with every other modification. Keys removed by eviction are logged
+// as deletions, UnmarshalBinary is not logged.
+// Values are encoded with the codec configured by WithValueCodec, by default values must be
+// []byte or nil. If record can't be encoded or written, logging stops and the error
+// is returned by WALError. Writes to w are serialized, w must not be shared with other trees.
+//
+// Format:
+//
+//	record:       uvarint length | crc32c of payload, little endian | payload
+//	insert:       1 | uvarint length | key | uvarint length | value
+//	delete:       2 | uvarint length | key
+//	delete range: 3 | inclusivity | uvarint length | start | uvarint length | end
+//	clear:        4
+//	batch:        5 | uvarint number of modifications | (insert | delete)...
+//	expiring:     6 | uvarint deadline | uvarint length | key | uvarint length | value
+func WithWAL(w io.Writer) Option {
+	return func(t *Tree) {
+		t.wal = &wal{w: w}
+	}
+}
+
+type wal struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+
+	stripes [walStripes]sync.Mutex
+}
+
+// stripe returns lock that orders modifications of the key, hash is fnv-1a.
+func (w *wal) stripe(key []byte) *sync.Mutex {
+	hash := uint32(2166136261)
+	for _, b := range key {
+		hash ^= uint32(b)
+		hash *= 16777619
+	}
+	return &w.stripes[hash%walStripes]
+}
+
+// lockAll orders the caller with modifications of every key.
+func (w *wal) lockAll() {
+	for i := range w.stripes {
+		w.stripes[i].Lock()
+	}
+}
+
+func (w *wal) unlockAll() {
+	for i := range w.stripes {
+		w.stripes[i].Unlock()
+	}
+}
+
+func (w *wal) insert(encode func([]byte, ValueType) []byte, l *leaf) {
+	w.log(encode, func(e *encoder) error {
+		if l.expires != 0 {
+			// key inserted by InsertTTL
+			e.buf = append(e.buf, walExpiring)
+			e.buf = binary.AppendUvarint(e.buf, uint64(l.expires))
+			e.bytes(l.key)
+			return e.value(l.value)
+		}
+		return e.insert(l.key, l.value)
+	})
+}
+
+func (w *wal) delete(key []byte) {
+	w.log(nil, func(e *encoder) error {
+		e.buf = append(e.buf, walDelete)
+		e.bytes(key)
+		return nil
+	})
+}
+
+func (w *wal) deleteIn(r Range) {
+	w.log(nil, func(e *encoder) error {
+		e.buf = append(e.buf, walDeleteRange, byte(r.Inclusivity))
+		e.bytes(r.Start)
+		e.bytes(r.End)
+		return nil
+	})
+}
+
+func (w *wal) clear() {
+	w.log(nil, func(e *encoder) error {
+		e.buf = append(e.buf, walClear)
+		return nil
+	})
+}
+
+func (w *wal) batch(encode func([]byte, ValueType) []byte, ops []txnOp) {
+	w.log(encode, func(e *encoder) error {
+		e.buf = append(e.buf, walBatch)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(ops)))
+		for _, op := range ops {
+			if op.delete {
+				e.buf = append(e.buf, walDelete)
+				e.bytes(op.key)
+			} else if err := e.insert(op.key, op.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// log frames the payload appended by fill and writes it, unless logging already failed.
+func (w *wal) log(encode func([]byte, ValueType) []byte, fill func(*encoder) error) {
+	e := encoder{encode: encode}
+	err := fill(&e)
+	var frame []byte
+	if err == nil {
+		frame = make([]byte, 0, binary.MaxVarintLen64+4+len(e.buf))
+		frame = binary.AppendUvarint(frame, uint64(len(e.buf)))
+		frame = binary.LittleEndian.AppendUint32(frame, crc32.Checksum(e.buf, castagnoli))
+		frame = append(frame, e.buf...)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if err == nil {
+		_, err = w.w.Write(frame)
+	}
+	w.err = err
+}
+
+func (e *encoder) insert(key []byte, value ValueType) error {
+	e.buf = append(e.buf, walInsert)
+	e.bytes(key)
+	return e.value(value)
+}
+
+// WALError returns error that stopped logging configured by WithWAL, nil if every
+// modification was logged.
+func (t *Tree) WALError() error {
+	if t.wal == nil {
+		return nil
+	}
+	t.wal.mu.Lock()
+	defer t.wal.mu.Unlock()
+	return t.wal.err
+}
+
+// Replay applies modifications logged by WithWAL in the order they were logged.
+// Keys are applied as they were stored, key transform is not applied again.
+// Records before the corrupted or truncated record are applied, and the error wrapping
+// ErrCorrupt is returned, so that the log with a torn tail can be replayed after a crash.
+// If the tree logs modifications, replayed modifications are logged as well.
+func (t *Tree) Replay(r io.Reader) error {
+	t.checkPoisoned()
+	br := bufio.NewReader(r)
+	for offset := 0; ; offset++ {
+		lth, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: record %d: %v", ErrCorrupt, offset, err)
+		}
+		if lth > math.MaxInt32 {
+			return fmt.Errorf("%w: record %d: length %d", ErrCorrupt, offset, lth)
+		}
+		var sum [4]byte
+		if _, err := io.ReadFull(br, sum[:]); err != nil {
+			return fmt.Errorf("%w: record %d: %v", ErrCorrupt, offset, err)
+		}
+		// not reused, decoder configured by WithValueCodec may retain the data
+		payload := make([]byte, lth)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return fmt.Errorf("%w: record %d: %v", ErrCorrupt, offset, err)
+		}
+		if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(sum[:]) {
+			return fmt.Errorf("%w: record %d: checksum mismatch", ErrCorrupt, offset)
+		}
+		if err := t.replay(payload); err != nil {
+			return fmt.Errorf("record %d: %w", offset, err)
+		}
+	}
+}
+
+// replay decodes the record completely before applying it, corrupted record is not applied.
+func (t *Tree) replay(payload []byte) error {
+	d := decoder{data: payload, decode: t.decode}
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case walInsert, walDelete:
+		op, err := d.op(tag)
+		if err != nil {
+			return err
+		}
+		if err := d.end(); err != nil {
+			return err
+		}
+		if op.delete {
+			t.remove(op.key)
+		} else {
+			t.insert(t.newLeaf(op.key, op.value))
+		}
+	case walExpiring:
+		deadline, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		op, err := d.op(walInsert)
+		if err != nil {
+			return err
+		}
+		if err := d.end(); err != nil {
+			return err
+		}
+		l := t.newLeaf(op.key, op.value)
+		l.expires = int64(deadline)
+		t.insert(l)
+	case walDeleteRange:
+		var r Range
+		inclusivity, err := d.byte()
+		if err != nil {
+			return err
+		}
+		r.Inclusivity = Inclusivity(inclusivity)
+		if r.Start, err = d.key(); err != nil {
+			return err
+		}
+		if r.End, err = d.key(); err != nil {
+			return err
+		}
+		if err := d.end(); err != nil {
+			return err
+		}
+		t.deleteIn(r)
+	case walClear:
+		if err := d.end(); err != nil {
+			return err
+		}
+		t.Clear()
+	case walBatch:
+		count, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if count > uint64(len(d.data)) {
+			return fmt.Errorf("%w: batch of %d modifications", ErrCorrupt, count)
+		}
+		x := &Txn{tree: t, ops: make([]txnOp, 0, count)}
+		for i := uint64(0); i < count; i++ {
+			tag, err := d.byte()
+			if err != nil {
+				return err
+			}
+			op, err := d.op(tag)
+			if err != nil {
+				return err
+			}
+			x.ops = append(x.ops, op)
+		}
+		if err := d.end(); err != nil {
+			return err
+		}
+		return x.Commit()
+	default:
+		return fmt.Errorf("%w: unknown record tag %d", ErrCorrupt, tag)
+	}
+	return nil
+}
+
+// op decodes insert or delete record without the tag.
+func (d *decoder) op(tag byte) (txnOp, error) {
+	var (
+		op  txnOp
+		err error
+	)
+	switch tag {
+	case walInsert:
+		if op.key, err = d.key(); err != nil {
+			return op, err
+		}
+		op.value, err = d.value()
+	case walDelete:
+		op.key, err = d.key()
+		op.delete = true
+	default:
+		err = fmt.Errorf("%w: unknown record tag %d", ErrCorrupt, tag)
+	}
+	return op, err
+}
+
+func (d *decoder) end() error {
+	if len(d.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(d.data))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package art
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALReplay(t *testing.T) {
+	skipUnsafe(t)
+	var log bytes.Buffer
+	tree := New(WithWAL(&log))
+	key := func(i int) []byte {
+		return []byte{byte(i >> 8), byte(i)}
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 2000; i++ {
+				k := key(rng.Intn(500))
+				switch op := rng.Intn(100); {
+				case op < 60:
+					tree.Insert(k, []byte{byte(w), byte(i)})
+				case op < 90:
+					tree.Delete(k)
+				case op < 98:
+					txn := tree.Begin()
+					txn.Insert(k, []byte{byte(w)})
+					txn.Delete(key(rng.Intn(500)))
+					require.NoError(t, txn.Commit())
+				default:
+					tree.DeleteRange(k, key(rng.Intn(500)))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.NoError(t, tree.WALError())
+	require.True(t, tree.Config().WAL)
+
+	restored := New()
+	require.NoError(t, restored.Replay(bytes.NewReader(log.Bytes())))
+	keys, values := tree.Dump()
+	restoredKeys, restoredValues := restored.Dump()
+	require.Equal(t, keys, restoredKeys)
+	require.Equal(t, values, restoredValues)
+	require.Equal(t, tree.Len(), restored.Len())
+
+	tree.Clear()
+	require.NoError(t, restored.Replay(bytes.NewReader(log.Bytes())))
+	require.Equal(t, 0, restored.Len())
+}
+
+func TestWALCorrupted(t *testing.T) {
+	var log bytes.Buffer
+	tree := New(WithWAL(&log))
+	tree.Insert([]byte("a"), []byte("1"))
+	complete := log.Len()
+	tree.Insert([]byte("b"), []byte("2"))
+
+	// torn tail, records before it are applied
+	restored := New()
+	err := restored.Replay(bytes.NewReader(log.Bytes()[:log.Len()-1]))
+	require.True(t, errors.Is(err, ErrCorrupt), "error %v", err)
+	keys, _ := restored.Dump()
+	require.Equal(t, [][]byte{[]byte("a")}, keys)
+
+	data := append([]byte(nil), log.Bytes()...)
+	data[complete+len(data[complete:])-1] ^= 0xff
+	restored = New()
+	err = restored.Replay(bytes.NewReader(data))
+	require.True(t, errors.Is(err, ErrCorrupt), "error %v", err)
+	require.Equal(t, 1, restored.Len())
+
+	// logging stops after the first failure
+	tree.Insert([]byte("c"), 3)
+	require.True(t, errors.Is(tree.WALError(), ErrUnsupportedValue))
+	lth := log.Len()
+	tree.Insert([]byte("d"), []byte("4"))
+	require.Equal(t, lth, log.Len())
+}